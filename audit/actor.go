@@ -0,0 +1,23 @@
+package audit
+
+import "context"
+
+// SystemActor is recorded for changes made by background jobs on nobody's
+// behalf.
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// WithActor records who is making the changes done with ctx, e.g. the
+// subject of the authenticated principal.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor on the context, or SystemActor when there is none.
+func Actor(ctx context.Context) string {
+	if actor, _ := ctx.Value(actorKey{}).(string); actor != "" {
+		return actor
+	}
+	return SystemActor
+}
@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"encoding/json"
+	"go-product-api/models"
+	"reflect"
+)
+
+// Diff compares the JSON encodings of before and after field by field and
+// returns the fields that differ. Either may be nil, for a create or a
+// delete. Fields named in ignore are left out.
+func Diff(before, after any, ignore ...string) (models.FieldChanges, error) {
+	old, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	next, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range ignore {
+		delete(old, name)
+		delete(next, name)
+	}
+
+	changes := models.FieldChanges{}
+	for name, value := range next {
+		if previous, ok := old[name]; !ok || !reflect.DeepEqual(previous, value) {
+			changes[name] = models.FieldChange{Before: previous, After: value}
+		}
+	}
+	for name, value := range old {
+		if _, ok := next[name]; !ok {
+			changes[name] = models.FieldChange{Before: value}
+		}
+	}
+	return changes, nil
+}
+
+func fields(v any) (map[string]any, error) {
+	m := map[string]any{}
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil() {
+		return m, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return m, json.Unmarshal(b, &m)
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"go-product-api/models"
+)
+
+type item struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+	Price int64    `json:"price"`
+}
+
+func TestDiffReportsChangedFieldsOnly(t *testing.T) {
+	before := item{ID: "1", Name: "Shoe", Tags: []string{"sale"}, Price: 100}
+	after := item{ID: "2", Name: "Boot", Tags: []string{"sale"}, Price: 100}
+
+	changes, err := Diff(before, after, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes["name"] != (models.FieldChange{Before: "Shoe", After: "Boot"}) {
+		t.Fatalf("got changes %v, want only name from Shoe to Boot", changes)
+	}
+}
+
+func TestDiffOfCreateAndDeleteCoversEveryField(t *testing.T) {
+	product := &item{ID: "1", Name: "Shoe", Price: 100}
+
+	created, err := Diff((*item)(nil), product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 4 || created["name"] != (models.FieldChange{After: "Shoe"}) {
+		t.Fatalf("create got changes %v, want every field with no before", created)
+	}
+
+	deleted, err := Diff(product, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// JSON numbers decode as float64.
+	if len(deleted) != 4 || deleted["price"] != (models.FieldChange{Before: float64(100)}) {
+		t.Fatalf("delete got changes %v, want every field with no after", deleted)
+	}
+}
+
+func TestActorDefaultsToSystem(t *testing.T) {
+	if got := Actor(context.Background()); got != SystemActor {
+		t.Fatalf("got actor %q, want %q", got, SystemActor)
+	}
+	if got := Actor(WithActor(context.Background(), "key-a")); got != "key-a" {
+		t.Fatalf("got actor %q, want key-a", got)
+	}
+}
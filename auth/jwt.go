@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-product-api/config"
+	"go-product-api/logging"
+	"log/slog"
+	"os"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrJWTDisabled = errors.New("JWT authentication is not configured")
+
+var jwks keyfunc.Keyfunc
+
+// Init loads the JWKS used to verify bearer tokens. Keys fetched from
+// AUTH_JWKS_URL are refreshed in the background; AUTH_JWKS_FILE is read once.
+func Init() {
+	var err error
+	switch {
+	case config.JWKSURL != "":
+		jwks, err = keyfunc.NewDefaultCtx(context.Background(), []string{config.JWKSURL})
+	case config.JWKSFile != "":
+		var raw []byte
+		raw, err = os.ReadFile(config.JWKSFile)
+		if err == nil {
+			jwks, err = keyfunc.NewJWKSetJSON(raw)
+		}
+	default:
+		slog.Info("JWT authentication disabled, no JWKS configured")
+		return
+	}
+	if err != nil {
+		logging.Fatal("Failed to load JWKS", "error.message", err)
+	}
+	slog.Info("JWT authentication enabled")
+}
+
+func authenticateJWT(tokenString string) (Principal, error) {
+	if jwks == nil {
+		return Principal{}, ErrJWTDisabled
+	}
+
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired()}
+	if config.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.JWTIssuer))
+	}
+	if config.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(config.JWTAudience))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, claims, jwks.Keyfunc, opts...); err != nil {
+		return Principal{}, err
+	}
+
+	subject, _ := claims.GetSubject()
+	role, err := roleFromClaims(claims)
+	if err != nil {
+		return Principal{}, err
+	}
+	tenantID, _ := claims[config.JWTTenantClaim].(string)
+	if tenantID == "" && !config.JWTAllowUnscoped {
+		return Principal{}, fmt.Errorf("token has no %q claim", config.JWTTenantClaim)
+	}
+	return Principal{Subject: subject, Role: role, Method: "jwt", TenantID: tenantID}, nil
+}
+
+// roleFromClaims accepts the role claim either as a single string or as a
+// list, in which case the highest known role wins.
+func roleFromClaims(claims jwt.MapClaims) (Role, error) {
+	var best Role
+	switch v := claims[config.JWTRoleClaim].(type) {
+	case string:
+		return ParseRole(v)
+	case []any:
+		for _, item := range v {
+			s, _ := item.(string)
+			if role, err := ParseRole(s); err == nil && role.Allows(best) {
+				best = role
+			}
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("token has no valid %q claim", config.JWTRoleClaim)
+	}
+	return best, nil
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-product-api/config"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// useSigningKey makes authenticateJWT trust a fresh RSA key for the
+// duration of the test and returns it.
+func useSigningKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"test","alg":"RS256","use":"sig","n":%q,"e":"AQAB"}]}`,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()))
+	previous := jwks
+	if jwks, err = keyfunc.NewJWKSetJSON([]byte(set)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { jwks = previous })
+	return key
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	claims["exp"] = time.Now().Add(time.Minute).Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestAuthenticateJWTBindsTenantClaim(t *testing.T) {
+	key := useSigningKey(t)
+	for _, tenantID := range []string{"tenant-a", "tenant-b"} {
+		token := sign(t, key, jwt.MapClaims{"sub": "user", config.JWTRoleClaim: "admin", config.JWTTenantClaim: tenantID})
+		principal, err := authenticateJWT(token)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tenantID, err)
+		}
+		if principal.TenantID != tenantID {
+			t.Fatalf("%s: principal is bound to %q", tenantID, principal.TenantID)
+		}
+	}
+}
+
+func TestAuthenticateJWTRejectsTokenWithoutTenant(t *testing.T) {
+	key := useSigningKey(t)
+	token := sign(t, key, jwt.MapClaims{"sub": "user", config.JWTRoleClaim: "admin"})
+
+	if principal, err := authenticateJWT(token); err == nil {
+		t.Fatalf("got principal bound to %q, want an error", principal.TenantID)
+	}
+
+	previous := config.JWTAllowUnscoped
+	config.JWTAllowUnscoped = true
+	t.Cleanup(func() { config.JWTAllowUnscoped = previous })
+	principal, err := authenticateJWT(token)
+	if err != nil || principal.TenantID != "" {
+		t.Fatalf("with unscoped tokens allowed got %q, %v", principal.TenantID, err)
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go-product-api/audit"
+	"go-product-api/config"
+	"go-product-api/logging"
+	"go-product-api/problem"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// ErrNoCredentials is returned by Resolve when the caller sent neither an
+// API key nor a bearer token.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticate resolves the caller from an X-API-Key header or an
+// Authorization: Bearer token and rejects the request when neither is valid.
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		principal, err := Resolve(ctx, c.GetHeader(APIKeyHeader), c.GetHeader("Authorization"))
+		if errors.Is(err, ErrNoCredentials) {
+			c.Header("WWW-Authenticate", `Bearer realm="go-product-api"`)
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeAuthenticationRequired, "Authentication required"))
+			return
+		}
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="go-product-api", error="invalid_token"`)
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials, "Invalid credentials"))
+			return
+		}
+
+		c.Request = c.Request.WithContext(Bind(ctx, principal))
+		c.Next()
+	}
+}
+
+// Resolve authenticates the caller from an API key or, without one, from an
+// Authorization header value carrying a bearer token. Failures other than
+// an unknown API key are logged.
+func Resolve(ctx context.Context, apiKey, authorization string) (Principal, error) {
+	if config.AuthDisabled {
+		return Principal{Subject: "anonymous", Role: RoleAdmin, Method: "none"}, nil
+	}
+
+	var (
+		principal Principal
+		err       error
+	)
+	if apiKey != "" {
+		principal, err = authenticateAPIKey(ctx, apiKey)
+	} else if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		principal, err = authenticateJWT(token)
+	} else {
+		return Principal{}, ErrNoCredentials
+	}
+
+	if err != nil && !errors.Is(err, ErrInvalidAPIKey) {
+		logging.FromContext(ctx).Warn("Authentication failed", "error.message", err)
+	}
+	return principal, err
+}
+
+// RequireRole rejects callers whose role does not include required. It must
+// run after Authenticate.
+func RequireRole(required Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFromContext(c.Request.Context())
+		if !ok || !principal.Role.Allows(required) {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeForbidden, "Insufficient permissions"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Bind stores the principal on the context, along with its subject as the
+// actor recorded in the audit trail.
+func Bind(ctx context.Context, p Principal) context.Context {
+	return audit.WithActor(WithPrincipal(ctx, p), p.Subject)
+}
@@ -0,0 +1,23 @@
+package auth
+
+import "context"
+
+// Principal is the authenticated caller of a request. TenantID is empty for
+// callers that may act on any tenant.
+type Principal struct {
+	Subject  string `json:"subject"`
+	Role     Role   `json:"role"`
+	Method   string `json:"method"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,31 @@
+package auth
+
+import "fmt"
+
+type Role string
+
+// Roles are ordered: every role is allowed to do what the roles below it can.
+const (
+	RoleReader Role = "reader"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("unknown role: %s", s)
+	}
+	return role, nil
+}
+
+// Allows reports whether r grants at least the required role.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
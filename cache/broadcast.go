@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"go-product-api/config"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// invalidationChannel is the Redis channel invalidations are broadcast on
+// with CACHE_INVALIDATION=redis.
+const invalidationChannel = "product_cache_invalidations"
+
+// StartInvalidationListener drops the products invalidated by other
+// instances from this one's cache until ctx is cancelled. Without
+// broadcast invalidations it does nothing. The returned channel is closed
+// once it has stopped.
+func StartInvalidationListener(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	if config.CacheInvalidation != "redis" {
+		close(done)
+		return done
+	}
+
+	sub := config.Redis.Subscribe(ctx, invalidationChannel)
+	go func() {
+		defer close(done)
+		defer sub.Close()
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("Cache invalidation listener stopped")
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				id, err := uuid.Parse(msg.Payload)
+				if err != nil {
+					slog.Warn("Ignoring malformed cache invalidation", "error.message", err)
+					continue
+				}
+				evict(ctx, id)
+			}
+		}
+	}()
+	slog.Info("Cache invalidation listener started", "redis.channel", invalidationChannel)
+	return done
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+	"go-product-api/config"
+	"go-product-api/logging"
+	"go-product-api/models"
+	"go-product-api/tenant"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// ProductCache stores products by ID in front of the read path. Get reports
+// a miss with ok == false; errors are only returned for backend failures.
+// Every Delete of an ID advances its generation, and Set only stores a
+// product if the generation is still the one read before the product was
+// loaded, so a load that raced an invalidation cannot put back what was
+// just invalidated.
+type ProductCache interface {
+	Get(ctx context.Context, id uuid.UUID) (product models.Product, ok bool, err error)
+	Generation(ctx context.Context, id uuid.UUID) (uint64, error)
+	Set(ctx context.Context, product models.Product, generation uint64) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// Products is the cache used by the read path and invalidated by the Kafka
+// consumer. It is a no-op until Init is called.
+var Products ProductCache = noopCache{}
+
+var (
+	stats         = expvar.NewMap("product_cache")
+	hits          = new(expvar.Int)
+	misses        = new(expvar.Int)
+	invalidations = new(expvar.Int)
+	backendErrors = new(expvar.Int)
+)
+
+func init() {
+	stats.Set("hits", hits)
+	stats.Set("misses", misses)
+	stats.Set("invalidations", invalidations)
+	stats.Set("errors", backendErrors)
+}
+
+var loads singleflight.Group
+
+func Init() {
+	switch config.CacheBackend {
+	case "memory":
+		Products = NewLRUCache(config.CacheSize, config.CacheTTL)
+	case "redis":
+		config.ConnectRedis()
+		Products = NewRedisCache(config.Redis, config.CacheTTL)
+	case "none":
+		Products = noopCache{}
+	default:
+		logging.Fatal("Unknown cache backend", "cache.backend", config.CacheBackend)
+	}
+	switch config.CacheInvalidation {
+	case "local":
+	case "redis":
+		config.ConnectRedis()
+	default:
+		logging.Fatal("Unknown cache invalidation", "cache.invalidation", config.CacheInvalidation)
+	}
+	slog.Info("Product cache initialized", "cache.backend", config.CacheBackend, "cache.ttl", config.CacheTTL.String(), "cache.invalidation", config.CacheInvalidation)
+}
+
+// Local reports whether the cache is private to this process and only
+// invalidated when this process consumes the product events.
+func Local() bool {
+	return config.CacheBackend == "memory" && config.CacheInvalidation == "local"
+}
+
+// GetOrLoad returns the cached product or calls load to fetch it. Concurrent
+// misses for the same ID share a single load so a hot product that expires
+// does not stampede the backend. The source string returned by load is passed
+// through; cached results report "cache".
+func GetOrLoad(ctx context.Context, id uuid.UUID, load func(ctx context.Context) (models.Product, string, error)) (models.Product, string, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Product{}, "", err
+	}
+
+	product, ok, err := Products.Get(ctx, id)
+	if err != nil {
+		backendErrors.Add(1)
+		logging.FromContext(ctx).Warn("Product cache read failed", "product.id", id, "error.message", err)
+	}
+	// Entries are keyed by product ID alone; never hand one tenant's product
+	// to another.
+	if ok && product.TenantID == tenantID {
+		hits.Add(1)
+		return product, "cache", nil
+	}
+	misses.Add(1)
+
+	type loaded struct {
+		product models.Product
+		source  string
+	}
+	v, err, _ := loads.Do(tenantID+"/"+id.String(), func() (any, error) {
+		// Detach from the first caller's cancellation so the shared load is
+		// not aborted for everyone else waiting on it.
+		ctx := context.WithoutCancel(ctx)
+		// Read before loading, so an invalidation that lands while the
+		// product is being loaded keeps the loaded copy out of the cache.
+		generation, genErr := Products.Generation(ctx, id)
+		if genErr != nil {
+			backendErrors.Add(1)
+			logging.FromContext(ctx).Warn("Product cache read failed", "product.id", id, "error.message", genErr)
+		}
+		product, source, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if genErr == nil {
+			if err := Products.Set(ctx, product, generation); err != nil {
+				backendErrors.Add(1)
+				logging.FromContext(ctx).Warn("Product cache write failed", "product.id", id, "error.message", err)
+			}
+		}
+		return loaded{product, source}, nil
+	})
+	if err != nil {
+		return models.Product{}, "", err
+	}
+	l := v.(loaded)
+	return l.product, l.source, nil
+}
+
+// Invalidate drops a product from the cache after it changed, and from the
+// caches of the other instances when invalidations are broadcast.
+func Invalidate(ctx context.Context, id uuid.UUID) {
+	invalidations.Add(1)
+	evict(ctx, id)
+	if config.CacheInvalidation == "redis" {
+		if err := config.Redis.Publish(ctx, invalidationChannel, id.String()).Err(); err != nil {
+			backendErrors.Add(1)
+			logging.FromContext(ctx).Warn("Product cache invalidation broadcast failed", "product.id", id, "error.message", err)
+		}
+	}
+}
+
+func evict(ctx context.Context, id uuid.UUID) {
+	if err := Products.Delete(ctx, id); err != nil {
+		backendErrors.Add(1)
+		logging.FromContext(ctx).Warn("Product cache invalidation failed", "product.id", id, "error.message", err)
+	}
+}
+
+type noopCache struct{}
+
+func (noopCache) Get(context.Context, uuid.UUID) (models.Product, bool, error) {
+	return models.Product{}, false, nil
+}
+func (noopCache) Generation(context.Context, uuid.UUID) (uint64, error) { return 0, nil }
+func (noopCache) Set(context.Context, models.Product, uint64) error     { return nil }
+func (noopCache) Delete(context.Context, uuid.UUID) error               { return nil }
@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-product-api/config"
+	"go-product-api/models"
+	"go-product-api/tenant"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var errNotFound = errors.New("not found")
+
+// useBackend makes backend the product cache for the duration of the test.
+func useBackend(t *testing.T, backend ProductCache) {
+	t.Helper()
+	previous := Products
+	Products = backend
+	t.Cleanup(func() { Products = previous })
+}
+
+func newRedisBackend(t *testing.T) ProductCache {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisCache(client, time.Minute)
+}
+
+func TestGetOrLoadDoesNotShareProductsAcrossTenants(t *testing.T) {
+	backends := map[string]func(t *testing.T) ProductCache{
+		"memory": func(t *testing.T) ProductCache { return NewLRUCache(10, time.Minute) },
+		"redis":  newRedisBackend,
+	}
+	for name, newBackend := range backends {
+		t.Run(name, func(t *testing.T) {
+			useBackend(t, newBackend(t))
+			id := uuid.New()
+			ctxA := tenant.WithTenant(context.Background(), "tenant-a")
+			ctxB := tenant.WithTenant(context.Background(), "tenant-b")
+
+			loadA := func(context.Context) (models.Product, string, error) {
+				return models.Product{ID: id, TenantID: "tenant-a", SKU: "SKU-A"}, "database", nil
+			}
+			if _, source, err := GetOrLoad(ctxA, id, loadA); err != nil || source != "database" {
+				t.Fatalf("tenant-a: first load got source %q, error %v", source, err)
+			}
+			if product, source, err := GetOrLoad(ctxA, id, loadA); err != nil || source != "cache" || product.TenantID != "tenant-a" {
+				t.Fatalf("tenant-a: second load got source %q, tenant %q, error %v", source, product.TenantID, err)
+			}
+
+			// tenant-b asks for the same ID and must go to its own scoped
+			// loader rather than being served tenant-a's entry.
+			loadedB := false
+			loadB := func(context.Context) (models.Product, string, error) {
+				loadedB = true
+				return models.Product{}, "", errNotFound
+			}
+			product, _, err := GetOrLoad(ctxB, id, loadB)
+			if !errors.Is(err, errNotFound) {
+				t.Fatalf("tenant-b: got product of %q, error %v; want %v", product.TenantID, err, errNotFound)
+			}
+			if !loadedB {
+				t.Fatal("tenant-b: was answered from the cache")
+			}
+		})
+	}
+}
+
+func TestGetOrLoadDoesNotCacheALoadThatRacedAnInvalidation(t *testing.T) {
+	backends := map[string]func(t *testing.T) ProductCache{
+		"memory": func(t *testing.T) ProductCache { return NewLRUCache(10, time.Minute) },
+		"redis":  newRedisBackend,
+	}
+	for name, newBackend := range backends {
+		t.Run(name, func(t *testing.T) {
+			useBackend(t, newBackend(t))
+			id := uuid.New()
+			ctx := tenant.WithTenant(context.Background(), "tenant-a")
+
+			// The product changes after the database read but before the
+			// loaded copy is written to the cache.
+			stale := func(ctx context.Context) (models.Product, string, error) {
+				product := models.Product{ID: id, TenantID: "tenant-a", SKU: "OLD"}
+				Invalidate(ctx, id)
+				return product, "database", nil
+			}
+			if product, _, err := GetOrLoad(ctx, id, stale); err != nil || product.SKU != "OLD" {
+				t.Fatalf("racing load got SKU %q, error %v", product.SKU, err)
+			}
+
+			fresh := func(context.Context) (models.Product, string, error) {
+				return models.Product{ID: id, TenantID: "tenant-a", SKU: "NEW"}, "database", nil
+			}
+			product, source, err := GetOrLoad(ctx, id, fresh)
+			if err != nil || source != "database" || product.SKU != "NEW" {
+				t.Fatalf("next load got SKU %q from %q, error %v; want the new row from the database", product.SKU, source, err)
+			}
+			if product, source, err := GetOrLoad(ctx, id, fresh); err != nil || source != "cache" || product.SKU != "NEW" {
+				t.Fatalf("third load got SKU %q from %q, error %v; want the new row from the cache", product.SKU, source, err)
+			}
+		})
+	}
+}
+
+func TestGetOrLoadRequiresTenant(t *testing.T) {
+	useBackend(t, NewLRUCache(10, time.Minute))
+	load := func(context.Context) (models.Product, string, error) {
+		t.Fatal("loaded without a tenant")
+		return models.Product{}, "", nil
+	}
+	if _, _, err := GetOrLoad(context.Background(), uuid.New(), load); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Fatalf("got error %v, want %v", err, tenant.ErrMissingTenant)
+	}
+}
+
+func TestInvalidationListenerEvictsBroadcastProducts(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	previousClient, previousInvalidation := config.Redis, config.CacheInvalidation
+	config.Redis, config.CacheInvalidation = client, "redis"
+	t.Cleanup(func() { config.Redis, config.CacheInvalidation = previousClient, previousInvalidation })
+
+	local := NewLRUCache(10, time.Minute)
+	useBackend(t, local)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := StartInvalidationListener(ctx)
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	product := models.Product{ID: uuid.New(), TenantID: "tenant-a"}
+	local.Set(ctx, product, 0)
+	// Another instance consumed the change and broadcast it.
+	if err := client.Publish(ctx, invalidationChannel, product.ID.String()).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok, _ := local.Get(ctx, product.ID); !ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("product is still cached after the broadcast invalidation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"go-product-api/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// LRUCache is an in-process cache bounded by size and TTL. Each replica has
+// its own copy, so with several replicas prefer the Redis backend.
+// Generations are kept as long as products, so loads that outlast the TTL
+// are not guarded.
+type LRUCache struct {
+	mu          sync.Mutex
+	lru         *expirable.LRU[uuid.UUID, models.Product]
+	generations *expirable.LRU[uuid.UUID, uint64]
+}
+
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		lru:         expirable.NewLRU[uuid.UUID, models.Product](size, nil, ttl),
+		generations: expirable.NewLRU[uuid.UUID, uint64](size, nil, ttl),
+	}
+}
+
+func (c *LRUCache) Get(_ context.Context, id uuid.UUID) (models.Product, bool, error) {
+	product, ok := c.lru.Get(id)
+	return product, ok, nil
+}
+
+func (c *LRUCache) Generation(_ context.Context, id uuid.UUID) (uint64, error) {
+	generation, _ := c.generations.Peek(id)
+	return generation, nil
+}
+
+func (c *LRUCache) Set(_ context.Context, product models.Product, generation uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, _ := c.generations.Peek(product.ID); current == generation {
+		c.lru.Add(product.ID, product)
+	}
+	return nil
+}
+
+func (c *LRUCache) Delete(_ context.Context, id uuid.UUID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	generation, _ := c.generations.Peek(id)
+	c.generations.Add(id, generation+1)
+	c.lru.Remove(id)
+	return nil
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-product-api/models"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix        = "product:"
+	redisGenerationPrefix = "product_generation:"
+)
+
+// setIfGeneration stores a product only while its generation is still the
+// expected one; a missing generation counts as 0.
+var setIfGeneration = redis.NewScript(`
+if (redis.call("GET", KEYS[2]) or "0") ~= ARGV[2] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[3])
+return 1
+`)
+
+// RedisCache stores products as JSON under product:<id> keys, shared by all
+// replicas, and their generations under product_generation:<id>, which
+// expire with the TTL like the products.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl}
+}
+
+func (c *RedisCache) Get(ctx context.Context, id uuid.UUID) (models.Product, bool, error) {
+	data, err := c.client.Get(ctx, redisKeyPrefix+id.String()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.Product{}, false, nil
+	}
+	if err != nil {
+		return models.Product{}, false, err
+	}
+
+	var product models.Product
+	if err := json.Unmarshal(data, &product); err != nil {
+		return models.Product{}, false, fmt.Errorf("error decoding cached product: %w", err)
+	}
+	return product, true, nil
+}
+
+func (c *RedisCache) Generation(ctx context.Context, id uuid.UUID) (uint64, error) {
+	generation, err := c.client.Get(ctx, redisGenerationPrefix+id.String()).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return generation, err
+}
+
+func (c *RedisCache) Set(ctx context.Context, product models.Product, generation uint64) error {
+	data, err := json.Marshal(product)
+	if err != nil {
+		return err
+	}
+	keys := []string{redisKeyPrefix + product.ID.String(), redisGenerationPrefix + product.ID.String()}
+	return setIfGeneration.Run(ctx, c.client, keys, data, strconv.FormatUint(generation, 10), c.ttl.Milliseconds()).Err()
+}
+
+func (c *RedisCache) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, redisKeyPrefix+id.String())
+		pipe.Incr(ctx, redisGenerationPrefix+id.String())
+		pipe.PExpire(ctx, redisGenerationPrefix+id.String(), c.ttl)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,45 @@
+// Package catalog implements the product operations offered by both the
+// REST and the gRPC API, so either reads from the same sources, validates
+// input the same way and publishes the same events.
+package catalog
+
+import (
+	"errors"
+	"go-product-api/problem"
+	"strings"
+)
+
+// ErrNotPublished wraps the error of a change that was saved but whose
+// event could not be published.
+var ErrNotPublished = errors.New("change saved but its event was not published")
+
+// ValidationError reports input that passed the binding tags of its struct
+// but is invalid as a whole, such as two prices for the same market.
+type ValidationError struct {
+	Errors []problem.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		fields[i] = fe.Field
+	}
+	return "invalid fields: " + strings.Join(fields, ", ")
+}
+
+// OptionsInUseError rejects an update that changes the options of a
+// product so they no longer fit one of its variants.
+type OptionsInUseError struct {
+	VariantSKU string
+}
+
+func (e *OptionsInUseError) Error() string {
+	return "the options no longer match variant " + e.VariantSKU
+}
+
+func invalid(errs []problem.FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
@@ -0,0 +1,91 @@
+package catalog
+
+import (
+	"context"
+	"go-product-api/models"
+	"go-product-api/money"
+	"go-product-api/repositories"
+)
+
+// Pricer picks the price to show in a requested currency, converting with
+// the exchange rate table when a product has no price in it.
+type Pricer struct {
+	currency string
+	market   string
+	rates    map[[2]string]float64
+}
+
+// NewPricer returns nil when no display currency was requested.
+func NewPricer(ctx context.Context, query DisplayQuery) (*Pricer, error) {
+	if query.DisplayCurrency == "" {
+		return nil, nil
+	}
+
+	rates, err := repositories.NewExchangeRateRepository().FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pricer{currency: query.DisplayCurrency, market: query.Market, rates: make(map[[2]string]float64, len(rates))}
+	for _, rate := range rates {
+		p.rates[[2]string{rate.BaseCurrency, rate.QuoteCurrency}] = rate.Rate
+	}
+	return p, nil
+}
+
+// Apply sets DisplayPrice on the product. A price in the display currency
+// wins over a converted one, and a price for the requested market wins over
+// the general price. DisplayPrice stays nil when no price can be converted.
+func (p *Pricer) Apply(product *models.Product) {
+	if p == nil {
+		return
+	}
+
+	var best *models.ProductPrice
+	bestScore := -1
+	for i := range product.Prices {
+		price := &product.Prices[i]
+		score := 0
+		switch price.Market {
+		case p.market:
+			score += 2
+		case "":
+			score++
+		default:
+			if p.market != "" {
+				continue
+			}
+		}
+		if price.Currency == p.currency {
+			score += 4
+		} else if _, ok := p.rate(price.Currency); !ok {
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = price, score
+		}
+	}
+	if best == nil {
+		product.DisplayPrice = nil
+		return
+	}
+
+	display := models.ProductPrice{Currency: p.currency, Market: best.Market, Amount: best.Amount}
+	if best.Currency != p.currency {
+		rate, _ := p.rate(best.Currency)
+		display.Amount = money.Convert(best.Amount, best.Currency, p.currency, rate)
+	}
+	product.DisplayPrice = &display
+}
+
+// rate returns the rate from currency into the display currency, using the
+// inverse of the opposite pair when only that one is stored.
+func (p *Pricer) rate(from string) (float64, bool) {
+	if rate, ok := p.rates[[2]string{from, p.currency}]; ok {
+		return rate, true
+	}
+	if rate, ok := p.rates[[2]string{p.currency, from}]; ok && rate != 0 {
+		return 1 / rate, true
+	}
+	return 0, false
+}
@@ -0,0 +1,76 @@
+package catalog
+
+import (
+	"testing"
+
+	"go-product-api/models"
+)
+
+func TestPricerPrefersNativeThenMarketPrices(t *testing.T) {
+	rates := map[[2]string]float64{{"USD", "EUR"}: 0.9, {"EUR", "GBP"}: 0.8}
+	tests := []struct {
+		name     string
+		currency string
+		market   string
+		prices   []models.ProductPrice
+		want     *models.ProductPrice
+	}{
+		{
+			name:     "native price wins over a converted one",
+			currency: "EUR",
+			prices:   []models.ProductPrice{{Currency: "USD", Amount: 1000}, {Currency: "EUR", Amount: 950}},
+			want:     &models.ProductPrice{Currency: "EUR", Amount: 950},
+		},
+		{
+			name:     "market price wins over the general one",
+			currency: "EUR",
+			market:   "DE",
+			prices:   []models.ProductPrice{{Currency: "EUR", Amount: 950}, {Currency: "EUR", Market: "DE", Amount: 900}, {Currency: "EUR", Market: "FR", Amount: 800}},
+			want:     &models.ProductPrice{Currency: "EUR", Market: "DE", Amount: 900},
+		},
+		{
+			name:     "converted with the rate",
+			currency: "EUR",
+			prices:   []models.ProductPrice{{Currency: "USD", Amount: 1000}},
+			want:     &models.ProductPrice{Currency: "EUR", Amount: 900},
+		},
+		{
+			name:     "converted with the inverse of the opposite rate",
+			currency: "EUR",
+			prices:   []models.ProductPrice{{Currency: "GBP", Amount: 800}},
+			want:     &models.ProductPrice{Currency: "EUR", Amount: 1000},
+		},
+		{
+			name:     "other markets are not shown",
+			currency: "EUR",
+			market:   "DE",
+			prices:   []models.ProductPrice{{Currency: "EUR", Market: "FR", Amount: 800}},
+		},
+		{
+			name:     "no rate to convert with",
+			currency: "JPY",
+			prices:   []models.ProductPrice{{Currency: "USD", Amount: 1000}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricer := &Pricer{currency: tt.currency, market: tt.market, rates: rates}
+			product := models.Product{Prices: tt.prices}
+			pricer.Apply(&product)
+
+			got := product.DisplayPrice
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Fatalf("got display price %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricerWithoutDisplayCurrencyLeavesProductsAlone(t *testing.T) {
+	var pricer *Pricer
+	product := models.Product{Prices: []models.ProductPrice{{Currency: "USD", Amount: 1000}}}
+	pricer.Apply(&product)
+	if product.DisplayPrice != nil {
+		t.Fatalf("got display price %+v, want none", product.DisplayPrice)
+	}
+}
@@ -0,0 +1,93 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"go-product-api/cache"
+	"go-product-api/events"
+	"go-product-api/models"
+	"go-product-api/repositories"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Create stores a new product and publishes product_created. Variants in
+// the input are ignored and the status defaults to draft. A product that
+// was stored but whose event was not published is returned along with an
+// ErrNotPublished error.
+func Create(ctx context.Context, input models.Product) (models.Product, error) {
+	if err := invalid(append(DuplicatePrices(input.Prices), DuplicateOptions(input.Options)...)); err != nil {
+		return models.Product{}, err
+	}
+	input.Variants = nil
+	if input.Status == "" {
+		input.Status = models.ProductStatusDraft
+	}
+	input.CreatedAt, input.UpdatedAt = time.Time{}, time.Time{}
+
+	entry, err := repositories.NewPostgresRepository().Create(ctx, &input)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	if err := events.PublishAuditedProductEvent(ctx, events.ProductCreated, input, entry); err != nil {
+		return input, fmt.Errorf("%w: %w", ErrNotPublished, err)
+	}
+	return input, nil
+}
+
+// Update replaces the editable fields of a product with those of the input
+// and publishes product_updated. The status is kept when the input has
+// none. Options have to keep fitting the existing variants, or the update
+// fails with an OptionsInUseError.
+func Update(ctx context.Context, id uuid.UUID, input models.Product) (models.Product, error) {
+	if err := invalid(append(DuplicatePrices(input.Prices), DuplicateOptions(input.Options)...)); err != nil {
+		return models.Product{}, err
+	}
+
+	product, entry, err := repositories.NewPostgresRepository().Update(ctx, id, func(product *models.Product) error {
+		for _, variant := range product.Variants {
+			if len(VariantOptionErrors(input.Options, variant.Options)) > 0 {
+				return &OptionsInUseError{VariantSKU: variant.SKU}
+			}
+		}
+
+		product.SKU = input.SKU
+		product.Name = input.Name
+		product.Description = input.Description
+		if input.Status != "" {
+			product.Status = input.Status
+		}
+		product.Tags = input.Tags
+		product.Attributes = input.Attributes
+		product.CategoryIDs = input.CategoryIDs
+		product.Options = input.Options
+		product.Prices = input.Prices
+		return nil
+	})
+	if err != nil {
+		return models.Product{}, err
+	}
+	cache.Invalidate(ctx, product.ID)
+
+	if err := events.PublishAuditedProductEvent(ctx, events.ProductUpdated, product, entry); err != nil {
+		return product, fmt.Errorf("%w: %w", ErrNotPublished, err)
+	}
+	return product, nil
+}
+
+// Delete removes a product and publishes product_deleted with the product
+// as it was.
+func Delete(ctx context.Context, id uuid.UUID) (models.Product, error) {
+	product, entry, err := repositories.NewPostgresRepository().Delete(ctx, id)
+	if err != nil {
+		return models.Product{}, err
+	}
+	cache.Invalidate(ctx, id)
+
+	if err := events.PublishAuditedProductEvent(ctx, events.ProductDeleted, product, entry); err != nil {
+		return product, fmt.Errorf("%w: %w", ErrNotPublished, err)
+	}
+	return product, nil
+}
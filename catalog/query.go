@@ -0,0 +1,90 @@
+package catalog
+
+import (
+	"fmt"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"strings"
+	"time"
+)
+
+// DisplayQuery asks for prices converted to a display currency, preferring
+// prices for the given market.
+type DisplayQuery struct {
+	DisplayCurrency string `form:"display_currency" json:"display_currency" binding:"omitempty,iso4217"`
+	Market          string `form:"market" json:"market" binding:"omitempty,iso3166_1_alpha2"`
+}
+
+// ProductQuery asks for a single product, optionally as it was at AsOf.
+type ProductQuery struct {
+	DisplayQuery
+	AsOf *time.Time `form:"as_of" json:"as_of" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// ListQuery searches the product list by text and filters and sorts it by
+// price in one currency. Amounts are in minor units of that currency.
+type ListQuery struct {
+	DisplayQuery
+	Query    string   `form:"q" json:"q" binding:"max=200"`
+	Currency string   `form:"currency" json:"currency" binding:"required_with=MinPrice MaxPrice Sort,omitempty,iso4217"`
+	MinPrice *int64   `form:"min_price" json:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice *int64   `form:"max_price" json:"max_price" binding:"omitempty,gte=0"`
+	Sort     string   `form:"sort" json:"sort" binding:"omitempty,oneof=price_asc price_desc"`
+	InStock  *bool    `form:"in_stock" json:"in_stock"`
+	Option   []string `form:"option" json:"option" binding:"max=10,dive,min=3"`
+	Limit    int      `form:"limit" json:"limit" binding:"omitempty,min=1,max=500"`
+	Offset   int      `form:"offset" json:"offset" binding:"omitempty,min=0"`
+}
+
+// DefaultListLimit is how many products a list returns when no limit is
+// asked for, whichever store serves it.
+const DefaultListLimit = 100
+
+// maxListWindow bounds how deep a list can be paged, as Elasticsearch
+// refuses to return results past its max_result_window.
+const maxListWindow = 10000
+
+// Filter returns the repository filter for the query, limited to
+// DefaultListLimit products unless the query asks for another limit. It
+// parses the option=name:value parameters into the options a variant must
+// have, each name given only once, and fails with a ValidationError
+// otherwise or when the page lies too deep.
+func (q ListQuery) Filter() (repositories.ProductFilter, error) {
+	filter := repositories.ProductFilter{
+		Query:    strings.TrimSpace(q.Query),
+		Currency: q.Currency,
+		MinPrice: q.MinPrice,
+		MaxPrice: q.MaxPrice,
+		Sort:     q.Sort,
+		InStock:  q.InStock,
+		Limit:    q.Limit,
+		Offset:   q.Offset,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Offset > maxListWindow-filter.Limit {
+		return repositories.ProductFilter{}, invalid([]problem.FieldError{{
+			Field:   "offset",
+			Code:    "max",
+			Message: fmt.Sprintf("plus limit must be at most %d", maxListWindow),
+		}})
+	}
+	if len(q.Option) == 0 {
+		return filter, nil
+	}
+
+	filter.Options = make(map[string]string, len(q.Option))
+	for i, option := range q.Option {
+		name, value, ok := strings.Cut(option, ":")
+		if _, dup := filter.Options[name]; !ok || name == "" || value == "" || dup {
+			return repositories.ProductFilter{}, invalid([]problem.FieldError{{
+				Field:   fmt.Sprintf("option[%d]", i),
+				Code:    "format",
+				Message: "must be name:value with each option name given once",
+			}})
+		}
+		filter.Options[name] = value
+	}
+	return filter, nil
+}
@@ -0,0 +1,23 @@
+package catalog
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestListQueryFilterLimits(t *testing.T) {
+	filter, err := ListQuery{}.Filter()
+	if err != nil || filter.Limit != DefaultListLimit || filter.Offset != 0 {
+		t.Fatalf("without a limit got limit %d, offset %d, error %v", filter.Limit, filter.Offset, err)
+	}
+
+	filter, err = ListQuery{Limit: 20, Offset: 40}.Filter()
+	if err != nil || filter.Limit != 20 || filter.Offset != 40 {
+		t.Fatalf("got limit %d, offset %d, error %v; want 20, 40", filter.Limit, filter.Offset, err)
+	}
+
+	var invalidInput *ValidationError
+	if _, err := (ListQuery{Limit: 500, Offset: 9600}).Filter(); !errors.As(err, &invalidInput) {
+		t.Fatalf("past the result window got error %v, want a validation error", err)
+	}
+}
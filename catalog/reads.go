@@ -0,0 +1,114 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"go-product-api/cache"
+	"go-product-api/config"
+	"go-product-api/eventstore"
+	"go-product-api/models"
+	"go-product-api/repositories"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sources a read can be served from.
+const (
+	SourceCache         = "cache"
+	SourceElasticsearch = "elasticsearch"
+	SourcePostgres      = "postgres"
+	SourceEventStore    = "event-store"
+)
+
+// Source says where a read was served from. Degraded is set when it fell
+// back to Postgres because Elasticsearch was unavailable.
+type Source struct {
+	Name     string
+	Degraded bool
+}
+
+// List serves the product list from Elasticsearch, falling back to Postgres
+// when strong consistency is requested or the search breaker is open. A
+// filter without a limit gets DefaultListLimit, so that both stores return
+// the same page.
+func List(ctx context.Context, filter repositories.ProductFilter, strong bool) ([]models.Product, Source, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if !strong {
+		products, err := repositories.NewElasticsearchRepository().FindAll(ctx, filter)
+		if !errors.Is(err, repositories.ErrElasticsearchUnavailable) {
+			return products, Source{Name: SourceElasticsearch}, err
+		}
+	}
+
+	products, err := repositories.NewPostgresRepository().FindAll(ctx, filter)
+	return products, Source{Name: SourcePostgres, Degraded: !strong}, err
+}
+
+// Get looks a product up in the read cache and then Elasticsearch.
+// Strong-consistency reads skip both and go to Postgres, which is also used
+// when the search breaker is open or the product is too new to have been
+// indexed yet.
+func Get(ctx context.Context, id uuid.UUID, strong bool) (models.Product, Source, error) {
+	if strong {
+		product, err := repositories.NewPostgresRepository().FindByID(ctx, id)
+		return product, Source{Name: SourcePostgres}, err
+	}
+
+	product, source, err := cache.GetOrLoad(ctx, id, func(ctx context.Context) (models.Product, string, error) {
+		return lookupProduct(ctx, id)
+	})
+	if source == sourceDegraded {
+		return product, Source{Name: SourcePostgres, Degraded: true}, err
+	}
+	return product, Source{Name: source}, err
+}
+
+// GetAt replays a product from the event store as it was at asOf.
+func GetAt(ctx context.Context, id uuid.UUID, asOf time.Time) (models.Product, Source, error) {
+	product, err := eventstore.StateAt(ctx, id, asOf)
+	return product, Source{Name: SourceEventStore}, err
+}
+
+// GetBySKU reads from Elasticsearch like List does. SKU lookups are not
+// cached since the cache is keyed by product ID.
+func GetBySKU(ctx context.Context, sku string, strong bool) (models.Product, Source, error) {
+	if !strong {
+		product, err := repositories.NewElasticsearchRepository().FindBySKU(ctx, sku)
+		if !errors.Is(err, repositories.ErrElasticsearchUnavailable) {
+			return product, Source{Name: SourceElasticsearch}, err
+		}
+	}
+
+	product, err := repositories.NewPostgresRepository().FindBySKU(ctx, sku)
+	return product, Source{Name: SourcePostgres, Degraded: !strong}, err
+}
+
+const sourceDegraded = "postgres-degraded"
+
+func lookupProduct(ctx context.Context, id uuid.UUID) (models.Product, string, error) {
+	product, err := repositories.NewElasticsearchRepository().FindByID(ctx, id)
+	switch {
+	case errors.Is(err, repositories.ErrElasticsearchUnavailable):
+		product, err = repositories.NewPostgresRepository().FindByID(ctx, id)
+		return product, sourceDegraded, err
+	case errors.Is(err, repositories.ErrProductNotFound) && createdWithin(id, config.ReadYourWritesWindow):
+		// The create event may not have reached the index yet.
+		product, err = repositories.NewPostgresRepository().FindByID(ctx, id)
+		return product, SourcePostgres, err
+	default:
+		return product, SourceElasticsearch, err
+	}
+}
+
+// createdWithin reports whether a product ID was generated less than window
+// ago. Product IDs are UUIDv7, so the creation time is embedded in the ID.
+func createdWithin(id uuid.UUID, window time.Duration) bool {
+	if id.Version() != 7 {
+		return false
+	}
+	sec, nsec := id.Time().UnixTime()
+	return time.Since(time.Unix(sec, nsec)) < window
+}
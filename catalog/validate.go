@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"fmt"
+	"go-product-api/models"
+	"go-product-api/problem"
+	"slices"
+	"strings"
+)
+
+// DuplicatePrices reports prices that repeat a currency and market already
+// listed earlier in the same product.
+func DuplicatePrices(prices []models.ProductPrice) []problem.FieldError {
+	seen := make(map[[2]string]bool, len(prices))
+	var errs []problem.FieldError
+	for i, price := range prices {
+		key := [2]string{price.Currency, price.Market}
+		if seen[key] {
+			errs = append(errs, problem.FieldError{
+				Field:   fmt.Sprintf("prices[%d]", i),
+				Code:    "unique",
+				Message: "duplicates another price with the same currency and market",
+			})
+		}
+		seen[key] = true
+	}
+	return errs
+}
+
+// DuplicateOptions reports options defined more than once on a product.
+func DuplicateOptions(options models.ProductOptions) []problem.FieldError {
+	seen := make(map[string]bool, len(options))
+	var errs []problem.FieldError
+	for i, option := range options {
+		if seen[option.Name] {
+			errs = append(errs, problem.FieldError{
+				Field:   fmt.Sprintf("options[%d].name", i),
+				Code:    "unique",
+				Message: "duplicates another option with the same name",
+			})
+		}
+		seen[option.Name] = true
+	}
+	return errs
+}
+
+// VariantOptionErrors reports options that the product does not define,
+// values it does not allow and options left out.
+func VariantOptionErrors(definitions models.ProductOptions, options models.VariantOptions) []problem.FieldError {
+	var errs []problem.FieldError
+	defined := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		defined[definition.Name] = true
+		field := "options." + definition.Name
+		value, ok := options[definition.Name]
+		switch {
+		case !ok:
+			errs = append(errs, problem.FieldError{Field: field, Code: "required", Message: "is required"})
+		case !slices.Contains(definition.Values, value):
+			errs = append(errs, problem.FieldError{
+				Field:   field,
+				Code:    "oneof",
+				Message: "must be one of: " + strings.Join(definition.Values, " "),
+			})
+		}
+	}
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		if !defined[name] {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		errs = append(errs, problem.FieldError{Field: "options." + name, Code: "unknown", Message: "is not an option of the product"})
+	}
+	return errs
+}
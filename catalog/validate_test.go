@@ -0,0 +1,55 @@
+package catalog
+
+import (
+	"testing"
+
+	"go-product-api/models"
+	"go-product-api/problem"
+)
+
+// codes maps each field error to its code.
+func codes(errs []problem.FieldError) map[string]string {
+	m := make(map[string]string, len(errs))
+	for _, fe := range errs {
+		m[fe.Field] = fe.Code
+	}
+	return m
+}
+
+func TestVariantOptionErrors(t *testing.T) {
+	definitions := models.ProductOptions{
+		{Name: "size", Values: []string{"S", "M", "L"}},
+		{Name: "colour", Values: []string{"red", "blue"}},
+	}
+	tests := []struct {
+		name    string
+		options models.VariantOptions
+		want    map[string]string
+	}{
+		{"fits", models.VariantOptions{"size": "M", "colour": "red"}, map[string]string{}},
+		{"missing", models.VariantOptions{"size": "M"}, map[string]string{"options.colour": "required"}},
+		{"not allowed", models.VariantOptions{"size": "XL", "colour": "red"}, map[string]string{"options.size": "oneof"}},
+		{"unknown", models.VariantOptions{"size": "M", "colour": "red", "fit": "slim"}, map[string]string{"options.fit": "unknown"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := codes(VariantOptionErrors(definitions, tt.options))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got errors %v, want %v", got, tt.want)
+			}
+			for field, code := range tt.want {
+				if got[field] != code {
+					t.Errorf("field %s: got code %q, want %q", field, got[field], code)
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateOptionsReportsRepeatedNames(t *testing.T) {
+	options := models.ProductOptions{{Name: "size"}, {Name: "colour"}, {Name: "size"}}
+	got := codes(DuplicateOptions(options))
+	if len(got) != 1 || got["options[2].name"] != "unique" {
+		t.Fatalf("got errors %v, want options[2].name reported once", got)
+	}
+}
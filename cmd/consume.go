@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os/signal"
+	"syscall"
+
+	"go-product-api/cache"
+	"go-product-api/config"
+	"go-product-api/logging"
+
+	"github.com/spf13/cobra"
+)
+
+func newConsumeCommand() *cobra.Command {
+	var workers, migrate bool
+	cmd := &cobra.Command{
+		Use:   "consume",
+		Short: "Consume product events into Elasticsearch without serving the API",
+		Long: `Consume product, category and stock events into Elasticsearch until SIGINT
+or SIGTERM, without serving the API. Run it next to serve --consumer=false.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return consume(cmd.Context(), workers, migrate)
+		},
+	}
+	cmd.Flags().BoolVar(&workers, "workers", false, "also run reservation expiry, scheduled prices and webhook delivery")
+	cmd.Flags().BoolVar(&migrate, "migrate", false, "apply pending database migrations before consuming")
+	return cmd
+}
+
+func consume(ctx context.Context, workers, migrate bool) error {
+	logging.Init(config.ServiceName)
+	config.InitTracing()
+
+	config.ConnectDatabase()
+	if err := requireSchema(ctx, migrate); err != nil {
+		return err
+	}
+	config.ConnectElasticsearch()
+	config.ConnectKafka()
+	cache.Init()
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	background := startBackground(backgroundCtx, true, workers)
+
+	<-ctx.Done()
+	slog.Info("Shutting down gracefully")
+	signal.Reset(syscall.SIGINT, syscall.SIGTERM)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	stopBackground()
+	background.wait(shutdownCtx)
+
+	shutdown()
+	return nil
+}
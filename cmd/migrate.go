@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"go-product-api/config"
+	"go-product-api/migrations"
+
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the PostgreSQL schema migrations",
+		Long: `Manage the PostgreSQL schema migrations embedded in the binary.
+
+Without a command it applies the pending migrations, like migrate up.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return migrateUp(cmd, 0)
+		},
+	}
+	cmd.AddCommand(newMigrateStatusCommand(), newMigrateUpCommand(), newMigrateDownCommand())
+	return cmd
+}
+
+func newMigrateStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "List the migrations and whether they are applied",
+		Long: `List every migration embedded in the binary or applied to the database.
+
+Exits with 3 when migrations are pending or an applied migration was
+modified, so scripts can check the schema before deploying.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config.ConnectDatabase()
+			defer config.CloseDatabase()
+
+			statuses, err := migrations.List(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+			outdated := 0
+			for _, s := range statuses {
+				state, appliedAt := "applied", ""
+				if s.AppliedAt != nil {
+					appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+				}
+				switch {
+				case s.Modified:
+					state = "modified"
+					outdated++
+				case s.Unknown:
+					state = "unknown"
+				case s.Pending():
+					state = "pending"
+					outdated++
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if outdated > 0 {
+				return partialError{failed: outdated, total: len(statuses)}
+			}
+			return nil
+		},
+	}
+}
+
+func newMigrateUpCommand() *cobra.Command {
+	var to int
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply the pending migrations",
+		Long: `Apply the pending migrations in version order, each in its own transaction.
+Runners on other instances wait for the one holding the migration lock.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if to < 0 {
+				return usageError{fmt.Errorf("--to must be a migration version")}
+			}
+			return migrateUp(cmd, to)
+		},
+	}
+	cmd.Flags().IntVar(&to, "to", 0, "only apply migrations up to this version")
+	return cmd
+}
+
+func migrateUp(cmd *cobra.Command, to int) error {
+	config.ConnectDatabase()
+	defer config.CloseDatabase()
+
+	out := progress(cmd)
+	applied, err := migrations.Up(cmd.Context(), to)
+	for _, m := range applied {
+		fmt.Fprintf(out, "Applied %d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(out, "No migrations to apply")
+	}
+	return nil
+}
+
+func newMigrateDownCommand() *cobra.Command {
+	var steps int
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the latest applied migrations",
+		Long: `Revert the latest applied migrations, newest first, each in its own
+transaction. Reverting usually drops data, so it requires --yes.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if steps < 1 {
+				return usageError{fmt.Errorf("--steps must be at least 1")}
+			}
+			if err := requireYes(cmd); err != nil {
+				return err
+			}
+			config.ConnectDatabase()
+			defer config.CloseDatabase()
+
+			out := progress(cmd)
+			reverted, err := migrations.Down(cmd.Context(), steps)
+			for _, m := range reverted {
+				fmt.Fprintf(out, "Reverted %d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				return err
+			}
+			if len(reverted) == 0 {
+				fmt.Fprintln(out, "No migrations to revert")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&steps, "steps", 1, "number of migrations to revert")
+	cmd.Flags().Bool("yes", false, "confirm reverting the migrations")
+	return cmd
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go-product-api/audit"
+	"go-product-api/cache"
+	"go-product-api/config"
+	"go-product-api/tenant"
+	"go-product-api/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// importProgressEvery is how many lines an import reports progress after.
+const importProgressEvery = 100
+
+func newProductsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "products",
+		Short: "Move the products of a tenant in and out as JSON lines",
+	}
+	cmd.PersistentFlags().String("tenant", config.DefaultTenant, "tenant whose products are exported or imported")
+	cmd.AddCommand(newProductsExportCommand(), newProductsImportCommand())
+	return cmd
+}
+
+func newProductsExportCommand() *cobra.Command {
+	var output string
+	var batchSize int
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the products of a tenant as JSON lines",
+		Args:  noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := tenantContext(cmd)
+			if err != nil {
+				return err
+			}
+			config.ConnectDatabase()
+			defer config.CloseDatabase()
+
+			w := cmd.OutOrStdout()
+			var file *os.File
+			if output != "-" {
+				if file, err = os.Create(output); err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+
+			out := progress(cmd)
+			exported, err := utils.ExportProducts(ctx, w, batchSize, func(exported int) {
+				fmt.Fprintf(out, "%d products exported\n", exported)
+			})
+			if err != nil {
+				return err
+			}
+			if file != nil {
+				if err := file.Close(); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(out, "Exported %d products\n", exported)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "file to write, or - for standard output")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 500, "products read from PostgreSQL at a time")
+	return cmd
+}
+
+func newProductsImportCommand() *cobra.Command {
+	var input, actor string
+	opts := utils.ImportOptions{}
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Create or update products of a tenant from JSON lines",
+		Long: `Read products as JSON lines, as written by export, and create or update them
+by SKU. Products are validated, audited and published like products written
+over the API; IDs, variants and stock in the input are ignored. Lines that
+fail are reported and skipped.
+
+Exits with 3 when some lines failed to import.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := tenantContext(cmd)
+			if err != nil {
+				return err
+			}
+			ctx = audit.WithActor(ctx, actor)
+
+			var r io.Reader = cmd.InOrStdin()
+			if input != "-" {
+				f, err := os.Open(input)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+
+			config.ConnectDatabase()
+			defer config.CloseDatabase()
+			if !opts.DryRun {
+				config.ConnectKafka()
+				defer config.CloseKafkaConnections()
+				cache.Init()
+				defer config.CloseRedis()
+			}
+
+			out := progress(cmd)
+			reported := 0
+			opts.Progress = func(result utils.ImportResult) {
+				// Failures are printed as they happen, whatever --quiet says.
+				for _, lineErr := range result.Errors[reported:] {
+					fmt.Fprintln(cmd.ErrOrStderr(), lineErr)
+				}
+				reported = len(result.Errors)
+
+				if lines := result.Created + result.Updated + result.Failed; lines%importProgressEvery == 0 {
+					fmt.Fprintf(out, "%d lines: %d created, %d updated, %d failed\n", lines, result.Created, result.Updated, result.Failed)
+				}
+			}
+
+			result, err := utils.ImportProducts(ctx, r, opts)
+			if err != nil {
+				return err
+			}
+			verb := "Imported"
+			if opts.DryRun {
+				verb = "Dry run:"
+			}
+			fmt.Fprintf(out, "%s %d created, %d updated, %d failed\n", verb, result.Created, result.Updated, result.Failed)
+			if result.Failed > 0 {
+				return partialError{failed: result.Failed, total: result.Created + result.Updated + result.Failed}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&input, "input", "i", "-", "file to read, or - for standard input")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "validate the input without writing anything")
+	cmd.Flags().StringVar(&actor, "actor", "import", "actor recorded in the audit trail")
+	return cmd
+}
+
+// tenantContext returns the command context bound to the --tenant flag.
+func tenantContext(cmd *cobra.Command) (context.Context, error) {
+	tenantID, _ := cmd.Flags().GetString("tenant")
+	if err := tenant.Validate(tenantID); err != nil {
+		return nil, usageError{err}
+	}
+	return tenant.WithTenant(cmd.Context(), tenantID), nil
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"go-product-api/config"
+	"go-product-api/eventstore"
+
+	"github.com/spf13/cobra"
+)
+
+func newRebuildCommand() *cobra.Command {
+	var target string
+	cmd := &cobra.Command{
+		Use:   "rebuild",
+		Short: "Rebuild a read model from the product event log",
+		Long: `Replay the stored product events, then the part of the product topic that
+was not stored yet, and write the resulting state of every product to
+PostgreSQL or Elasticsearch. It is a disaster-recovery operation: stop the
+consumers first. Requires --yes.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch eventstore.Target(target) {
+			case eventstore.TargetPostgres, eventstore.TargetElasticsearch:
+			default:
+				return usageError{fmt.Errorf("--target must be %s or %s", eventstore.TargetPostgres, eventstore.TargetElasticsearch)}
+			}
+			if err := requireYes(cmd); err != nil {
+				return err
+			}
+			config.ConnectDatabase()
+			defer config.CloseDatabase()
+			config.ConnectElasticsearch()
+
+			return eventstore.Rebuild(cmd.Context(), eventstore.Target(target))
+		},
+	}
+	cmd.Flags().StringVar(&target, "target", "", "read model to rebuild: postgres or elasticsearch")
+	cmd.Flags().Bool("yes", false, "confirm overwriting the read model")
+	return cmd
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"go-product-api/config"
+	"go-product-api/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func newReindexCommand() *cobra.Command {
+	opts := utils.SyncOptions{}
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Recreate the Elasticsearch indices and sync them from PostgreSQL",
+		Long: `Delete the product indices, recreate them with the current mapping and sync
+every product from PostgreSQL into them. Searches miss products until the
+sync reaches them, so run it after mapping changes or to recover from a
+broken index. Requires --yes.
+
+Exits with 3 when some products failed to index.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireYes(cmd); err != nil {
+				return err
+			}
+			config.ConnectDatabase()
+			defer config.CloseDatabase()
+			config.ConnectElasticsearch()
+
+			if err := utils.InitializeIndices(cmd.Context()); err != nil {
+				return err
+			}
+			return runSync(cmd, opts)
+		},
+	}
+	cmd.Flags().Bool("yes", false, "confirm deleting the indices")
+	cmd.Flags().IntVar(&opts.BatchSize, "batch-size", 500, "products read from PostgreSQL at a time")
+	return cmd
+}
@@ -0,0 +1,137 @@
+// Package cmd is the command line of the service: serving the API and the
+// one-off operations around it, such as migrating the database, rebuilding
+// the search indices or moving products in and out of a tenant.
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go-product-api/config"
+	"go-product-api/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the binary. A partial failure means the command ran to the
+// end but some of the items it processed failed, e.g. products that did not
+// import.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+	exitPartial = 3
+)
+
+// usageError is a command line the binary cannot run, such as an unknown
+// flag or a missing confirmation.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+
+// partialError reports that some of the items a command processed failed.
+type partialError struct {
+	failed int
+	total  int
+}
+
+func (e partialError) Error() string {
+	return fmt.Sprintf("%d of %d failed", e.failed, e.total)
+}
+
+// Execute runs the command named by the arguments and returns the exit
+// code. Without a command it serves the API, as the binary did before it
+// had commands.
+func Execute() int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cmd, err := newRootCommand().ExecuteContextC(ctx)
+	if err == nil {
+		return exitOK
+	}
+
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	var usage usageError
+	var partial partialError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+		return exitUsage
+	case errors.As(err, &partial):
+		return exitPartial
+	default:
+		return exitFailure
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &serveOptions{}
+	root := &cobra.Command{
+		Use:   "go-product-api",
+		Short: "Product catalog API",
+		Long: `Product catalog API backed by PostgreSQL, Elasticsearch and Kafka.
+
+Without a command it serves the API, like the serve command.`,
+		Args:          noArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// Commands that are not servers keep stdout for their
+			// result; serve and consume switch back to stdout.
+			logging.InitTo(os.Stderr, config.ServiceName)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(cmd.Context(), opts)
+		},
+	}
+	root.PersistentFlags().BoolP("quiet", "q", false, "suppress progress output")
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError{err}
+	})
+	opts.addFlags(root)
+
+	root.AddCommand(
+		newServeCommand(),
+		newConsumeCommand(),
+		newMigrateCommand(),
+		newSyncCommand(),
+		newReindexCommand(),
+		newRebuildCommand(),
+		newTopicsCommand(),
+		newProductsCommand(),
+	)
+	return root
+}
+
+// noArgs rejects positional arguments as a usage error.
+func noArgs(cmd *cobra.Command, args []string) error {
+	if err := cobra.NoArgs(cmd, args); err != nil {
+		return usageError{err}
+	}
+	return nil
+}
+
+// requireYes guards destructive commands behind --yes.
+func requireYes(cmd *cobra.Command) error {
+	if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+		return usageError{fmt.Errorf("%s is destructive; pass --yes to confirm", cmd.CommandPath())}
+	}
+	return nil
+}
+
+// progress returns where a command reports its progress: standard error,
+// or nowhere with --quiet.
+func progress(cmd *cobra.Command) io.Writer {
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+		return io.Discard
+	}
+	return cmd.ErrOrStderr()
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// run executes the command line without serving or connecting to anything;
+// every case fails before a backend is needed.
+func run(args ...string) error {
+	root := newRootCommand()
+	root.SetArgs(args)
+	root.SetOut(io.Discard)
+	root.SetErr(io.Discard)
+	_, err := root.ExecuteContextC(context.Background())
+	return err
+}
+
+func TestCommandLineMistakesAreUsageErrors(t *testing.T) {
+	tests := map[string][]string{
+		"unknown flag":                 {"reindex", "--bogus"},
+		"positional argument":          {"migrate", "up", "extra"},
+		"unknown target":               {"rebuild", "--target", "mongodb", "--yes"},
+		"destructive unconfirmed":      {"rebuild", "--target", "postgres"},
+		"migration revert unconfirmed": {"migrate", "down"},
+		"no migrations to revert":      {"migrate", "down", "--steps", "0", "--yes"},
+		"negative migration version":   {"migrate", "up", "--to", "-1"},
+	}
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			var usage usageError
+			if err := run(args...); !errors.As(err, &usage) {
+				t.Fatalf("%s: got error %v, want a usage error", strings.Join(args, " "), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go-product-api/auth"
+	"go-product-api/cache"
+	"go-product-api/config"
+	"go-product-api/events"
+	"go-product-api/grpcserver"
+	"go-product-api/inventory"
+	"go-product-api/logging"
+	"go-product-api/middleware"
+	"go-product-api/migrations"
+	"go-product-api/pricing"
+	"go-product-api/ratelimit"
+	"go-product-api/routes"
+	"go-product-api/stream"
+	"go-product-api/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+type serveOptions struct {
+	addr     string
+	grpcAddr string
+	consumer bool
+	workers  bool
+	migrate  bool
+}
+
+func (o *serveOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.addr, "addr", config.ServerAddr, "address of the HTTP server")
+	cmd.Flags().StringVar(&o.grpcAddr, "grpc-addr", config.GRPCAddr, "address of the gRPC server")
+	cmd.Flags().BoolVar(&o.consumer, "consumer", true, "consume product events into Elasticsearch in this process")
+	cmd.Flags().BoolVar(&o.workers, "workers", true, "run reservation expiry, scheduled prices and webhook delivery in this process")
+	cmd.Flags().BoolVar(&o.migrate, "migrate", false, "apply pending database migrations before serving")
+}
+
+func newServeCommand() *cobra.Command {
+	opts := &serveOptions{}
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the HTTP and gRPC APIs",
+		Long: `Serve the HTTP and gRPC APIs until SIGINT or SIGTERM.
+
+By default the process also consumes product events and runs the background
+workers; turn them off with --consumer=false and --workers=false to scale the
+API separately from a consume process. The product cache then has to be
+shared or have its invalidations broadcast, with CACHE_BACKEND=redis or
+CACHE_INVALIDATION=redis.
+
+The database schema has to be migrated first, with migrate up or --migrate;
+serve refuses to start while migrations are pending.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(cmd.Context(), opts)
+		},
+	}
+	opts.addFlags(cmd)
+	return cmd
+}
+
+func serve(ctx context.Context, opts *serveOptions) error {
+	logging.Init(config.ServiceName)
+	config.InitTracing()
+	config.ServerAddr, config.GRPCAddr = opts.addr, opts.grpcAddr
+
+	if os.Getenv(gin.EnvGinMode) == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	r, err := routes.NewEngine()
+	if err != nil {
+		return err
+	}
+	r.Use(middleware.RequestID(), middleware.Recovery(), otelgin.Middleware(config.ServiceName), middleware.Logger())
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	// Only the consumer invalidates the cache, so one private to this
+	// process would never be.
+	if !opts.consumer && cache.Local() {
+		return usageError{errors.New("--consumer=false needs CACHE_INVALIDATION=redis, or CACHE_BACKEND=redis or none")}
+	}
+
+	config.ConnectDatabase()
+	if err := requireSchema(ctx, opts.migrate); err != nil {
+		return err
+	}
+	config.ConnectElasticsearch()
+	config.ConnectKafka()
+	cache.Init()
+	auth.Init()
+	ratelimit.Init()
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	background := startBackground(backgroundCtx, opts.consumer, opts.workers)
+	background = append(background, loop{"cache invalidation listener", cache.StartInvalidationListener(backgroundCtx)})
+	streamDone := stream.Start(backgroundCtx)
+	routes.SetupRoutes(r)
+
+	srv := &http.Server{
+		Addr:    config.ServerAddr,
+		Handler: r,
+	}
+	// Streams never finish on their own, so end them when shutdown starts.
+	srv.RegisterOnShutdown(stream.Shutdown)
+
+	grpcSrv := grpcserver.New()
+
+	serverErr := make(chan error, 2)
+	go func() {
+		slog.Info("HTTP server listening", "server.address", config.ServerAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	go func() {
+		lis, err := net.Listen("tcp", config.GRPCAddr)
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		slog.Info("gRPC server listening", "server.address", config.GRPCAddr)
+		if err := grpcSrv.Serve(lis); err != nil {
+			serverErr <- err
+		}
+	}()
+
+	var failed error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down gracefully")
+	case failed = <-serverErr:
+		slog.Error("Server failed", "error.message", failed)
+	}
+	// A second signal kills the process instead of waiting for shutdown.
+	signal.Reset(syscall.SIGINT, syscall.SIGTERM)
+
+	// Stop accepting requests and let in-flight ones drain first, so any
+	// events they publish are still flushed by the producer below.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP server shutdown did not complete", "error.message", err)
+	}
+	// WatchProducts streams were ended by stream.Shutdown above, so a
+	// graceful stop only waits for unary calls in flight.
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcSrv.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		slog.Error("gRPC server shutdown did not complete")
+		grpcSrv.Stop()
+	}
+
+	stopBackground()
+	background.wait(shutdownCtx)
+	select {
+	case <-streamDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("Timed out waiting for product stream reader to stop")
+	}
+
+	shutdown()
+	if failed != nil {
+		return fmt.Errorf("server failed: %w", failed)
+	}
+	return nil
+}
+
+// requireSchema applies the pending migrations when migrate is set and
+// otherwise refuses to start on a schema that is not up to date.
+func requireSchema(ctx context.Context, migrate bool) error {
+	if migrate {
+		_, err := migrations.Up(ctx, 0)
+		return err
+	}
+	err := migrations.Check(ctx)
+	if errors.Is(err, migrations.ErrSchemaOutdated) {
+		return fmt.Errorf("%w; run migrate up or start with --migrate", err)
+	}
+	return err
+}
+
+// loop is a background loop and the channel it closes once stopped.
+type loop struct {
+	name string
+	done <-chan struct{}
+}
+
+type loops []loop
+
+// startBackground starts the event consumer and the background workers
+// that are switched on.
+func startBackground(ctx context.Context, consumer, workers bool) loops {
+	var started loops
+	if consumer {
+		started = append(started, loop{"kafka consumer", events.StartConsumer(ctx)})
+	}
+	if workers {
+		started = append(started,
+			loop{"reservation expiry", inventory.StartReservationExpiry(ctx)},
+			loop{"price scheduler", pricing.StartPriceScheduler(ctx)},
+			loop{"webhook dispatcher", webhooks.StartDispatcher(ctx)},
+		)
+	}
+	return started
+}
+
+// wait waits for the loops to stop, giving up on each once ctx is done.
+func (l loops) wait(ctx context.Context) {
+	for _, loop := range l {
+		select {
+		case <-loop.done:
+		case <-ctx.Done():
+			slog.Warn("Timed out waiting for " + loop.name + " to stop")
+		}
+	}
+}
+
+// shutdown closes the connections to the backing services and flushes the
+// traces.
+func shutdown() {
+	config.CloseKafkaConnections()
+	config.CloseDatabase()
+	config.CloseRedis()
+	config.ShutdownTracing()
+	slog.Info("Shutdown complete")
+}
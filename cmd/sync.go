@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"go-product-api/config"
+	"go-product-api/tenant"
+	"go-product-api/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func newSyncCommand() *cobra.Command {
+	opts := utils.SyncOptions{}
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Index products from PostgreSQL into Elasticsearch",
+		Long: `Index every product from PostgreSQL into Elasticsearch, for all tenants or
+the one given with --tenant. Products already indexed are overwritten; use
+reindex to start from empty indices.
+
+Exits with 3 when some products failed to index.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.TenantID != "" {
+				if err := tenant.Validate(opts.TenantID); err != nil {
+					return usageError{err}
+				}
+			}
+			config.ConnectDatabase()
+			defer config.CloseDatabase()
+			config.ConnectElasticsearch()
+
+			return runSync(cmd, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.TenantID, "tenant", "", "only sync this tenant")
+	cmd.Flags().IntVar(&opts.BatchSize, "batch-size", 500, "products read from PostgreSQL at a time")
+	return cmd
+}
+
+// runSync syncs with progress output and reports failed products as a
+// partial failure.
+func runSync(cmd *cobra.Command, opts utils.SyncOptions) error {
+	out := progress(cmd)
+	opts.Progress = syncProgress(out)
+
+	result, err := utils.SyncPostgresToElasticsearch(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Synced %d tenants: %d products indexed, %d failed\n", result.Tenants, result.Indexed, result.Failed)
+	if result.Failed > 0 {
+		return partialError{failed: result.Failed, total: result.Indexed + result.Failed}
+	}
+	return nil
+}
+
+func syncProgress(out io.Writer) func(tenantID string, indexed, failed int) {
+	return func(tenantID string, indexed, failed int) {
+		fmt.Fprintf(out, "%s: %d indexed, %d failed\n", tenantID, indexed, failed)
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"go-product-api/config"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/spf13/cobra"
+)
+
+func newTopicsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "topics",
+		Short: "Manage the Kafka topics of the service",
+	}
+	cmd.AddCommand(newTopicsCreateCommand())
+	return cmd
+}
+
+func newTopicsCreateCommand() *cobra.Command {
+	var partitions, replicationFactor int
+	var validateOnly bool
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create the Kafka topics the service uses",
+		Long: `Create the product, category and stock topics. Topics that already exist are
+left unchanged. Prints one line per topic with the outcome.
+
+Exits with 3 when some topics could not be created.`,
+		Args: noArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if partitions < 1 || replicationFactor < 1 {
+				return usageError{fmt.Errorf("--partitions and --replication-factor must be at least 1")}
+			}
+
+			results, err := config.CreateTopics(cmd.Context(), partitions, replicationFactor, validateOnly)
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, result := range results {
+				outcome := "created"
+				switch result.Error.Code() {
+				case kafka.ErrNoError:
+					if validateOnly {
+						outcome = "valid"
+					}
+				case kafka.ErrTopicAlreadyExists:
+					outcome = "already exists"
+				default:
+					outcome = "failed: " + result.Error.String()
+					failed++
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", result.Topic, outcome)
+			}
+			if failed > 0 {
+				return partialError{failed: failed, total: len(results)}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&partitions, "partitions", 1, "partitions of each topic")
+	cmd.Flags().IntVar(&replicationFactor, "replication-factor", 1, "replicas of each partition")
+	cmd.Flags().BoolVar(&validateOnly, "validate-only", false, "only check that the topics could be created")
+	return cmd
+}
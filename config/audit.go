@@ -0,0 +1,55 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"go-product-api/logging"
+	"log/slog"
+	"strings"
+)
+
+// AuditIndex is the Elasticsearch index the consumer copies audit entries
+// into, for searching the audit trail across products. Postgres stays the
+// record; leaving it empty disables the copy.
+var AuditIndex = getEnv("AUDIT_INDEX", "")
+
+const auditMappings = `{
+	"properties": {
+		"id": { "type": "keyword" },
+		"tenant_id": { "type": "keyword" },
+		"resource_type": { "type": "keyword" },
+		"resource_id": { "type": "keyword" },
+		"action": { "type": "keyword" },
+		"actor": { "type": "keyword" },
+		"request_id": { "type": "keyword" },
+		"changes": { "type": "flattened" },
+		"created_at": { "type": "date" }
+	}
+}`
+
+func createAuditIndex() {
+	ctx := context.Background()
+	res, err := ES.Indices.Exists([]string{AuditIndex}, ES.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		logging.Fatal("Error checking if audit index exists", "error.message", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 404 {
+		return
+	}
+
+	res, err = ES.Indices.Create(
+		AuditIndex,
+		ES.Indices.Create.WithContext(ctx),
+		ES.Indices.Create.WithBody(strings.NewReader(`{"mappings": `+auditMappings+`}`)),
+	)
+	if err != nil {
+		logging.Fatal("Error creating audit index", "error.message", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && !strings.Contains(res.String(), "resource_already_exists_exception") {
+		logging.Fatal("Error creating audit index", "error.message", fmt.Errorf("%s", res.String()))
+	}
+	slog.Info("Audit index created successfully", "index", AuditIndex)
+}
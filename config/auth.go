@@ -0,0 +1,20 @@
+package config
+
+// Authentication settings. API keys are always accepted; JWTs are accepted
+// when a JWKS is configured through AUTH_JWKS_URL or AUTH_JWKS_FILE.
+// ADMIN_API_KEY bootstraps an admin credential for creating the first keys.
+var (
+	AuthDisabled = getEnvBool("AUTH_DISABLED", false)
+	AdminAPIKey  = getEnv("ADMIN_API_KEY", "")
+	JWKSURL      = getEnv("AUTH_JWKS_URL", "")
+	JWKSFile     = getEnv("AUTH_JWKS_FILE", "")
+	JWTIssuer    = getEnv("AUTH_JWT_ISSUER", "")
+	JWTAudience  = getEnv("AUTH_JWT_AUDIENCE", "")
+	JWTRoleClaim = getEnv("AUTH_JWT_ROLE_CLAIM", "role")
+
+	JWTTenantClaim = getEnv("AUTH_JWT_TENANT_CLAIM", "tenant_id")
+
+	// JWTAllowUnscoped accepts tokens without a tenant claim as callers
+	// that may act on any tenant. Such tokens are rejected by default.
+	JWTAllowUnscoped = getEnvBool("AUTH_JWT_ALLOW_UNSCOPED", false)
+)
@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// Circuit breaker settings for Elasticsearch reads. The breaker opens after
+// ES_BREAKER_MAX_FAILURES consecutive failures, stays open for
+// ES_BREAKER_OPEN_TIMEOUT and then lets ES_BREAKER_HALF_OPEN_REQUESTS probe
+// requests through before closing again.
+var (
+	ESBreakerMaxFailures      = getEnvInt("ES_BREAKER_MAX_FAILURES", 5)
+	ESBreakerOpenTimeout      = getEnvDuration("ES_BREAKER_OPEN_TIMEOUT", 30*time.Second)
+	ESBreakerHalfOpenRequests = getEnvInt("ES_BREAKER_HALF_OPEN_REQUESTS", 1)
+	ESBreakerInterval         = getEnvDuration("ES_BREAKER_INTERVAL", 60*time.Second)
+)
@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+// CACHE_BACKEND selects the product read cache: memory (default), redis or
+// none. Entries expire after CACHE_TTL; the memory backend holds at most
+// CACHE_SIZE products.
+//
+// Products are invalidated by the process consuming the product events.
+// With CACHE_INVALIDATION=redis it also broadcasts the invalidations over
+// Redis, so the memory caches of every instance drop the product; the
+// default, local, only reaches the cache of the consuming process.
+var (
+	CacheBackend      = getEnv("CACHE_BACKEND", "memory")
+	CacheTTL          = getEnvDuration("CACHE_TTL", 5*time.Minute)
+	CacheSize         = getEnvInt("CACHE_SIZE", 10000)
+	CacheInvalidation = getEnv("CACHE_INVALIDATION", "local")
+)
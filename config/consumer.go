@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// A message the consumer fails to process is retried up to
+// ConsumerMaxAttempts times, after ConsumerBaseBackoff doubling up to
+// ConsumerMaxBackoff. It is then sent to DeadLetterTopic and committed, so
+// one bad message cannot hold up its partition forever.
+var (
+	ConsumerMaxAttempts = getEnvInt("KAFKA_CONSUMER_MAX_ATTEMPTS", 5)
+	ConsumerBaseBackoff = getEnvDuration("KAFKA_CONSUMER_BASE_BACKOFF", time.Second)
+	ConsumerMaxBackoff  = getEnvDuration("KAFKA_CONSUMER_MAX_BACKOFF", 30*time.Second)
+)
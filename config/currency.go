@@ -0,0 +1,5 @@
+package config
+
+// DefaultCurrency is the ISO 4217 currency that prices stored before
+// multi-currency support are assumed to be in.
+var DefaultCurrency = getEnv("DEFAULT_CURRENCY", "IDR")
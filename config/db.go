@@ -1,9 +1,8 @@
 package config
 
 import (
-	"fmt"
-	"go-product-api/models"
-	"log"
+	"go-product-api/logging"
+	"log/slog"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -13,17 +12,26 @@ var DB *gorm.DB
 
 func ConnectDatabase() {
 	dsn := "host=localhost user=devuser password=devpass dbname=go_products port=5433 sslmode=disable"
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 
 	if err != nil {
-		log.Fatal("Failed to connect to database: ", err)
+		logging.Fatal("Failed to connect to database", "error.message", err)
 	}
 
 	DB = database
-	fmt.Println("Database connection established")
+	slog.Info("Database connection established")
+}
 
-	err = database.AutoMigrate(&models.Product{})
+func CloseDatabase() {
+	if DB == nil {
+		return
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		slog.Error("Failed to get database pool", "error.message", err)
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		slog.Error("Failed to close database pool", "error.message", err)
 	}
 }
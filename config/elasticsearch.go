@@ -3,12 +3,15 @@ package config
 import (
 	"context"
 	"fmt"
-	"log"
+	"go-product-api/logging"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
+	"go.opentelemetry.io/otel"
 )
 
 var ES *elasticsearch.Client
@@ -18,11 +21,13 @@ func ConnectElasticsearch() {
 		Addresses: []string{"http://elasticsearch:9200"},
 		Username:  os.Getenv("ES_USERNAME"),
 		Password:  os.Getenv("ES_PASSWORD"),
+
+		Instrumentation: elasticsearch.NewOpenTelemetryInstrumentation(otel.GetTracerProvider(), false),
 	}
 
 	client, err := elasticsearch.NewClient(cfg)
 	if err != nil {
-		log.Fatalf("Error creating Elasticsearch client: %s", err)
+		logging.Fatal("Error creating Elasticsearch client", "error.message", err)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -30,47 +35,206 @@ func ConnectElasticsearch() {
 
 	res, err := client.Info(client.Info.WithContext(ctx))
 	if err != nil {
-		log.Fatalf("Error getting Elasticsearch info: %s", err)
+		logging.Fatal("Error getting Elasticsearch info", "error.message", err)
 	}
 	defer res.Body.Close()
 
 	ES = client
-	fmt.Println("Elasticsearch connection established")
+	slog.Info("Elasticsearch connection established")
 
 	createProductIndex()
+	if AuditIndex != "" {
+		createAuditIndex()
+	}
 }
 
-func createProductIndex() {
-	mapping := `{
-		"mappings": {
+const ProductIndex = "products"
+
+const productMappings = `{
+	"properties": {
+		"id": { "type": "keyword" },
+		"tenant_id": { "type": "keyword" },
+		"sku": { "type": "keyword" },
+		"name": { "type": "text" },
+		"description": { "type": "text" },
+		"status": { "type": "keyword" },
+		"tags": { "type": "keyword" },
+		"attributes": { "type": "flattened" },
+		"category_ids": { "type": "keyword" },
+		"category_paths": { "type": "keyword" },
+		"in_stock": { "type": "boolean" },
+		"options": {
+			"properties": {
+				"name": { "type": "keyword" },
+				"values": { "type": "keyword" }
+			}
+		},
+		"variants": {
+			"type": "nested",
 			"properties": {
 				"id": { "type": "keyword" },
-				"name": { "type": "text" },
-				"description": { "type": "text" },
-				"price": { "type": "integer" }
+				"product_id": { "type": "keyword" },
+				"sku": { "type": "keyword" },
+				"options": { "type": "flattened" },
+				"prices": {
+					"properties": {
+						"currency": { "type": "keyword" },
+						"market": { "type": "keyword" },
+						"amount": { "type": "long" }
+					}
+				},
+				"stock": { "type": "long" },
+				"created_at": { "type": "date" },
+				"updated_at": { "type": "date" }
+			}
+		},
+		"created_at": { "type": "date" },
+		"updated_at": { "type": "date" },
+		"prices": {
+			"type": "nested",
+			"properties": {
+				"currency": { "type": "keyword" },
+				"market": { "type": "keyword" },
+				"amount": { "type": "long" }
 			}
 		}
-	}`
+	}
+}`
+
+func createProductIndex() {
+	if err := ensureIndex(context.Background(), ProductIndex); err != nil {
+		logging.Fatal("Error creating index", "error.message", err)
+	}
+}
 
-	res, err := ES.Indices.Exists([]string{"products"})
+// ensureIndex creates the index with the product mapping, or adds any new
+// fields to the mapping of an existing one.
+func ensureIndex(ctx context.Context, index string) error {
+	res, err := ES.Indices.Exists([]string{index}, ES.Indices.Exists.WithContext(ctx))
 	if err != nil {
-		log.Fatalf("Error checking if index exists: %s", err)
+		return fmt.Errorf("error checking if index exists: %w", err)
 	}
+	res.Body.Close()
 
 	if res.StatusCode == 404 {
 		res, err := ES.Indices.Create(
-			"products",
-			ES.Indices.Create.WithBody(strings.NewReader(mapping)),
+			index,
+			ES.Indices.Create.WithContext(ctx),
+			ES.Indices.Create.WithBody(strings.NewReader(`{"mappings": `+productMappings+`}`)),
 		)
 		if err != nil {
-			log.Fatalf("Error creating index: %s", err)
+			return fmt.Errorf("error creating index: %w", err)
 		}
 		defer res.Body.Close()
 
-		if res.IsError() {
-			log.Fatalf("Error creating index: %s", res.String())
+		if res.IsError() && !strings.Contains(res.String(), "resource_already_exists_exception") {
+			return fmt.Errorf("error creating index: %s", res.String())
+		}
+
+		slog.Info("Products index created successfully", "index", index)
+		return nil
+	}
+
+	res, err = ES.Indices.PutMapping(
+		[]string{index},
+		strings.NewReader(productMappings),
+		ES.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("error updating mapping: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error updating mapping: %s", res.String())
+	}
+	return nil
+}
+
+var tenantIndices sync.Map
+
+// TenantProductIndices returns the index or alias to read a tenant's products
+// from and the index to write them to, creating them on first use. In alias
+// mode reads go through a filtered alias over the shared index; in index mode
+// each tenant has an index of its own.
+func TenantProductIndices(ctx context.Context, tenantID string) (read, write string, err error) {
+	name := ProductIndex + "_" + tenantID
+	if ESTenantMode == "index" {
+		read, write = name, name
+	} else {
+		read, write = name, ProductIndex
+	}
+
+	if _, ok := tenantIndices.Load(name); ok {
+		return read, write, nil
+	}
+
+	if ESTenantMode == "index" {
+		err = ensureIndex(ctx, name)
+	} else {
+		err = ensureTenantAlias(ctx, name, tenantID)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	tenantIndices.Store(name, struct{}{})
+	return read, write, nil
+}
+
+func ensureTenantAlias(ctx context.Context, alias, tenantID string) error {
+	body := fmt.Sprintf(`{"filter": {"term": {"tenant_id": %q}}}`, tenantID)
+	res, err := ES.Indices.PutAlias(
+		[]string{ProductIndex},
+		alias,
+		ES.Indices.PutAlias.WithContext(ctx),
+		ES.Indices.PutAlias.WithBody(strings.NewReader(body)),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating tenant alias: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error creating tenant alias: %s", res.String())
+	}
+	return nil
+}
+
+// RecreateProductIndices deletes the product index, and in index mode the
+// indices of the given tenants, and creates them again with the current
+// mapping along with the tenants' aliases. Searches find nothing until the
+// products are indexed again.
+func RecreateProductIndices(ctx context.Context, tenantIDs []string) error {
+	indices := []string{ProductIndex}
+	if ESTenantMode == "index" {
+		for _, tenantID := range tenantIDs {
+			indices = append(indices, ProductIndex+"_"+tenantID)
 		}
+	}
+
+	res, err := ES.Indices.Delete(
+		indices,
+		ES.Indices.Delete.WithContext(ctx),
+		ES.Indices.Delete.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting product indices: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("error deleting product indices: %s", res.String())
+	}
+	slog.Info("Product indices deleted", "indices", indices)
 
-		fmt.Println("Products index created successfully")
+	if err := ensureIndex(ctx, ProductIndex); err != nil {
+		return err
+	}
+	tenantIndices.Clear()
+	for _, tenantID := range tenantIDs {
+		if _, _, err := TenantProductIndices(ctx, tenantID); err != nil {
+			return err
+		}
 	}
+	return nil
 }
@@ -0,0 +1,10 @@
+package config
+
+// GraphQL operations are rejected before they run when their estimated cost
+// exceeds GraphQLMaxComplexity or their fields nest deeper than
+// GraphQLMaxDepth. Every field costs one, a mutation field 200 more, and
+// the fields under a paginated list are counted once per product asked for.
+var (
+	GraphQLMaxComplexity = getEnvInt("GRAPHQL_MAX_COMPLEXITY", 2000)
+	GraphQLMaxDepth      = getEnvInt("GRAPHQL_MAX_DEPTH", 8)
+)
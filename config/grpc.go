@@ -0,0 +1,9 @@
+package config
+
+// The gRPC API listens on GRPCAddr next to the HTTP server. Reflection lets
+// tools such as grpcurl discover its services; turn it off with
+// GRPC_REFLECTION=false where the schema should not be exposed.
+var (
+	GRPCAddr       = getEnv("GRPC_ADDR", ":9090")
+	GRPCReflection = getEnvBool("GRPC_REFLECTION", true)
+)
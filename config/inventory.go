@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// DefaultWarehouse is used by stock requests that name no warehouse.
+// Reservations without a TTL expire after ReservationTTL and are never
+// held longer than ReservationMaxTTL; expired ones are released every
+// ReservationSweepInterval.
+var (
+	DefaultWarehouse         = getEnv("INVENTORY_DEFAULT_WAREHOUSE", "default")
+	ReservationTTL           = getEnvDuration("RESERVATION_TTL", 15*time.Minute)
+	ReservationMaxTTL        = getEnvDuration("RESERVATION_MAX_TTL", 24*time.Hour)
+	ReservationSweepInterval = getEnvDuration("RESERVATION_SWEEP_INTERVAL", 30*time.Second)
+)
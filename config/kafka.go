@@ -3,7 +3,8 @@ package config
 import (
 	"context"
 	"fmt"
-	"log"
+	"go-product-api/logging"
+	"log/slog"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
@@ -14,26 +15,37 @@ var (
 	KafkaConsumer *kafka.Consumer
 )
 
+const kafkaBootstrapServers = "localhost:29092"
+
 var (
-	ProductTopic = "product_events"
+	ProductTopic  = "product_events"
+	CategoryTopic = "category_events"
+	StockTopic    = "stock_events"
+
+	// DeadLetterTopic receives the messages the consumer gave up on.
+	DeadLetterTopic = "dead_letter_events"
 )
 
-func ConnectKafka() {
-	bootstrapServers := "localhost:29092"
+// ProductTopicEpoch is stored with every product event consumed into the
+// event store. Raise it after recreating the product topic, whose offsets
+// then start over, so its events are not mistaken for those already stored
+// at the same positions.
+var ProductTopicEpoch = getEnvInt("KAFKA_PRODUCT_TOPIC_EPOCH", 0)
 
+func ConnectKafka() {
 	producerConfig := kafka.ConfigMap{
-		"bootstrap.servers":       bootstrapServers,
+		"bootstrap.servers":       kafkaBootstrapServers,
 		"client.id":               "go-product-api",
 		"socket.keepalive.enable": true,
 	}
 
 	producer, err := kafka.NewProducer(&producerConfig)
 	if err != nil {
-		log.Fatalf("Failed to create Kafka producer: %s", err)
+		logging.Fatal("Failed to create Kafka producer", "error.message", err)
 	}
 
 	consumerConfig := kafka.ConfigMap{
-		"bootstrap.servers":  bootstrapServers,
+		"bootstrap.servers":  kafkaBootstrapServers,
 		"group.id":           "go-product-group",
 		"auto.offset.reset":  "earliest",
 		"enable.auto.commit": false,
@@ -43,22 +55,22 @@ func ConnectKafka() {
 
 	consumer, err := kafka.NewConsumer(&consumerConfig)
 	if err != nil {
-		log.Fatalf("Failed to create Kafka consumer: %s", err)
+		logging.Fatal("Failed to create Kafka consumer", "error.message", err)
 	}
 
 	KafkaProducer = producer
 	KafkaConsumer = consumer
 
-	fmt.Println("Kafka connection established")
+	slog.Info("Kafka connection established")
 
 	go func() {
 		for e := range producer.Events() {
 			switch ev := e.(type) {
 			case *kafka.Message:
 				if ev.TopicPartition.Error != nil {
-					log.Printf("Delivery failed: %v\n", ev.TopicPartition.Error)
+					slog.Error("Delivery failed", "error.message", ev.TopicPartition.Error, "kafka.topic", *ev.TopicPartition.Topic)
 				} else {
-					log.Printf("Message delivered to %v\n", ev.TopicPartition)
+					slog.Debug("Message delivered", "kafka.topic", *ev.TopicPartition.Topic, "kafka.partition", ev.TopicPartition.Partition, "kafka.offset", int64(ev.TopicPartition.Offset))
 				}
 			}
 		}
@@ -68,51 +80,81 @@ func ConnectKafka() {
 }
 
 func ensureTopicExists() {
-	adminClient, err := kafka.NewAdminClient(&kafka.ConfigMap{
-		"bootstrap.servers": "localhost:29092",
-	})
-	if err != nil {
-		log.Printf("Failed to create admin client: %v\n", err)
-		return
-	}
-	defer adminClient.Close()
-
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	topics := []kafka.TopicSpecification{
-		{
-			Topic:             ProductTopic,
-			NumPartitions:     1,
-			ReplicationFactor: 1,
-		},
-	}
-
-	results, err := adminClient.CreateTopics(ctx, topics)
+	results, err := CreateTopics(ctx, 1, 1, false)
 	if err != nil {
-		log.Printf("Failed to create topics: %v\n", err)
+		slog.Error("Failed to create topics", "error.message", err)
 		return
 	}
 
 	for _, result := range results {
 		if result.Error.Code() != kafka.ErrNoError &&
 			result.Error.Code() != kafka.ErrTopicAlreadyExists {
-			log.Printf("Failed to create topic %s: %v\n", result.Topic, result.Error)
+			slog.Error("Failed to create topic", "kafka.topic", result.Topic, "error.message", result.Error)
 		} else {
-			log.Printf("Topic %s created or already exists\n", result.Topic)
+			slog.Info("Topic created or already exists", "kafka.topic", result.Topic)
 		}
 	}
+}
+
+// Topics are the topics the service produces to and consumes from.
+func Topics() []string {
+	return []string{ProductTopic, CategoryTopic, StockTopic, DeadLetterTopic}
+}
+
+// CreateTopics creates the service's topics with the given number of
+// partitions and replication factor. The result for each topic says whether
+// it was created or already existed; existing topics are left unchanged.
+// With validateOnly the broker only checks that the topics could be
+// created.
+func CreateTopics(ctx context.Context, partitions, replicationFactor int, validateOnly bool) ([]kafka.TopicResult, error) {
+	adminClient, err := kafka.NewAdminClient(&kafka.ConfigMap{
+		"bootstrap.servers": kafkaBootstrapServers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin client: %w", err)
+	}
+	defer adminClient.Close()
+
+	var topics []kafka.TopicSpecification
+	for _, topic := range Topics() {
+		topics = append(topics, kafka.TopicSpecification{
+			Topic:             topic,
+			NumPartitions:     partitions,
+			ReplicationFactor: replicationFactor,
+		})
+	}
+
+	results, err := adminClient.CreateTopics(ctx, topics, kafka.SetAdminValidateOnly(validateOnly))
+	if err != nil {
+		return nil, err
+	}
 
 	metadata, err := adminClient.GetMetadata(nil, true, 10000)
 	if err != nil {
-		log.Printf("Failed to get metadata: %v\n", err)
-		return
+		slog.Error("Failed to get metadata", "error.message", err)
+		return results, nil
 	}
 
-	log.Printf("Connected to Kafka cluster with %d brokers\n", len(metadata.Brokers))
+	slog.Info("Connected to Kafka cluster", "kafka.brokers", len(metadata.Brokers))
 	for _, broker := range metadata.Brokers {
-		log.Printf("Broker: %d at %s\n", broker.ID, broker.Host)
+		slog.Debug("Kafka broker", "kafka.broker.id", broker.ID, "kafka.broker.host", broker.Host)
 	}
+	return results, nil
+}
+
+// NewReplayConsumer returns a consumer for reading topics from the start
+// with explicitly assigned partitions. It never commits, so the offsets of
+// the service's consumer group are left alone.
+func NewReplayConsumer() (*kafka.Consumer, error) {
+	return kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  kafkaBootstrapServers,
+		"group.id":           "go-product-replay",
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": false,
+	})
 }
 
 func CloseKafkaConnections() {
@@ -0,0 +1,7 @@
+package config
+
+import "time"
+
+// PriceSchedulerInterval is how often due scheduled price changes are
+// applied, and so roughly how late after their effective time they land.
+var PriceSchedulerInterval = getEnvDuration("PRICE_SCHEDULER_INTERVAL", 30*time.Second)
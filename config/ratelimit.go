@@ -0,0 +1,19 @@
+package config
+
+// Token bucket limits per client, as a sustained rate in requests per second
+// plus a burst size, for each class of endpoint. The IP limit applies to every
+// request from an address before it is authenticated. RATE_LIMIT_STORE is
+// memory (per replica) or redis (shared across replicas).
+var (
+	RateLimitEnabled = getEnvBool("RATE_LIMIT_ENABLED", true)
+	RateLimitStore   = getEnv("RATE_LIMIT_STORE", "memory")
+
+	RateLimitReadRPS     = getEnvFloat("RATE_LIMIT_READ_RPS", 50)
+	RateLimitReadBurst   = getEnvInt("RATE_LIMIT_READ_BURST", 100)
+	RateLimitSearchRPS   = getEnvFloat("RATE_LIMIT_SEARCH_RPS", 10)
+	RateLimitSearchBurst = getEnvInt("RATE_LIMIT_SEARCH_BURST", 20)
+	RateLimitWriteRPS    = getEnvFloat("RATE_LIMIT_WRITE_RPS", 5)
+	RateLimitWriteBurst  = getEnvInt("RATE_LIMIT_WRITE_BURST", 10)
+	RateLimitIPRPS       = getEnvFloat("RATE_LIMIT_IP_RPS", 100)
+	RateLimitIPBurst     = getEnvInt("RATE_LIMIT_IP_BURST", 200)
+)
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"context"
+	"go-product-api/logging"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var Redis *redis.Client
+
+var RedisAddr = getEnv("REDIS_ADDR", "localhost:6379")
+
+func ConnectRedis() {
+	if Redis != nil {
+		return
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     RedisAddr,
+		Password: getEnv("REDIS_PASSWORD", ""),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		logging.Fatal("Failed to connect to Redis", "error.message", err)
+	}
+
+	Redis = client
+	slog.Info("Redis connection established", "redis.address", RedisAddr)
+}
+
+func CloseRedis() {
+	if Redis != nil {
+		if err := Redis.Close(); err != nil {
+			slog.Error("Failed to close Redis client", "error.message", err)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+var (
+	ServerAddr      = getEnv("SERVER_ADDR", ":8082")
+	ShutdownTimeout = getEnvDuration("SHUTDOWN_TIMEOUT", 15*time.Second)
+)
+
+// TrustedProxies lists the addresses or CIDR ranges of the proxies whose
+// X-Forwarded-For and X-Real-IP headers are believed, comma-separated. By
+// default none are, so the client IP that per-IP rate limits key on is the
+// peer address.
+var TrustedProxies = getEnvList("TRUSTED_PROXIES")
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// Product change streams buffer up to StreamBufferSize events per client; a
+// client that falls further behind is disconnected and has to resume with
+// Last-Event-ID, which replays at most StreamReplayLimit messages from Kafka
+// before the client is told to reload instead. At most StreamMaxReplays
+// resumes replay at once, each with its own Kafka consumer; further resumes
+// are turned away until one finishes. Idle streams get a heartbeat
+// every StreamHeartbeatInterval, and a client that does not take a write
+// within StreamWriteTimeout is disconnected.
+var (
+	StreamBufferSize        = getEnvInt("STREAM_BUFFER_SIZE", 256)
+	StreamReplayLimit       = getEnvInt("STREAM_REPLAY_LIMIT", 10000)
+	StreamMaxReplays        = getEnvInt("STREAM_MAX_REPLAYS", 8)
+	StreamHeartbeatInterval = getEnvDuration("STREAM_HEARTBEAT_INTERVAL", 15*time.Second)
+	StreamWriteTimeout      = getEnvDuration("STREAM_WRITE_TIMEOUT", 10*time.Second)
+)
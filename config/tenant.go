@@ -0,0 +1,11 @@
+package config
+
+// DefaultTenant is used when a caller that is not bound to a tenant (the
+// bootstrap admin key, unscoped keys, or AUTH_DISABLED) sends no X-Tenant-ID.
+// ESTenantMode selects how tenants are separated in Elasticsearch: "alias"
+// keeps one products index with a filtered alias per tenant, "index" gives
+// every tenant its own index.
+var (
+	DefaultTenant = getEnv("TENANT_DEFAULT", "default")
+	ESTenantMode  = getEnv("ES_TENANT_MODE", "alias")
+)
@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+// Per-operation deadlines applied on top of the caller's context. Each can be
+// overridden with a Go duration string, e.g. ES_SEARCH_TIMEOUT=500ms.
+var (
+	DBQueryTimeout   = getEnvDuration("DB_QUERY_TIMEOUT", 5*time.Second)
+	DBWriteTimeout   = getEnvDuration("DB_WRITE_TIMEOUT", 5*time.Second)
+	ESSearchTimeout  = getEnvDuration("ES_SEARCH_TIMEOUT", 3*time.Second)
+	ESGetTimeout     = getEnvDuration("ES_GET_TIMEOUT", 2*time.Second)
+	ESWriteTimeout   = getEnvDuration("ES_WRITE_TIMEOUT", 5*time.Second)
+	KafkaSendTimeout = getEnvDuration("KAFKA_SEND_TIMEOUT", 5*time.Second)
+)
+
+// ReadYourWritesWindow is how long after creation a product that is missing
+// from Elasticsearch is looked up in Postgres instead of reported as 404.
+var ReadYourWritesWindow = getEnvDuration("READ_YOUR_WRITES_WINDOW", 30*time.Second)
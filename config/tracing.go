@@ -0,0 +1,103 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"go-product-api/logging"
+	"log/slog"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const ServiceName = "go-product-api"
+
+var TracerProvider *sdktrace.TracerProvider
+
+var traceFile *os.File
+
+// InitTracing installs the global tracer provider and the W3C trace context
+// propagator. The exporter is chosen with OTEL_TRACES_EXPORTER:
+//   - otlp (default): OTLP/HTTP, endpoint taken from OTEL_EXPORTER_OTLP_ENDPOINT
+//   - stdout: pretty-printed spans on stdout
+//   - file: JSON spans appended to OTEL_TRACES_FILE (default traces.json)
+//   - none: spans are propagated but never exported
+func InitTracing() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporterName := os.Getenv("OTEL_TRACES_EXPORTER")
+	if exporterName == "" {
+		exporterName = "otlp"
+	}
+	if exporterName == "none" {
+		slog.Info("Tracing exporter disabled")
+		return
+	}
+
+	exporter, err := newTraceExporter(exporterName)
+	if err != nil {
+		logging.Fatal("Failed to create trace exporter", "error.message", err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)),
+	)
+	if err != nil {
+		logging.Fatal("Failed to create trace resource", "error.message", err)
+	}
+
+	TracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(TracerProvider)
+
+	slog.Info("Tracing initialized", "exporter", exporterName)
+}
+
+func newTraceExporter(name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "otlp":
+		return otlptracehttp.New(context.Background())
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		path := os.Getenv("OTEL_TRACES_FILE")
+		if path == "" {
+			path = "traces.json"
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening trace file: %w", err)
+		}
+		traceFile = f
+		return stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", name)
+	}
+}
+
+// ShutdownTracing flushes any buffered spans and releases the exporter.
+func ShutdownTracing() {
+	if TracerProvider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := TracerProvider.Shutdown(ctx); err != nil {
+			slog.Error("Failed to shut down tracer provider", "error.message", err)
+		}
+	}
+	if traceFile != nil {
+		traceFile.Close()
+	}
+}
@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+// Webhook deliveries are attempted every WebhookPollInterval, each with
+// WebhookTimeout to respond and up to WebhookConcurrency at a time. A failed
+// delivery is retried after WebhookBaseBackoff, doubling up to
+// WebhookMaxBackoff, and given up after WebhookMaxAttempts. A subscription
+// whose deliveries failed WebhookDisableAfter times in a row is disabled.
+var (
+	WebhookPollInterval = getEnvDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second)
+	WebhookTimeout      = getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+	WebhookConcurrency  = getEnvInt("WEBHOOK_CONCURRENCY", 8)
+	WebhookBaseBackoff  = getEnvDuration("WEBHOOK_BASE_BACKOFF", 30*time.Second)
+	WebhookMaxBackoff   = getEnvDuration("WEBHOOK_MAX_BACKOFF", time.Hour)
+	WebhookMaxAttempts  = getEnvInt("WEBHOOK_MAX_ATTEMPTS", 10)
+	WebhookDisableAfter = getEnvInt("WEBHOOK_DISABLE_AFTER", 50)
+)
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"go-product-api/auth"
+	"go-product-api/config"
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"go-product-api/tenant"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateAPIKeyInput binds a new key to TenantID, or to the default tenant
+// when it is empty. Only callers not bound to a tenant may choose another
+// tenant than their own, or set AllTenants to create a key that may act on
+// any tenant.
+type CreateAPIKeyInput struct {
+	Name       string `json:"name" binding:"required"`
+	Role       string `json:"role" binding:"required,oneof=reader editor admin"`
+	TenantID   string `json:"tenant_id" binding:"excluded_with=AllTenants"`
+	AllTenants bool   `json:"all_tenants"`
+}
+
+type CreateAPIKeyResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description List API keys, including revoked ones. Callers bound to a tenant only see the keys of their tenant. Secrets are never returned.
+// @Tags api-keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Router /api-keys [get]
+func GetAPIKeys(c *gin.Context) {
+	keys, err := repositories.NewAPIKeyRepository().FindAll(c.Request.Context(), callerTenant(c))
+	if err != nil {
+		respondError(c, err, "api_key", "Failed to fetch API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Create a new API key bound to a tenant: the caller's own tenant, or for callers not bound to one the given tenant_id or the default tenant. Keys for all tenants need all_tenants and a caller not bound to a tenant. The plaintext key is only returned in this response.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param apiKey body CreateAPIKeyInput true "API key name and role"
+// @Success 201 {object} CreateAPIKeyResponse
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions or access to tenant denied"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Router /api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	var input CreateAPIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	// A key never gets more reach than the caller creating it.
+	tenantID := input.TenantID
+	if scope := callerTenant(c); scope != "" {
+		if input.AllTenants || (tenantID != "" && tenantID != scope) {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeTenantForbidden, "Access to tenant denied"))
+			return
+		}
+		tenantID = scope
+	} else if tenantID == "" && !input.AllTenants {
+		tenantID = config.DefaultTenant
+	}
+	if !input.AllTenants {
+		if err := tenant.Validate(tenantID); err != nil {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidTenant, err.Error()))
+			return
+		}
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		respondError(c, err, "api_key", "Failed to generate API key")
+		return
+	}
+
+	apiKey := models.APIKey{
+		Name:     input.Name,
+		Prefix:   prefix,
+		KeyHash:  hash,
+		Role:     input.Role,
+		TenantID: tenantID,
+	}
+	if err := repositories.NewAPIKeyRepository().Create(c.Request.Context(), &apiKey); err != nil {
+		respondError(c, err, "api_key", "Failed to create API key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{APIKey: apiKey, Key: key})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke an API key so it can no longer authenticate. Callers bound to a tenant can only revoke the keys of their tenant.
+// @Tags api-keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} object "message: API key revoked"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 404 {object} problem.Problem "API key not found"
+// @Router /api-keys/{id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("api_key"))
+		return
+	}
+
+	if err := repositories.NewAPIKeyRepository().Revoke(c.Request.Context(), id, callerTenant(c)); err != nil {
+		respondError(c, err, "api_key", "Failed to revoke API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// callerTenant is the tenant the authenticated caller is bound to, or empty
+// for callers that may act on any tenant.
+func callerTenant(c *gin.Context) string {
+	principal, _ := auth.PrincipalFromContext(c.Request.Context())
+	return principal.TenantID
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-product-api/auth"
+	"go-product-api/internal/testutil"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// serveAs runs a request against the API key routes as the given principal.
+func serveAs(principal auth.Principal, method, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	})
+	r.GET("/api-keys", GetAPIKeys)
+	r.POST("/api-keys", CreateAPIKey)
+	r.DELETE("/api-keys/:id", RevokeAPIKey)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+var adminOfTenantA = auth.Principal{Subject: "admin-a", Role: auth.RoleAdmin, Method: "api_key", TenantID: "tenant-a"}
+
+func TestCreateAPIKeyCannotEscapeCallersTenant(t *testing.T) {
+	testutil.MockDatabase(t)
+	bodies := map[string]string{
+		"all tenants":    `{"name":"escalate","role":"admin","all_tenants":true}`,
+		"another tenant": `{"name":"escalate","role":"admin","tenant_id":"tenant-b"}`,
+	}
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			w := serveAs(adminOfTenantA, http.MethodPost, "/api-keys", body)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusForbidden, w.Body)
+			}
+		})
+	}
+}
+
+func TestCreateAPIKeyBindsKeyToCallersTenant(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "api_keys"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := serveAs(adminOfTenantA, http.MethodPost, "/api-keys", `{"name":"reader","role":"reader","tenant_id":"tenant-a"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var key CreateAPIKeyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &key); err != nil {
+		t.Fatal(err)
+	}
+	if key.TenantID != "tenant-a" {
+		t.Fatalf("key is bound to %q, want tenant-a", key.TenantID)
+	}
+}
+
+func TestGetAPIKeysOnlyListsCallersTenant(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	mock.ExpectQuery(`FROM "api_keys" WHERE tenant_id = \$1`).
+		WithArgs("tenant-a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id"}).AddRow(uuid.New(), "tenant-a"))
+
+	w := serveAs(adminOfTenantA, http.MethodGet, "/api-keys", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
+
+func TestRevokeAPIKeyOfAnotherTenantIsNotFound(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	id := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "api_keys" SET "revoked_at"`).
+		WithArgs(sqlmock.AnyArg(), id, "tenant-a").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	w := serveAs(adminOfTenantA, http.MethodDelete, "/api-keys/"+id.String(), "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+	}
+}
@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"go-product-api/catalog"
+	"go-product-api/events"
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CreateCategoryInput struct {
+	Name     string     `json:"name" binding:"required,min=1,max=100"`
+	ParentID *uuid.UUID `json:"parent_id"`
+	Position *int       `json:"position" binding:"omitempty,gte=0"`
+}
+
+type UpdateCategoryInput struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+// MoveCategoryInput places a category under ParentID, or at the root when it
+// is null, at Position among its new siblings, or last when omitted.
+type MoveCategoryInput struct {
+	ParentID *uuid.UUID `json:"parent_id"`
+	Position *int       `json:"position" binding:"omitempty,gte=0"`
+}
+
+// GetCategories godoc
+// @Summary Get category tree
+// @Description Get the tenant's categories as a tree, siblings in position order
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Success 200 {array} models.Category
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /categories [get]
+func GetCategories(c *gin.Context) {
+	categories, err := repositories.NewCategoryRepository().FindAll(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "category", "Failed to fetch categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, buildTree(categories))
+}
+
+// GetCategory godoc
+// @Summary Get category by ID
+// @Description Get a single category without its children
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Category ID"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} problem.Problem "Invalid category ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Category not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /categories/{id} [get]
+func GetCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("category"))
+		return
+	}
+
+	category, err := repositories.NewCategoryRepository().FindByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "category", "Failed to fetch category")
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// GetCategoryProducts godoc
+// @Summary Get products in category
+// @Description Get the products filed under the category or any of its descendants, from Elasticsearch or, for strong reads or while Elasticsearch is unavailable, from PostgreSQL
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Category ID"
+// @Param consistency query string false "Set to strong to read from PostgreSQL" Enums(eventual, strong)
+// @Param currency query string false "ISO 4217 currency to filter and sort by; required with min_price, max_price and sort"
+// @Param min_price query int false "Minimum price in minor units of currency"
+// @Param max_price query int false "Maximum price in minor units of currency"
+// @Param sort query string false "Sort by price in currency" Enums(price_asc, price_desc)
+// @Param in_stock query bool false "Only products with (true) or without (false) available stock"
+// @Param option query []string false "Only products with a variant having all these option values, each as name:value, e.g. size:M" collectionFormat(multi)
+// @Param display_currency query string false "ISO 4217 currency to show display_price in"
+// @Param market query string false "ISO 3166-1 alpha-2 market whose prices are preferred for display_price"
+// @Param q query string false "Only products whose name, SKU, description or tags match this text, best matches first"
+// @Param limit query int false "Maximum number of products to return, 100 by default" minimum(1) maximum(500)
+// @Param offset query int false "Number of products to skip; offset plus limit must be at most 10000" minimum(0)
+// @Success 200 {array} models.Product
+// @Failure 400 {object} problem.Problem "Invalid category ID or query parameters"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Category not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /categories/{id}/products [get]
+func GetCategoryProducts(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("category"))
+		return
+	}
+
+	var query catalog.ListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		problem.Abort(c, problem.FromQueryBindError(err))
+		return
+	}
+
+	filter, err := query.Filter()
+	if err != nil {
+		problem.Abort(c, queryProblem(err))
+		return
+	}
+
+	category, err := repositories.NewCategoryRepository().FindByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "category", "Failed to fetch category")
+		return
+	}
+
+	pricer, err := catalog.NewPricer(c.Request.Context(), query.DisplayQuery)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch exchange rates")
+		return
+	}
+
+	filter.CategoryPath = category.Path
+	products, err := listProducts(c, filter)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch products")
+		return
+	}
+	for i := range products {
+		pricer.Apply(&products[i])
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// CreateCategory godoc
+// @Summary Create category
+// @Description Create a category under parent_id, or at the root, at position among its siblings, or last
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param category body CreateCategoryInput true "Category data"
+// @Success 201 {object} models.Category
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /categories [post]
+func CreateCategory(c *gin.Context) {
+	var input CreateCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	category := models.Category{Name: input.Name, ParentID: input.ParentID}
+	if err := repositories.NewCategoryRepository().Create(c.Request.Context(), &category, input.Position); err != nil {
+		respondError(c, err, "category", "Failed to create category")
+		return
+	}
+
+	if err := events.PublishCategoryEvent(c.Request.Context(), events.CategoryEvent{Type: events.CategoryCreated, Category: category}); err != nil {
+		respondPublishError(c, err, "Category created but failed to publish event")
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary Rename category
+// @Description Rename a category. Use the move endpoint to change its parent or position.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Category ID"
+// @Param category body UpdateCategoryInput true "Category data"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Category not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /categories/{id} [put]
+func UpdateCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("category"))
+		return
+	}
+
+	var input UpdateCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	category, err := repositories.NewCategoryRepository().Rename(c.Request.Context(), id, input.Name)
+	if err != nil {
+		respondError(c, err, "category", "Failed to update category")
+		return
+	}
+
+	if err := events.PublishCategoryEvent(c.Request.Context(), events.CategoryEvent{Type: events.CategoryUpdated, Category: category}); err != nil {
+		respondPublishError(c, err, "Category updated but failed to publish event")
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// MoveCategory godoc
+// @Summary Move or reorder category
+// @Description Move a category with its subtree under another parent, or to the root when parent_id is null, and place it at position among its new siblings. Keeping the parent reorders it. Products in the subtree are re-indexed asynchronously.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Category ID"
+// @Param move body MoveCategoryInput true "New parent and position"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Category not found"
+// @Failure 409 {object} problem.Problem "Move would create a cycle"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /categories/{id}/move [post]
+func MoveCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("category"))
+		return
+	}
+
+	var input MoveCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	category, previousPath, err := repositories.NewCategoryRepository().Move(c.Request.Context(), id, input.ParentID, input.Position)
+	if err != nil {
+		respondError(c, err, "category", "Failed to move category")
+		return
+	}
+
+	event := events.CategoryEvent{Type: events.CategoryMoved, Category: category, PreviousPath: previousPath}
+	if err := events.PublishCategoryEvent(c.Request.Context(), event); err != nil {
+		respondPublishError(c, err, "Category moved but failed to publish event")
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete category
+// @Description Delete a category that has no subcategories. Its products are unfiled from it and re-indexed asynchronously.
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Category ID"
+// @Success 200 {object} object "message: Category deleted"
+// @Failure 400 {object} problem.Problem "Invalid category ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Category not found"
+// @Failure 409 {object} problem.Problem "Category has subcategories"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /categories/{id} [delete]
+func DeleteCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("category"))
+		return
+	}
+
+	category, productIDs, err := repositories.NewCategoryRepository().Delete(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "category", "Failed to delete category")
+		return
+	}
+
+	event := events.CategoryEvent{Type: events.CategoryDeleted, Category: category, ProductIDs: productIDs}
+	if err := events.PublishCategoryEvent(c.Request.Context(), event); err != nil {
+		respondPublishError(c, err, "Category deleted but failed to publish event")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted"})
+}
+
+// buildTree nests categories under their parents. It expects parents to
+// come before their children, as CategoryRepository.FindAll returns them.
+func buildTree(categories []models.Category) []models.Category {
+	children := make(map[uuid.UUID][]int)
+	var roots []int
+	for i, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, i)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], i)
+		}
+	}
+
+	var build func(i int) models.Category
+	build = func(i int) models.Category {
+		category := categories[i]
+		for _, child := range children[category.ID] {
+			category.Children = append(category.Children, build(child))
+		}
+		return category
+	}
+
+	tree := make([]models.Category, 0, len(roots))
+	for _, i := range roots {
+		tree = append(tree, build(i))
+	}
+	return tree
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-product-api/catalog"
+	"go-product-api/logging"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"go-product-api/stream"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// respondError maps a repository or event error to a problem response.
+// Deadline errors become 504 so callers can tell a slow backend from a broken
+// one. The underlying error is logged but never sent to the client; detail
+// is the message used for unexpected failures.
+func respondError(c *gin.Context, err error, resource, detail string) {
+	var (
+		invalidInput *catalog.ValidationError
+		optionsInUse *catalog.OptionsInUseError
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		problem.Abort(c, problem.New(http.StatusGatewayTimeout, problem.CodeBackendTimeout, "A backend service did not respond in time"))
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, repositories.ErrProductNotFound):
+		problem.Abort(c, notFound(resource))
+	case errors.Is(err, repositories.ErrVariantNotFound):
+		problem.Abort(c, notFound("variant"))
+	case errors.Is(err, repositories.ErrWebhookDeliveryNotFound):
+		problem.Abort(c, notFound("webhook_delivery"))
+	case errors.Is(err, repositories.ErrReservationNotFound):
+		problem.Abort(c, notFound("reservation"))
+	case errors.Is(err, repositories.ErrScheduledChangeNotFound):
+		problem.Abort(c, notFound("scheduled_price_change"))
+	case errors.Is(err, repositories.ErrScheduledChangeClosed):
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeScheduledChangeClosed, "The price change has already been applied or cancelled"))
+	case errors.As(err, &invalidInput):
+		problem.Abort(c, *invalidFields(invalidInput.Errors))
+	case errors.As(err, &optionsInUse):
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeOptionsInUse,
+			"The options no longer match variant "+optionsInUse.VariantSKU+"; update or delete it first"))
+	case errors.Is(err, repositories.ErrInsufficientStock):
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeInsufficientStock, "Not enough stock is available"))
+	case errors.Is(err, repositories.ErrReservationClosed):
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeReservationClosed, "The reservation has already been committed, released or expired"))
+	case errors.Is(err, repositories.ErrReservationExpired):
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeReservationExpired, "The reservation expired before it was committed"))
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		problem.Abort(c, conflict(resource))
+	case errors.Is(err, repositories.ErrUnknownCategory):
+		problem.Abort(c, invalidReference("category_ids", "refers to a category that does not exist"))
+	case errors.Is(err, repositories.ErrParentCategoryNotFound):
+		problem.Abort(c, invalidReference("parent_id", "refers to a category that does not exist"))
+	case errors.Is(err, repositories.ErrCategoryCycle):
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeInvalidMove, "A category cannot be moved under itself or one of its descendants"))
+	case errors.Is(err, repositories.ErrCategoryNotEmpty):
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeCategoryNotEmpty, "Move or delete the subcategories first"))
+	case errors.Is(err, repositories.ErrElasticsearchUnavailable):
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, problem.CodeServiceUnavailable, "The service is temporarily unavailable"))
+	case errors.Is(err, stream.ErrShutdown):
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, problem.CodeServiceUnavailable, "The server is shutting down"))
+	case errors.Is(err, stream.ErrReplayBusy):
+		c.Header("Retry-After", "1")
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, problem.CodeServiceUnavailable, "Too many streams are resuming; retry shortly"))
+	default:
+		logging.FromContext(c.Request.Context()).Error(detail, "error.message", err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternal, detail))
+	}
+}
+
+// respondPublishError reports a change that was saved but whose event could
+// not be published.
+func respondPublishError(c *gin.Context, err error, detail string) {
+	logging.FromContext(c.Request.Context()).Error(detail, "error.message", err)
+	problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeEventPublishFailed, detail))
+}
+
+func notFound(resource string) problem.Problem {
+	return problem.New(http.StatusNotFound, resource+"_"+problem.CodeNotFound, capitalize(strings.ReplaceAll(resource, "_", " "))+" not found")
+}
+
+func conflict(resource string) problem.Problem {
+	return problem.New(http.StatusConflict, resource+"_"+problem.CodeConflict, capitalize(strings.ReplaceAll(resource, "_", " "))+" conflicts with an existing one")
+}
+
+func invalidReference(field, message string) problem.Problem {
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "The request body contains invalid fields")
+	p.Errors = []problem.FieldError{{Field: field, Code: "exists", Message: message}}
+	return p
+}
+
+// invalidFields returns a problem listing errs as invalid fields of the
+// request body, or nil when there are none.
+func invalidFields(errs []problem.FieldError) *problem.Problem {
+	if len(errs) == 0 {
+		return nil
+	}
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "The request body contains invalid fields")
+	p.Errors = errs
+	return &p
+}
+
+// queryProblem reports query parameters that catalog rejected as a whole.
+func queryProblem(err error) problem.Problem {
+	p := problem.FromQueryBindError(nil)
+	var invalidInput *catalog.ValidationError
+	if errors.As(err, &invalidInput) {
+		p.Errors = invalidInput.Errors
+	}
+	return p
+}
+
+func invalidID(resource string) problem.Problem {
+	return problem.New(http.StatusBadRequest, problem.CodeInvalidID, "Invalid "+strings.ReplaceAll(resource, "_", " ")+" ID")
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExchangeRatePath struct {
+	Base  string `uri:"base" json:"base" binding:"required,iso4217"`
+	Quote string `uri:"quote" json:"quote" binding:"required,iso4217,nefield=Base"`
+}
+
+type ExchangeRateInput struct {
+	Rate float64 `json:"rate" binding:"required,gt=0"`
+}
+
+// GetExchangeRates godoc
+// @Summary List exchange rates
+// @Description List the rates used to convert prices into a display currency
+// @Tags exchange-rates
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} models.ExchangeRate
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Router /exchange-rates [get]
+func GetExchangeRates(c *gin.Context) {
+	rates, err := repositories.NewExchangeRateRepository().FindAll(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "exchange_rate", "Failed to fetch exchange rates")
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// PutExchangeRate godoc
+// @Summary Set exchange rate
+// @Description Create or replace the rate for a currency pair. One unit of base is worth rate units of quote.
+// @Tags exchange-rates
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param base path string true "ISO 4217 base currency"
+// @Param quote path string true "ISO 4217 quote currency"
+// @Param rate body ExchangeRateInput true "Conversion rate"
+// @Success 200 {object} models.ExchangeRate
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Router /exchange-rates/{base}/{quote} [put]
+func PutExchangeRate(c *gin.Context) {
+	var path ExchangeRatePath
+	if err := c.ShouldBindUri(&path); err != nil {
+		problem.Abort(c, problem.FromQueryBindError(err))
+		return
+	}
+
+	var input ExchangeRateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	rate := models.ExchangeRate{
+		BaseCurrency:  path.Base,
+		QuoteCurrency: path.Quote,
+		Rate:          input.Rate,
+	}
+	if err := repositories.NewExchangeRateRepository().Upsert(c.Request.Context(), &rate); err != nil {
+		respondError(c, err, "exchange_rate", "Failed to save exchange rate")
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// DeleteExchangeRate godoc
+// @Summary Delete exchange rate
+// @Description Delete the rate for a currency pair
+// @Tags exchange-rates
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param base path string true "ISO 4217 base currency"
+// @Param quote path string true "ISO 4217 quote currency"
+// @Success 200 {object} object "message: Exchange rate deleted"
+// @Failure 400 {object} problem.Problem "Invalid currency pair"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Exchange rate not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Router /exchange-rates/{base}/{quote} [delete]
+func DeleteExchangeRate(c *gin.Context) {
+	var path ExchangeRatePath
+	if err := c.ShouldBindUri(&path); err != nil {
+		problem.Abort(c, problem.FromQueryBindError(err))
+		return
+	}
+
+	if err := repositories.NewExchangeRateRepository().Delete(c.Request.Context(), path.Base, path.Quote); err != nil {
+		respondError(c, err, "exchange_rate", "Failed to delete exchange rate")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted"})
+}
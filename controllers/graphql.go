@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"go-product-api/graph"
+	"go-product-api/problem"
+	"go-product-api/ratelimit"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GraphQL godoc
+// @Summary Query the catalog with GraphQL
+// @Description Run a GraphQL query or mutation against the product catalog. Queries cover product lookups by id or SKU, listing and search with filters and cursor pagination; mutations create, update and delete products and require the editor role. Lookups by id and product categories are batched per request. Operations nesting deeper or estimated costlier than the configured limits are rejected with code query_too_complex; mutation fields are costly. Each mutation field is also charged against the write rate limit, on top of the search limit the request takes. Errors are reported in the errors array with the problem code in extensions.code, and the response status stays 200.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param request body graph.Request true "GraphQL request"
+// @Success 200 {object} map[string]interface{} "GraphQL response with data and errors"
+// @Failure 400 {object} problem.Problem "Malformed request body"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Router /graphql [post]
+func GraphQL(c *gin.Context) {
+	var req graph.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	doc, failed := graph.Prepare(req)
+	if failed != nil {
+		c.JSON(http.StatusOK, failed)
+		return
+	}
+	// The route only takes a search token; every mutation field writes, so
+	// each is charged against the write limit as well.
+	if !ratelimit.Charge(c, ratelimit.ClassWrite, doc.Mutations) {
+		return
+	}
+
+	c.JSON(http.StatusOK, doc.Execute(c.Request.Context()))
+}
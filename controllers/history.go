@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetProductHistory godoc
+// @Summary Get product history
+// @Description Get the audit trail of a product, newest first: who created, updated or deleted it, when, in which request and which fields changed. The trail of a deleted product stays available.
+// @Tags products
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {object} problem.Problem "Invalid product ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/history [get]
+func GetProductHistory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return
+	}
+
+	entries, err := repositories.NewAuditRepository().FindByResource(c.Request.Context(), models.AuditResourceProduct, id)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch product history")
+		return
+	}
+	// Products created before the audit trail existed have no entries yet.
+	if len(entries) == 0 {
+		if _, err := repositories.NewPostgresRepository().FindByID(c.Request.Context(), id); err != nil {
+			respondError(c, err, "product", "Failed to fetch product")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"go-product-api/catalog"
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PriceOverview is a product's current prices together with how they got
+// there and what is scheduled next.
+type PriceOverview struct {
+	ProductID uuid.UUID                     `json:"product_id"`
+	Current   []models.ProductPrice         `json:"current"`
+	History   []models.PriceHistory         `json:"history"`
+	Scheduled []models.ScheduledPriceChange `json:"scheduled"`
+}
+
+// ScheduledPriceChangeInput sets Prices once EffectiveAt is reached. Prices
+// in other currencies and markets are left unchanged.
+type ScheduledPriceChangeInput struct {
+	EffectiveAt time.Time             `json:"effective_at" binding:"required"`
+	Prices      []models.ProductPrice `json:"prices" binding:"required,min=1,max=50,dive"`
+}
+
+// GetProductPrices godoc
+// @Summary Get product prices
+// @Description Get the current prices of a product, its price history newest first and its pending scheduled price changes soonest first
+// @Tags pricing
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Success 200 {object} PriceOverview
+// @Failure 400 {object} problem.Problem "Invalid product ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/prices [get]
+func GetProductPrices(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	product, err := repositories.NewPostgresRepository().FindByID(ctx, id)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch product")
+		return
+	}
+	priceRepo := repositories.NewPriceRepository()
+	history, err := priceRepo.FindHistory(ctx, id)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch price history")
+		return
+	}
+	scheduled, err := priceRepo.FindScheduled(ctx, id)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch scheduled price changes")
+		return
+	}
+
+	c.JSON(http.StatusOK, PriceOverview{
+		ProductID: id,
+		Current:   product.Prices,
+		History:   history,
+		Scheduled: scheduled,
+	})
+}
+
+// ScheduleProductPriceChange godoc
+// @Summary Schedule a price change
+// @Description Set prices of a product at a future time. Each price replaces the product's price in the same currency and market or is added.
+// @Tags pricing
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Param change body ScheduledPriceChangeInput true "Effective time and prices"
+// @Success 201 {object} models.ScheduledPriceChange
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/prices/schedule [post]
+func ScheduleProductPriceChange(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return
+	}
+
+	var input ScheduledPriceChangeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+	if !input.EffectiveAt.After(time.Now()) {
+		p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "The request body contains invalid fields")
+		p.Errors = []problem.FieldError{{Field: "effective_at", Code: "future", Message: "must be in the future"}}
+		problem.Abort(c, p)
+		return
+	}
+	if p := invalidFields(catalog.DuplicatePrices(input.Prices)); p != nil {
+		problem.Abort(c, *p)
+		return
+	}
+
+	change := models.ScheduledPriceChange{
+		ProductID:   id,
+		Prices:      input.Prices,
+		EffectiveAt: input.EffectiveAt,
+	}
+	if err := repositories.NewPriceRepository().Schedule(c.Request.Context(), &change); err != nil {
+		respondError(c, err, "product", "Failed to schedule price change")
+		return
+	}
+
+	c.JSON(http.StatusCreated, change)
+}
+
+// CancelProductPriceChange godoc
+// @Summary Cancel a scheduled price change
+// @Description Cancel a price change that has not been applied yet
+// @Tags pricing
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Param schedule_id path string true "Scheduled price change ID"
+// @Success 200 {object} models.ScheduledPriceChange
+// @Failure 400 {object} problem.Problem "Invalid ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Scheduled price change not found"
+// @Failure 409 {object} problem.Problem "Price change already applied or cancelled"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/prices/schedule/{schedule_id} [delete]
+func CancelProductPriceChange(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return
+	}
+	scheduleID, err := uuid.Parse(c.Param("schedule_id"))
+	if err != nil {
+		problem.Abort(c, invalidID("scheduled_price_change"))
+		return
+	}
+
+	change, err := repositories.NewPriceRepository().Cancel(c.Request.Context(), id, scheduleID)
+	if err != nil {
+		respondError(c, err, "scheduled_price_change", "Failed to cancel price change")
+		return
+	}
+
+	c.JSON(http.StatusOK, change)
+}
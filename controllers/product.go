@@ -1,9 +1,10 @@
 package controllers
 
 import (
-	"go-product-api/events"
+	"errors"
+	"go-product-api/catalog"
 	"go-product-api/models"
-	"go-product-api/repositories"
+	"go-product-api/problem"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,116 +13,246 @@ import (
 
 // GetProducts godoc
 // @Summary Get all products
-// @Description Get list of all products from Elasticsearch
+// @Description Get a page of the products, 100 unless limit says otherwise, from Elasticsearch, or from PostgreSQL when strong consistency is requested or Elasticsearch is unavailable
 // @Tags products
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param consistency query string false "Set to strong to read from PostgreSQL" Enums(eventual, strong)
+// @Param X-Consistency header string false "Alternative to the consistency query parameter"
+// @Param q query string false "Only products whose name, SKU, description or tags match this text, best matches first"
+// @Param currency query string false "ISO 4217 currency to filter and sort by; required with min_price, max_price and sort"
+// @Param min_price query int false "Minimum price in minor units of currency"
+// @Param max_price query int false "Maximum price in minor units of currency"
+// @Param sort query string false "Sort by price in currency" Enums(price_asc, price_desc)
+// @Param in_stock query bool false "Only products with (true) or without (false) available stock"
+// @Param option query []string false "Only products with a variant having all these option values, each as name:value, e.g. size:M" collectionFormat(multi)
+// @Param display_currency query string false "ISO 4217 currency to show display_price in"
+// @Param market query string false "ISO 3166-1 alpha-2 market whose prices are preferred for display_price"
+// @Param limit query int false "Maximum number of products to return, 100 by default" minimum(1) maximum(500)
+// @Param offset query int false "Number of products to skip; offset plus limit must be at most 10000" minimum(0)
 // @Success 200 {array} models.Product
+// @Failure 400 {object} problem.Problem "Invalid query parameters"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
 // @Router /products [get]
 func GetProducts(c *gin.Context) {
-	esRepo := repositories.NewElasticsearchRepository()
-	products, err := esRepo.FindAll()
+	var query catalog.ListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		problem.Abort(c, problem.FromQueryBindError(err))
+		return
+	}
+
+	filter, err := query.Filter()
+	if err != nil {
+		problem.Abort(c, queryProblem(err))
+		return
+	}
+
+	pricer, err := catalog.NewPricer(c.Request.Context(), query.DisplayQuery)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products: " + err.Error()})
+		respondError(c, err, "product", "Failed to fetch exchange rates")
 		return
 	}
 
+	products, err := listProducts(c, filter)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch products")
+		return
+	}
+	for i := range products {
+		pricer.Apply(&products[i])
+	}
+
 	c.JSON(http.StatusOK, products)
 }
 
 // GetProduct godoc
 // @Summary Get product by ID
-// @Description Get product details by product ID from Elasticsearch
+// @Description Get product details by product ID from Elasticsearch. Recently created products that are not indexed yet, reads with strong consistency and reads while Elasticsearch is unavailable are served from PostgreSQL. With as_of the product is replayed from the event store as it was at that time.
 // @Tags products
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
 // @Param id path string true "Product ID"
+// @Param consistency query string false "Set to strong to read from PostgreSQL" Enums(eventual, strong)
+// @Param X-Consistency header string false "Alternative to the consistency query parameter"
+// @Param display_currency query string false "ISO 4217 currency to show display_price in"
+// @Param market query string false "ISO 3166-1 alpha-2 market whose prices are preferred for display_price"
+// @Param as_of query string false "RFC 3339 time to read the product as of, e.g. 2024-05-01T12:00:00Z"
 // @Success 200 {object} models.Product
-// @Failure 404 {object} object "Product not found"
+// @Failure 400 {object} problem.Problem "Invalid product ID or query parameters"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
 // @Router /products/{id} [get]
 func GetProduct(c *gin.Context) {
-	esRepo := repositories.NewElasticsearchRepository()
-	products, err := esRepo.FindAll()
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products: " + err.Error()})
+		problem.Abort(c, invalidID("product"))
 		return
 	}
 
-	c.JSON(http.StatusOK, products)
+	var query catalog.ProductQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		problem.Abort(c, problem.FromQueryBindError(err))
+		return
+	}
+
+	pricer, err := catalog.NewPricer(c.Request.Context(), query.DisplayQuery)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch exchange rates")
+		return
+	}
+
+	var (
+		product models.Product
+		source  catalog.Source
+	)
+	if query.AsOf != nil {
+		product, source, err = catalog.GetAt(c.Request.Context(), id, *query.AsOf)
+	} else {
+		product, source, err = catalog.Get(c.Request.Context(), id, strongConsistency(c))
+	}
+	setReadSource(c, source)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch product")
+		return
+	}
+	pricer.Apply(&product)
+
+	c.JSON(http.StatusOK, product)
+}
+
+// GetProductBySKU godoc
+// @Summary Get product by SKU
+// @Description Get product details by SKU from Elasticsearch, or from PostgreSQL when strong consistency is requested or Elasticsearch is unavailable
+// @Tags products
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param sku path string true "Product SKU"
+// @Param consistency query string false "Set to strong to read from PostgreSQL" Enums(eventual, strong)
+// @Param X-Consistency header string false "Alternative to the consistency query parameter"
+// @Param display_currency query string false "ISO 4217 currency to show display_price in"
+// @Param market query string false "ISO 3166-1 alpha-2 market whose prices are preferred for display_price"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} problem.Problem "Invalid query parameters"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/sku/{sku} [get]
+func GetProductBySKU(c *gin.Context) {
+	var query catalog.DisplayQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		problem.Abort(c, problem.FromQueryBindError(err))
+		return
+	}
+
+	pricer, err := catalog.NewPricer(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch exchange rates")
+		return
+	}
+
+	product, source, err := catalog.GetBySKU(c.Request.Context(), c.Param("sku"), strongConsistency(c))
+	setReadSource(c, source)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch product")
+		return
+	}
+	pricer.Apply(&product)
+
+	c.JSON(http.StatusOK, product)
 }
 
 // CreateProduct godoc
 // @Summary Create new product
-// @Description Create a new product entry in PostgreSQL and send event to Kafka
+// @Description Create a new product entry in PostgreSQL and send event to Kafka. The SKU must be unique within the tenant and status defaults to draft.
 // @Tags products
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
 // @Param product body models.Product true "Product data"
 // @Success 201 {object} models.Product
-// @Failure 400 {object} object "Invalid input"
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 409 {object} problem.Problem "SKU already in use"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
 // @Router /products [post]
 func CreateProduct(c *gin.Context) {
 	var input models.Product
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
 		return
 	}
 
-	pgRepo := repositories.NewPostgresRepository()
-	if err := pgRepo.Create(&input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product: " + err.Error()})
+	product, err := catalog.Create(c.Request.Context(), input)
+	if errors.Is(err, catalog.ErrNotPublished) {
+		respondPublishError(c, err, "Product created but failed to publish event")
 		return
 	}
-
-	if err := events.PublishProductEvent(events.ProductCreated, input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Product created but failed to publish event: " + err.Error()})
+	if err != nil {
+		respondError(c, err, "product", "Failed to create product")
 		return
 	}
 
-	c.JSON(http.StatusCreated, input)
+	c.JSON(http.StatusCreated, product)
 }
 
 // UpdateProduct godoc
 // @Summary Update product
-// @Description Update existing product by ID in PostgreSQL and send event to Kafka
+// @Description Update existing product by ID in PostgreSQL and send event to Kafka. Status is kept when omitted.
 // @Tags products
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
 // @Param id path string true "Product ID"
 // @Param product body models.Product true "Updated product data"
 // @Success 200 {object} models.Product
-// @Failure 400 {object} object "Invalid input"
-// @Failure 404 {object} object "Product not found"
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 409 {object} problem.Problem "SKU already in use, or options still used by a variant"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
 // @Router /products/{id} [put]
 func UpdateProduct(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
-		return
-	}
-
-	pgRepo := repositories.NewPostgresRepository()
-	product, err := pgRepo.FindByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		problem.Abort(c, invalidID("product"))
 		return
 	}
 
 	var input models.Product
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
 		return
 	}
 
-	product.Name = input.Name
-	product.Description = input.Description
-	product.Price = input.Price
-
-	if err := pgRepo.Update(&product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product: " + err.Error()})
+	product, err := catalog.Update(c.Request.Context(), id, input)
+	if errors.Is(err, catalog.ErrNotPublished) {
+		respondPublishError(c, err, "Product updated but failed to publish event")
 		return
 	}
-
-	if err := events.PublishProductEvent(events.ProductUpdated, product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Product updated but failed to publish event: " + err.Error()})
+	if err != nil {
+		respondError(c, err, "product", "Failed to update product")
 		return
 	}
 
@@ -133,31 +264,31 @@ func UpdateProduct(c *gin.Context) {
 // @Description Delete product by ID from PostgreSQL and send event to Kafka
 // @Tags products
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
 // @Param id path string true "Product ID"
 // @Success 200 {object} object "message: Product deleted"
-// @Failure 404 {object} object "Product not found"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
 // @Router /products/{id} [delete]
 func DeleteProduct(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		problem.Abort(c, invalidID("product"))
 		return
 	}
 
-	pgRepo := repositories.NewPostgresRepository()
-	product, err := pgRepo.FindByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	_, err = catalog.Delete(c.Request.Context(), id)
+	if errors.Is(err, catalog.ErrNotPublished) {
+		respondPublishError(c, err, "Product deleted but failed to publish event")
 		return
 	}
-
-	if err := pgRepo.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product: " + err.Error()})
-		return
-	}
-
-	if err := events.PublishProductEvent(events.ProductDeleted, product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Product deleted but failed to publish event: " + err.Error()})
+	if err != nil {
+		respondError(c, err, "product", "Failed to delete product")
 		return
 	}
 
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-product-api/internal/testutil"
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// postProduct runs a create request in tenant-a and decodes the problem it
+// is expected to fail with.
+func postProduct(t *testing.T, body string) (*httptest.ResponseRecorder, problem.Problem) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), "tenant-a"))
+	})
+	r.POST("/products", CreateProduct)
+
+	req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, problem.ContentType) {
+		t.Fatalf("got content type %q, want %q: %s", got, problem.ContentType, w.Body)
+	}
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	return w, p
+}
+
+func TestCreateProductReportsEveryInvalidFieldByJSONName(t *testing.T) {
+	w, p := postProduct(t, `{"name":"Shoe","status":"sold","prices":[{"currency":"dollars","amount":-1}]}`)
+
+	if w.Code != http.StatusBadRequest || p.Code != problem.CodeValidationFailed || p.Instance != "/products" {
+		t.Fatalf("got status %d, problem %+v; want a validation_failed problem for /products", w.Code, p)
+	}
+	want := map[string]string{
+		"sku":                "required",
+		"status":             "oneof",
+		"prices[0].currency": "iso4217",
+		"prices[0].amount":   "gte",
+	}
+	got := make(map[string]string)
+	for _, fe := range p.Errors {
+		got[fe.Field] = fe.Code
+		if fe.Message == "" {
+			t.Errorf("field %s has no message", fe.Field)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got field errors %v, want %v", got, want)
+	}
+	for field, code := range want {
+		if got[field] != code {
+			t.Errorf("field %s: got code %q, want %q", field, got[field], code)
+		}
+	}
+}
+
+func TestCreateProductRejectsBodiesThatDoNotDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		code  string
+		field string
+	}{
+		{"malformed", `{"sku":`, problem.CodeMalformedBody, ""},
+		{"wrong type", `{"sku":"SKU-1","name":"Shoe","prices":"free"}`, problem.CodeValidationFailed, "prices"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, p := postProduct(t, tt.body)
+			if w.Code != http.StatusBadRequest || p.Code != tt.code {
+				t.Fatalf("got status %d, code %q; want %d, %q", w.Code, p.Code, http.StatusBadRequest, tt.code)
+			}
+			if tt.field != "" && (len(p.Errors) != 1 || p.Errors[0].Field != tt.field) {
+				t.Fatalf("got field errors %+v, want one for %s", p.Errors, tt.field)
+			}
+		})
+	}
+}
+
+func TestCreateProductWithSKUInUseConflicts(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	mock.ExpectBegin()
+	// The product is bound to the caller's tenant and starts as a draft.
+	mock.ExpectExec(`INSERT INTO "products"`).
+		WithArgs(sqlmock.AnyArg(), "tenant-a", "SKU-1", "Shoe", "", models.ProductStatusDraft,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	w, p := postProduct(t, `{"sku":"SKU-1","name":"Shoe","prices":[{"currency":"USD","amount":1999}]}`)
+	if w.Code != http.StatusConflict || p.Code != "product_"+problem.CodeConflict {
+		t.Fatalf("got status %d, code %q; want %d, product_conflict", w.Code, p.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"go-product-api/catalog"
+	"go-product-api/models"
+	"go-product-api/repositories"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	consistencyHeader = "X-Consistency"
+	readSourceHeader  = "X-Read-Source"
+	degradedHeader    = "X-Degraded-Mode"
+)
+
+// listProducts serves the product list like catalog.List and reports where
+// it was read from.
+func listProducts(c *gin.Context, filter repositories.ProductFilter) ([]models.Product, error) {
+	products, source, err := catalog.List(c.Request.Context(), filter, strongConsistency(c))
+	setReadSource(c, source)
+	return products, err
+}
+
+// setReadSource tells the client where a read was served from and whether
+// it was degraded by Elasticsearch being unavailable.
+func setReadSource(c *gin.Context, source catalog.Source) {
+	if source.Degraded {
+		c.Header(degradedHeader, "elasticsearch-unavailable")
+	}
+	if source.Name != "" {
+		c.Header(readSourceHeader, source.Name)
+	}
+}
+
+// strongConsistency reports whether the client asked to read its own writes,
+// either with ?consistency=strong or an X-Consistency: strong header. Such
+// reads bypass Elasticsearch and go straight to Postgres.
+func strongConsistency(c *gin.Context) bool {
+	value := c.Query("consistency")
+	if value == "" {
+		value = c.GetHeader(consistencyHeader)
+	}
+	return strings.EqualFold(value, "strong")
+}
@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"errors"
+	"go-product-api/config"
+	"go-product-api/events"
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// StockSummary totals a product's stock over all warehouses.
+type StockSummary struct {
+	ProductID  uuid.UUID           `json:"product_id"`
+	OnHand     int64               `json:"on_hand"`
+	Reserved   int64               `json:"reserved"`
+	Available  int64               `json:"available"`
+	InStock    bool                `json:"in_stock"`
+	Warehouses []models.StockLevel `json:"warehouses"`
+}
+
+// StockAdjustmentInput adds Delta units to, or with a negative Delta
+// removes them from, the stock on hand in Warehouse.
+type StockAdjustmentInput struct {
+	Warehouse string `json:"warehouse" binding:"omitempty,max=64"`
+	Delta     int64  `json:"delta" binding:"required"`
+}
+
+type ReservationInput struct {
+	Warehouse  string `json:"warehouse" binding:"omitempty,max=64"`
+	Quantity   int64  `json:"quantity" binding:"required,gt=0"`
+	TTLSeconds int    `json:"ttl_seconds" binding:"omitempty,gt=0"`
+}
+
+// GetProductStock godoc
+// @Summary Get product stock
+// @Description Get the stock of a product per warehouse and in total
+// @Tags inventory
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Success 200 {object} StockSummary
+// @Failure 400 {object} problem.Problem "Invalid product ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/stock [get]
+func GetProductStock(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return
+	}
+
+	levels, err := repositories.NewStockRepository().FindByProduct(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch stock")
+		return
+	}
+
+	summary := StockSummary{ProductID: id, Warehouses: levels}
+	for _, level := range levels {
+		summary.OnHand += level.OnHand
+		summary.Reserved += level.Reserved
+		summary.Available += level.Available
+	}
+	summary.InStock = summary.Available > 0
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// AdjustProductStock godoc
+// @Summary Adjust product stock
+// @Description Add units to the stock on hand in a warehouse, or remove them with a negative delta. Reserved units cannot be removed.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Param adjustment body StockAdjustmentInput true "Warehouse and change in units"
+// @Success 200 {object} models.StockLevel
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 409 {object} problem.Problem "Not enough unreserved stock to remove"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/stock/adjustments [post]
+func AdjustProductStock(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return
+	}
+
+	var input StockAdjustmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+	if input.Warehouse == "" {
+		input.Warehouse = config.DefaultWarehouse
+	}
+
+	level, err := repositories.NewStockRepository().Adjust(c.Request.Context(), id, input.Warehouse, input.Delta)
+	if err != nil {
+		respondError(c, err, "product", "Failed to adjust stock")
+		return
+	}
+
+	if err := events.PublishStockEvent(c.Request.Context(), events.StockEvent{Type: events.StockAdjusted, Level: level}); err != nil {
+		respondPublishError(c, err, "Stock adjusted but failed to publish event")
+		return
+	}
+
+	c.JSON(http.StatusOK, level)
+}
+
+// ReserveProductStock godoc
+// @Summary Reserve product stock
+// @Description Hold units in a warehouse until the reservation is committed, released or expires after ttl_seconds
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Param reservation body ReservationInput true "Warehouse, quantity and time to live"
+// @Success 201 {object} models.Reservation
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 409 {object} problem.Problem "Not enough stock available"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/reservations [post]
+func ReserveProductStock(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return
+	}
+
+	var input ReservationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+	if input.Warehouse == "" {
+		input.Warehouse = config.DefaultWarehouse
+	}
+	ttl := reservationTTL(input.TTLSeconds)
+
+	reservation, level, err := repositories.NewStockRepository().Reserve(c.Request.Context(), id, input.Warehouse, input.Quantity, time.Now().Add(ttl))
+	if err != nil {
+		respondError(c, err, "product", "Failed to reserve stock")
+		return
+	}
+
+	event := events.StockEvent{Type: events.StockReserved, Level: level, Reservation: &reservation}
+	if err := events.PublishStockEvent(c.Request.Context(), event); err != nil {
+		respondPublishError(c, err, "Stock reserved but failed to publish event")
+		return
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// GetReservation godoc
+// @Summary Get reservation
+// @Description Get a stock reservation by ID
+// @Tags inventory
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Reservation ID"
+// @Success 200 {object} models.Reservation
+// @Failure 400 {object} problem.Problem "Invalid reservation ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Reservation not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /reservations/{id} [get]
+func GetReservation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("reservation"))
+		return
+	}
+
+	reservation, err := repositories.NewStockRepository().FindReservation(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "reservation", "Failed to fetch reservation")
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// CommitReservation godoc
+// @Summary Commit reservation
+// @Description Take the reserved units out of stock, e.g. once an order is paid
+// @Tags inventory
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Reservation ID"
+// @Success 200 {object} models.Reservation
+// @Failure 400 {object} problem.Problem "Invalid reservation ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Reservation not found"
+// @Failure 409 {object} problem.Problem "Reservation is not pending or has expired"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /reservations/{id}/commit [post]
+func CommitReservation(c *gin.Context) {
+	closeReservation(c, events.StockCommitted)
+}
+
+// ReleaseReservation godoc
+// @Summary Release reservation
+// @Description Return the reserved units to the available stock, e.g. when a cart is abandoned
+// @Tags inventory
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Reservation ID"
+// @Success 200 {object} models.Reservation
+// @Failure 400 {object} problem.Problem "Invalid reservation ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Reservation not found"
+// @Failure 409 {object} problem.Problem "Reservation is not pending"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /reservations/{id}/release [post]
+func ReleaseReservation(c *gin.Context) {
+	closeReservation(c, events.StockReleased)
+}
+
+func closeReservation(c *gin.Context, eventType events.EventType) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("reservation"))
+		return
+	}
+
+	stockRepo := repositories.NewStockRepository()
+	var reservation models.Reservation
+	var level models.StockLevel
+	if eventType == events.StockCommitted {
+		reservation, level, err = stockRepo.Commit(c.Request.Context(), id)
+	} else {
+		reservation, level, err = stockRepo.Release(c.Request.Context(), id)
+	}
+
+	// A commit that came too late still expired the reservation, which
+	// changed the stock and must be announced.
+	if errors.Is(err, repositories.ErrReservationExpired) {
+		eventType = events.ReservationExpired
+		event := events.StockEvent{Type: eventType, Level: level, Reservation: &reservation}
+		if pubErr := events.PublishStockEvent(c.Request.Context(), event); pubErr != nil {
+			respondPublishError(c, pubErr, "Reservation expired but failed to publish event")
+			return
+		}
+	}
+	if err != nil {
+		respondError(c, err, "reservation", "Failed to update reservation")
+		return
+	}
+
+	event := events.StockEvent{Type: eventType, Level: level, Reservation: &reservation}
+	if err := events.PublishStockEvent(c.Request.Context(), event); err != nil {
+		respondPublishError(c, err, "Reservation updated but failed to publish event")
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// reservationTTL turns a requested ttl_seconds into the hold duration. It
+// clamps to ReservationMaxTTL before converting, as a large number of
+// seconds would overflow the Duration.
+func reservationTTL(seconds int) time.Duration {
+	if seconds <= 0 {
+		return config.ReservationTTL
+	}
+	if seconds >= int(config.ReservationMaxTTL/time.Second) {
+		return config.ReservationMaxTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
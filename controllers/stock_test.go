@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go-product-api/config"
+)
+
+func TestReservationTTLClampsBeforeOverflowing(t *testing.T) {
+	previousTTL, previousMax := config.ReservationTTL, config.ReservationMaxTTL
+	config.ReservationTTL, config.ReservationMaxTTL = 15*time.Minute, 24*time.Hour
+	t.Cleanup(func() { config.ReservationTTL, config.ReservationMaxTTL = previousTTL, previousMax })
+
+	tests := []struct {
+		seconds int
+		want    time.Duration
+	}{
+		{0, 15 * time.Minute},
+		{60, time.Minute},
+		{86400, 24 * time.Hour},
+		{86401, 24 * time.Hour},
+		// Multiplied by time.Second these wrap to a negative or short hold.
+		{math.MaxInt64 / int(time.Second) * 2, 24 * time.Hour},
+		{math.MaxInt, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := reservationTTL(tt.seconds); got != tt.want {
+			t.Errorf("reservationTTL(%d) = %v, want %v", tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-product-api/config"
+	"go-product-api/logging"
+	"go-product-api/problem"
+	"go-product-api/stream"
+	"go-product-api/tenant"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+)
+
+// The browser's same-origin check is the default; the admin UI is served
+// from the API's own origin.
+var upgrader = websocket.Upgrader{}
+
+// StreamProducts godoc
+// @Summary Stream product changes
+// @Description Push the tenant's product_created, product_updated and product_deleted events as server-sent events as they happen. The id of each event resumes the stream after it when sent back as Last-Event-ID. A resume that reaches too far back starts with a reset event, after which products should be reloaded. Idle streams get a comment every few seconds; clients that fall behind are disconnected and should reconnect.
+// @Tags products
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param Last-Event-ID header string false "Resume after this event"
+// @Param product_id query []string false "Only events of these products" collectionFormat(multi)
+// @Param type query []string false "Only events of these types" Enums(product_created, product_updated, product_deleted) collectionFormat(multi)
+// @Param last_event_id query string false "Resume after this event, for clients that cannot set Last-Event-ID"
+// @Success 200 {object} stream.Message "One per event"
+// @Failure 400 {object} problem.Problem "Invalid query parameters or event ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 503 {object} problem.Problem "Server is shutting down or too many streams are resuming"
+// @Router /products/stream [get]
+func StreamProducts(c *gin.Context) {
+	var query stream.Query
+	if err := c.ShouldBindQuery(&query); err != nil {
+		problem.Abort(c, problem.FromQueryBindError(err))
+		return
+	}
+	if id := c.GetHeader("Last-Event-ID"); id != "" {
+		// The header takes precedence and is held to the same limits.
+		query.LastEventID = id
+		if err := binding.Validator.ValidateStruct(&query); err != nil {
+			problem.Abort(c, problem.FromQueryBindError(err))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	session, ok := openStream(c, ctx, query)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	rc := http.NewResponseController(c.Writer)
+	heartbeat := time.NewTicker(config.StreamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case msg, open := <-session.Messages():
+			if !open {
+				logStreamEnd(c, session.Err())
+				return
+			}
+			rc.SetWriteDeadline(time.Now().Add(config.StreamWriteTimeout))
+			err = sse.Encode(c.Writer, sse.Event{Id: msg.ID, Event: string(msg.Type), Data: msg})
+		case <-heartbeat.C:
+			rc.SetWriteDeadline(time.Now().Add(config.StreamWriteTimeout))
+			_, err = c.Writer.WriteString(": heartbeat\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// StreamProductsWebSocket godoc
+// @Summary Stream product changes over WebSocket
+// @Description Push the tenant's product events as JSON text messages over a WebSocket, with the same filters, resumption and reset event as the server-sent event stream. The server pings idle connections; clients that fall behind are closed with status 1013 and should reconnect with last_event_id.
+// @Tags products
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param product_id query []string false "Only events of these products" collectionFormat(multi)
+// @Param type query []string false "Only events of these types" Enums(product_created, product_updated, product_deleted) collectionFormat(multi)
+// @Param last_event_id query string false "Resume after this event"
+// @Success 101 {object} stream.Message "One per event"
+// @Failure 400 {object} problem.Problem "Invalid query parameters or event ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 503 {object} problem.Problem "Server is shutting down or too many streams are resuming"
+// @Router /products/stream/ws [get]
+func StreamProductsWebSocket(c *gin.Context) {
+	var query stream.Query
+	if err := c.ShouldBindQuery(&query); err != nil {
+		problem.Abort(c, problem.FromQueryBindError(err))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	session, ok := openStream(c, ctx, query)
+	if !ok {
+		return
+	}
+
+	// The upgrader has already responded when it fails.
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Clients send nothing but control frames; reading handles them and
+	// notices when the client goes away or stops answering pings.
+	readDeadline := func() {
+		conn.SetReadDeadline(time.Now().Add(config.StreamHeartbeatInterval + config.StreamWriteTimeout))
+	}
+	readDeadline()
+	conn.SetPongHandler(func(string) error {
+		readDeadline()
+		return nil
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(config.StreamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case msg, open := <-session.Messages():
+			if !open {
+				err := session.Err()
+				logStreamEnd(c, err)
+				closeWebSocket(conn, err)
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(config.StreamWriteTimeout))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(config.StreamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// openStream opens the session a stream handler sends, responding with a
+// problem when it cannot.
+func openStream(c *gin.Context, ctx context.Context, query stream.Query) (*stream.Session, bool) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		respondError(c, err, "product", "Failed to open product stream")
+		return nil, false
+	}
+
+	session, err := stream.Open(ctx, tenantID, query.LastEventID, query.Filter())
+	if errors.Is(err, stream.ErrInvalidEventID) {
+		p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "The query string contains invalid parameters")
+		p.Errors = []problem.FieldError{{Field: "last_event_id", Code: "event_id", Message: "is not an event ID from this stream"}}
+		problem.Abort(c, p)
+		return nil, false
+	}
+	if err != nil {
+		respondError(c, err, "product", "Failed to open product stream")
+		return nil, false
+	}
+	return session, true
+}
+
+func closeWebSocket(conn *websocket.Conn, err error) {
+	code, text := websocket.CloseNormalClosure, ""
+	switch {
+	case errors.Is(err, stream.ErrLagged):
+		code, text = websocket.CloseTryAgainLater, err.Error()
+	case errors.Is(err, stream.ErrShutdown):
+		code, text = websocket.CloseGoingAway, err.Error()
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(config.StreamWriteTimeout))
+}
+
+func logStreamEnd(c *gin.Context, err error) {
+	if errors.Is(err, stream.ErrLagged) {
+		logging.FromContext(c.Request.Context()).Warn("Disconnected slow product stream client")
+	}
+}
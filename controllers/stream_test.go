@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamProductsLimitsLastEventIDHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/products/stream", StreamProducts)
+
+	req := httptest.NewRequest(http.MethodGet, "/products/stream", nil)
+	req.Header.Set("Last-Event-ID", strings.Repeat("0", 1025))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"last_event_id"`) {
+		t.Fatalf("got status %d, body %s; want last_event_id rejected", w.Code, w.Body)
+	}
+}
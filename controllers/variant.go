@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"go-product-api/cache"
+	"go-product-api/catalog"
+	"go-product-api/events"
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetProductVariants godoc
+// @Summary List product variants
+// @Description List the variants of a product
+// @Tags variants
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Success 200 {array} models.ProductVariant
+// @Failure 400 {object} problem.Problem "Invalid product ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/variants [get]
+func GetProductVariants(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return
+	}
+
+	variants, err := repositories.NewVariantRepository().FindByProduct(c.Request.Context(), productID)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch variants")
+		return
+	}
+
+	c.JSON(http.StatusOK, variants)
+}
+
+// GetProductVariant godoc
+// @Summary Get product variant
+// @Description Get a variant of a product by ID
+// @Tags variants
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Param variant_id path string true "Variant ID"
+// @Success 200 {object} models.ProductVariant
+// @Failure 400 {object} problem.Problem "Invalid product or variant ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Variant not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/variants/{variant_id} [get]
+func GetProductVariant(c *gin.Context) {
+	productID, variantID, ok := variantIDs(c)
+	if !ok {
+		return
+	}
+
+	variant, err := repositories.NewVariantRepository().FindByID(c.Request.Context(), productID, variantID)
+	if err != nil {
+		respondError(c, err, "variant", "Failed to fetch variant")
+		return
+	}
+
+	c.JSON(http.StatusOK, variant)
+}
+
+// CreateProductVariant godoc
+// @Summary Create product variant
+// @Description Create a variant with a value for every option of the product. The product is re-indexed through a product_updated event.
+// @Tags variants
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Param variant body models.ProductVariant true "Variant data"
+// @Success 201 {object} models.ProductVariant
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Product not found"
+// @Failure 409 {object} problem.Problem "SKU or option values already in use"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/variants [post]
+func CreateProductVariant(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return
+	}
+
+	product, err := repositories.NewPostgresRepository().FindByID(c.Request.Context(), productID)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch product")
+		return
+	}
+
+	input, ok := bindVariant(c, product)
+	if !ok {
+		return
+	}
+	input.ProductID = productID
+
+	if err := repositories.NewVariantRepository().Create(c.Request.Context(), &input); err != nil {
+		respondError(c, err, "variant", "Failed to create variant")
+		return
+	}
+
+	if !publishVariantChange(c, productID, "Variant created but failed to publish event") {
+		return
+	}
+
+	c.JSON(http.StatusCreated, input)
+}
+
+// UpdateProductVariant godoc
+// @Summary Update product variant
+// @Description Replace the SKU, options, prices and stock of a variant. The product is re-indexed through a product_updated event.
+// @Tags variants
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Param variant_id path string true "Variant ID"
+// @Param variant body models.ProductVariant true "Variant data"
+// @Success 200 {object} models.ProductVariant
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Product or variant not found"
+// @Failure 409 {object} problem.Problem "SKU or option values already in use"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/variants/{variant_id} [put]
+func UpdateProductVariant(c *gin.Context) {
+	productID, variantID, ok := variantIDs(c)
+	if !ok {
+		return
+	}
+
+	product, err := repositories.NewPostgresRepository().FindByID(c.Request.Context(), productID)
+	if err != nil {
+		respondError(c, err, "product", "Failed to fetch product")
+		return
+	}
+
+	input, ok := bindVariant(c, product)
+	if !ok {
+		return
+	}
+	input.ID = variantID
+	input.ProductID = productID
+
+	variantRepo := repositories.NewVariantRepository()
+	if err := variantRepo.Update(c.Request.Context(), &input); err != nil {
+		respondError(c, err, "variant", "Failed to update variant")
+		return
+	}
+	variant, err := variantRepo.FindByID(c.Request.Context(), productID, variantID)
+	if err != nil {
+		respondError(c, err, "variant", "Failed to fetch variant")
+		return
+	}
+
+	if !publishVariantChange(c, productID, "Variant updated but failed to publish event") {
+		return
+	}
+
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeleteProductVariant godoc
+// @Summary Delete product variant
+// @Description Delete a variant. The product is re-indexed through a product_updated event.
+// @Tags variants
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Product ID"
+// @Param variant_id path string true "Variant ID"
+// @Success 200 {object} object "message: Variant deleted"
+// @Failure 400 {object} problem.Problem "Invalid product or variant ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Variant not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /products/{id}/variants/{variant_id} [delete]
+func DeleteProductVariant(c *gin.Context) {
+	productID, variantID, ok := variantIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := repositories.NewVariantRepository().Delete(c.Request.Context(), productID, variantID); err != nil {
+		respondError(c, err, "variant", "Failed to delete variant")
+		return
+	}
+
+	if !publishVariantChange(c, productID, "Variant deleted but failed to publish event") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted"})
+}
+
+func variantIDs(c *gin.Context) (productID, variantID uuid.UUID, ok bool) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("product"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	variantID, err = uuid.Parse(c.Param("variant_id"))
+	if err != nil {
+		problem.Abort(c, invalidID("variant"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	return productID, variantID, true
+}
+
+// bindVariant binds the request body and checks its options against the
+// option definitions of the product and its prices for duplicates.
+func bindVariant(c *gin.Context, product models.Product) (models.ProductVariant, bool) {
+	var input models.ProductVariant
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return input, false
+	}
+	input.ID, input.CreatedAt, input.UpdatedAt = uuid.Nil, time.Time{}, time.Time{}
+	if input.Options == nil {
+		input.Options = models.VariantOptions{}
+	}
+
+	if p := invalidFields(catalog.VariantOptionErrors(product.Options, input.Options)); p != nil {
+		problem.Abort(c, *p)
+		return input, false
+	}
+
+	prices := make([]models.ProductPrice, len(input.Prices))
+	for i, price := range input.Prices {
+		prices[i] = models.ProductPrice{Currency: price.Currency, Market: price.Market}
+	}
+	if p := invalidFields(catalog.DuplicatePrices(prices)); p != nil {
+		problem.Abort(c, *p)
+		return input, false
+	}
+	return input, true
+}
+
+// publishVariantChange sends the product with its current variants through
+// the product event stream so the index and caches pick the change up.
+func publishVariantChange(c *gin.Context, productID uuid.UUID, detail string) bool {
+	ctx := c.Request.Context()
+	cache.Invalidate(ctx, productID)
+
+	product, err := repositories.NewPostgresRepository().FindByID(ctx, productID)
+	if err == nil {
+		err = events.PublishProductEvent(ctx, events.ProductUpdated, product)
+	}
+	if err != nil {
+		respondPublishError(c, err, detail)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"go-product-api/webhooks"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebhookInput configures a webhook subscription. URL must be https and its
+// host must resolve to public addresses only. An empty EventTypes
+// subscribes to every product event. A Secret is generated when creating a
+// subscription without one; on update a Secret rotates it. Active defaults
+// to true, and setting it re-enables a subscription that was disabled after
+// repeated failures.
+type WebhookInput struct {
+	URL        string   `json:"url" binding:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"max=3,dive,oneof=product_created product_updated product_deleted"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16,max=128"`
+	Active     *bool    `json:"active"`
+}
+
+type CreateWebhookResponse struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+type DeliveryListQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+const defaultDeliveryLimit = 50
+
+func unsafeTarget() problem.Problem {
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "The request body contains invalid fields")
+	p.Errors = []problem.FieldError{{Field: "url", Code: "public_https", Message: "must be an https URL whose host resolves to public addresses"}}
+	return p
+}
+
+// GetWebhooks godoc
+// @Summary List webhook subscriptions
+// @Description List the tenant's webhook subscriptions. Secrets are never returned.
+// @Tags webhooks
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Success 200 {array} models.WebhookSubscription
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	subscriptions, err := repositories.NewWebhookRepository().FindAll(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "webhook", "Failed to fetch webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// GetWebhook godoc
+// @Summary Get webhook subscription
+// @Description Get a webhook subscription by ID, including whether it was disabled after repeated failures
+// @Tags webhooks
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} problem.Problem "Invalid webhook ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Webhook not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /webhooks/{id} [get]
+func GetWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("webhook"))
+		return
+	}
+
+	subscription, err := repositories.NewWebhookRepository().FindByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "webhook", "Failed to fetch webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// CreateWebhook godoc
+// @Summary Create webhook subscription
+// @Description Subscribe a URL to product events. The URL must be https and resolve to public addresses, and redirects are not followed. Each delivery is a POST of the event signed with the secret: X-Webhook-Signature is "v1=" and the hex HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>". The secret is only returned in this response.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param webhook body WebhookInput true "Target URL, event types and optional secret"
+// @Success 201 {object} CreateWebhookResponse
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var input WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+	if err := webhooks.CheckTarget(c.Request.Context(), input.URL); err != nil {
+		problem.Abort(c, unsafeTarget())
+		return
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		var err error
+		if secret, err = webhooks.GenerateSecret(); err != nil {
+			respondError(c, err, "webhook", "Failed to generate webhook secret")
+			return
+		}
+	}
+	subscription := models.WebhookSubscription{
+		URL:        input.URL,
+		EventTypes: input.EventTypes,
+		Secret:     secret,
+		Active:     input.Active == nil || *input.Active,
+	}
+	if err := repositories.NewWebhookRepository().Create(c.Request.Context(), &subscription); err != nil {
+		respondError(c, err, "webhook", "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateWebhookResponse{WebhookSubscription: subscription, Secret: secret})
+}
+
+// UpdateWebhook godoc
+// @Summary Update webhook subscription
+// @Description Change the URL, event types or secret of a webhook subscription, or pause and re-enable it. Re-enabling resumes deliveries that are still pending.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Webhook ID"
+// @Param webhook body WebhookInput true "Target URL, event types, optional new secret and active flag"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} problem.Problem "Invalid input"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Webhook not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /webhooks/{id} [put]
+func UpdateWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("webhook"))
+		return
+	}
+
+	var input WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+	if err := webhooks.CheckTarget(c.Request.Context(), input.URL); err != nil {
+		problem.Abort(c, unsafeTarget())
+		return
+	}
+
+	webhookRepo := repositories.NewWebhookRepository()
+	subscription, err := webhookRepo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "webhook", "Failed to fetch webhook")
+		return
+	}
+
+	subscription.URL = input.URL
+	subscription.EventTypes = input.EventTypes
+	if input.Secret != "" {
+		subscription.Secret = input.Secret
+	}
+	active := input.Active == nil || *input.Active
+	if active && !subscription.Active {
+		subscription.ConsecutiveFailures = 0
+		subscription.DisabledAt = nil
+	}
+	subscription.Active = active
+
+	if err := webhookRepo.Update(c.Request.Context(), &subscription); err != nil {
+		respondError(c, err, "webhook", "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook subscription
+// @Description Delete a webhook subscription together with its delivery log. Pending deliveries are dropped.
+// @Tags webhooks
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} object "message: Webhook deleted"
+// @Failure 400 {object} problem.Problem "Invalid webhook ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Webhook not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("webhook"))
+		return
+	}
+
+	if err := repositories.NewWebhookRepository().Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err, "webhook", "Failed to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// GetWebhookDeliveries godoc
+// @Summary List webhook deliveries
+// @Description List the latest deliveries of a webhook subscription, newest first, with the outcome of their latest attempt
+// @Tags webhooks
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "Number of deliveries to return, 1-500, default 50"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} problem.Problem "Invalid webhook ID or query parameters"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Webhook not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("webhook"))
+		return
+	}
+
+	var query DeliveryListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		problem.Abort(c, problem.FromQueryBindError(err))
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultDeliveryLimit
+	}
+
+	deliveries, err := repositories.NewWebhookRepository().FindDeliveries(c.Request.Context(), id, query.Limit)
+	if err != nil {
+		respondError(c, err, "webhook", "Failed to fetch webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// RedeliverWebhook godoc
+// @Summary Redeliver a webhook
+// @Description Queue a delivery to be sent again as a new delivery of the same event, with the same X-Webhook-Event-Id
+// @Tags webhooks
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-Tenant-ID header string false "Tenant to act on, for callers not bound to a tenant"
+// @Param id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Failure 400 {object} problem.Problem "Invalid ID"
+// @Failure 401 {object} problem.Problem "Authentication required"
+// @Failure 403 {object} problem.Problem "Insufficient permissions"
+// @Failure 404 {object} problem.Problem "Webhook delivery not found"
+// @Failure 429 {object} problem.Problem "Rate limit exceeded"
+// @Failure 504 {object} problem.Problem "Backend timed out"
+// @Router /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func RedeliverWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, invalidID("webhook"))
+		return
+	}
+	deliveryID, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		problem.Abort(c, invalidID("webhook_delivery"))
+		return
+	}
+
+	delivery, err := repositories.NewWebhookRepository().Redeliver(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		respondError(c, err, "webhook", "Failed to redeliver webhook")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
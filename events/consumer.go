@@ -1,65 +1,345 @@
 package events
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go-product-api/cache"
 	"go-product-api/config"
+	"go-product-api/logging"
+	"go-product-api/models"
 	"go-product-api/repositories"
-	"log"
+	"go-product-api/tenant"
+	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
-func StartConsumer() {
+// StartConsumer runs the consumer loop until ctx is cancelled. A message
+// that is already being processed is finished and committed before the loop
+// exits, unless it failed and is waiting to be retried; the returned channel
+// is closed once it has.
+func StartConsumer(ctx context.Context) <-chan struct{} {
 	esRepo := repositories.NewElasticsearchRepository()
+	pgRepo := repositories.NewPostgresRepository()
+	stockRepo := repositories.NewStockRepository()
+	eventRepo := repositories.NewProductEventRepository()
+	webhookRepo := repositories.NewWebhookRepository()
 
-	err := config.KafkaConsumer.Subscribe(config.ProductTopic, nil)
+	topics := []string{config.ProductTopic, config.CategoryTopic, config.StockTopic}
+	err := config.KafkaConsumer.SubscribeTopics(topics, nil)
 	if err != nil {
-		log.Fatalf("Failed to subscribe to topic %s: %v", config.ProductTopic, err)
+		logging.Fatal("Failed to subscribe to topics", "kafka.topics", topics, "error.message", err)
 	}
 
+	done := make(chan struct{})
 	go func() {
-		for {
+		defer close(done)
+		for ctx.Err() == nil {
 			msg, err := config.KafkaConsumer.ReadMessage(100 * time.Millisecond)
 			if err != nil {
-				if err.(kafka.Error).Code() == kafka.ErrTimedOut {
+				if kerr, ok := err.(kafka.Error); ok && kerr.Code() == kafka.ErrTimedOut {
 					continue
 				}
-				log.Printf("Consumer error: %v", err)
+				slog.Error("Consumer error", "error.message", err)
 				continue
 			}
-			if err := processMessage(msg, esRepo); err != nil {
-				log.Printf("Error processing message: %v", err)
+			committed := handle(ctx, msg, func() error {
+				return processMessage(msg, esRepo, pgRepo, stockRepo, eventRepo, webhookRepo)
+			})
+			if !committed {
+				break
 			}
 		}
+		slog.Info("kafka consumer stopped")
 	}()
-	log.Println("kafka consumer started")
+	slog.Info("kafka consumer started", "kafka.topics", topics)
+	return done
 }
-func processMessage(msg *kafka.Message, esRepo *repositories.ElasticsearchRepository) error {
-	var event ProductEvent
-	if err := json.Unmarshal(msg.Value, &event); err != nil {
-		return fmt.Errorf("error unmarshaling event: %w", err)
+
+// handle processes msg, retrying failures with backoff, and commits its
+// offset once it was processed or, after config.ConsumerMaxAttempts, sent to
+// the dead letter topic. It returns false without committing when ctx is
+// cancelled first, so the message is delivered again after a restart.
+func handle(ctx context.Context, msg *kafka.Message, process func() error) bool {
+	logger := slog.With(
+		"kafka.topic", *msg.TopicPartition.Topic,
+		"kafka.partition", msg.TopicPartition.Partition,
+		"kafka.offset", int64(msg.TopicPartition.Offset),
+	)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = process(); err == nil {
+			break
+		}
+		logger.Error("Error processing message", "error.message", err, "kafka.attempts", attempt)
+		if attempt >= config.ConsumerMaxAttempts {
+			break
+		}
+		if !sleep(ctx, retryBackoff(attempt)) {
+			return false
+		}
+	}
+
+	// A message given up on is only committed once the dead letter topic
+	// has it.
+	for err != nil {
+		dlqErr := deadLetter(ctx, msg, err)
+		if dlqErr == nil {
+			logger.Warn("Message sent to the dead letter topic", "kafka.dead_letter_topic", config.DeadLetterTopic)
+			break
+		}
+		logger.Error("Failed to send message to the dead letter topic", "error.message", dlqErr)
+		if !sleep(ctx, config.ConsumerMaxBackoff) {
+			return false
+		}
 	}
 
-	log.Printf("Processing %s event for product ID: %s", event.Type, event.Product.ID)
+	if _, err := config.KafkaConsumer.CommitMessage(msg); err != nil {
+		logger.Error("Failed to commit offset", "error.message", err)
+	}
+	return true
+}
+
+// deadLetter copies msg to the dead letter topic, with its origin and the
+// error that made the consumer give up on it in headers.
+func deadLetter(ctx context.Context, msg *kafka.Message, cause error) error {
+	topic := config.DeadLetterTopic
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: deadLetterTopicHeader, Value: []byte(*msg.TopicPartition.Topic)},
+		kafka.Header{Key: deadLetterPartitionHeader, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		kafka.Header{Key: deadLetterOffsetHeader, Value: []byte(msg.TopicPartition.Offset.String())},
+		kafka.Header{Key: deadLetterErrorHeader, Value: []byte(cause.Error())},
+	)
+	return deliver(ctx, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+	})
+}
+
+// retryBackoff returns how long to wait after the given failed attempt: the
+// base backoff doubled for every attempt after the first, capped at the
+// maximum.
+func retryBackoff(attempt int) time.Duration {
+	wait := config.ConsumerBaseBackoff
+	for i := 1; i < attempt && wait < config.ConsumerMaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, config.ConsumerMaxBackoff)
+}
+
+// sleep waits for d and reports whether it did before ctx was done.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func processMessage(msg *kafka.Message, esRepo *repositories.ElasticsearchRepository, pgRepo *repositories.PostgresRepository, stockRepo *repositories.StockRepository, eventRepo *repositories.ProductEventRepository, webhookRepo *repositories.WebhookRepository) (err error) {
+	topic := *msg.TopicPartition.Topic
+	carrier := headerCarrier{&msg.Headers}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+	if id := carrier.Get(requestIDHeader); id != "" {
+		ctx = logging.WithRequestID(ctx, id)
+	}
+	ctx, span := tracer.Start(ctx, topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", topic),
+			attribute.String("messaging.operation", "process"),
+			attribute.Int("messaging.kafka.destination.partition", int(msg.TopicPartition.Partition)),
+			attribute.Int64("messaging.kafka.message.offset", int64(msg.TopicPartition.Offset)),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	logger := logging.FromContext(ctx).With(
+		"kafka.topic", topic,
+		"kafka.partition", msg.TopicPartition.Partition,
+		"kafka.offset", int64(msg.TopicPartition.Offset),
+	)
+
+	switch topic {
+	case config.CategoryTopic:
+		return processCategoryEvent(ctx, span, logger, msg.Value, esRepo, pgRepo)
+	case config.StockTopic:
+		return processStockEvent(ctx, span, logger, msg.Value, esRepo, stockRepo)
+	default:
+		return processProductEvent(ctx, span, logger, msg, esRepo, stockRepo, eventRepo, webhookRepo)
+	}
+}
+
+func processProductEvent(ctx context.Context, span trace.Span, logger *slog.Logger, msg *kafka.Message, esRepo *repositories.ElasticsearchRepository, stockRepo *repositories.StockRepository, eventRepo *repositories.ProductEventRepository, webhookRepo *repositories.WebhookRepository) error {
+	event, err := DecodeProductEvent(msg)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(
+		attribute.String("event.type", string(event.Type)),
+		attribute.String("product.id", event.Product.ID.String()),
+	)
+
+	ctx = tenant.WithTenant(ctx, event.TenantID)
+	logger = logger.With("organization.id", event.TenantID)
+
+	logger.Info("Processing event", "event.action", event.Type, "product.id", event.Product.ID)
+
+	if event.Type != ProductCreated && event.Type != ProductUpdated && event.Type != ProductDeleted {
+		return fmt.Errorf("unknown event type: %s", event.Type)
+	}
+	stored := StoredEvent(msg, event)
+	if err := eventRepo.Append(ctx, &stored); err != nil {
+		return fmt.Errorf("error storing event: %w", err)
+	}
+	queued, err := enqueueWebhooks(ctx, webhookRepo, stored, event)
+	if err != nil {
+		return fmt.Errorf("error queueing webhooks: %w", err)
+	}
+	if queued > 0 {
+		logger.Info("Webhooks queued", "product.id", event.Product.ID, "count", queued)
+	}
 
 	switch event.Type {
 	case ProductCreated, ProductUpdated:
-		if err := esRepo.Index(event.Product); err != nil {
+		// Stock events travel on their own topic and may be consumed before
+		// or after this one, so take the flag from Postgres rather than from
+		// the possibly stale payload.
+		inStock, err := stockRepo.InStock(ctx, event.Product.ID)
+		if err != nil {
+			return fmt.Errorf("error fetching stock: %w", err)
+		}
+		event.Product.InStock = inStock
+		if err := esRepo.Index(ctx, event.Product); err != nil {
 			return fmt.Errorf("error indexing product: %w", err)
 		}
-		log.Printf("Product indexed in Elasticsearch: %s", event.Product.ID)
+		logger.Info("Product indexed in Elasticsearch", "product.id", event.Product.ID)
+		cache.Invalidate(ctx, event.Product.ID)
 
 	case ProductDeleted:
-		if err := esRepo.Delete(event.Product.ID); err != nil {
+		if err := esRepo.Delete(ctx, event.Product.ID); err != nil {
 			return fmt.Errorf("error deleting product: %w", err)
 		}
-		log.Printf("Product deleted from Elasticsearch: %s", event.Product.ID)
+		logger.Info("Product deleted from Elasticsearch", "product.id", event.Product.ID)
+		cache.Invalidate(ctx, event.Product.ID)
+	}
+
+	if event.Audit != nil && config.AuditIndex != "" {
+		if err := esRepo.IndexAuditEntry(ctx, *event.Audit); err != nil {
+			return fmt.Errorf("error indexing audit entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// processCategoryEvent re-indexes the products whose denormalised category
+// paths changed: those in the subtree of a moved category and those filed
+// under a deleted one. Creates and renames leave the paths unchanged.
+func processCategoryEvent(ctx context.Context, span trace.Span, logger *slog.Logger, value []byte, esRepo *repositories.ElasticsearchRepository, pgRepo *repositories.PostgresRepository) error {
+	var event CategoryEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		return fmt.Errorf("error unmarshaling event: %w", err)
+	}
+	span.SetAttributes(
+		attribute.String("event.type", string(event.Type)),
+		attribute.String("category.id", event.Category.ID.String()),
+	)
+
+	ctx = tenant.WithTenant(ctx, event.TenantID)
+	logger = logger.With("organization.id", event.TenantID)
+
+	logger.Info("Processing event", "event.action", event.Type, "category.id", event.Category.ID)
+
+	var products []models.Product
+	switch event.Type {
+	case CategoryCreated, CategoryUpdated:
+		return nil
+
+	case CategoryMoved:
+		if event.PreviousPath == event.Category.Path {
+			return nil
+		}
+		var err error
+		products, err = pgRepo.FindAll(ctx, repositories.ProductFilter{CategoryPath: event.Category.Path})
+		if err != nil {
+			return fmt.Errorf("error fetching products of moved category: %w", err)
+		}
+
+	case CategoryDeleted:
+		for _, id := range event.ProductIDs {
+			product, err := pgRepo.FindByID(ctx, id)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("error fetching product %s: %w", id, err)
+			}
+			products = append(products, product)
+		}
 
 	default:
 		return fmt.Errorf("unknown event type: %s", event.Type)
 	}
 
+	for _, product := range products {
+		if err := esRepo.Index(ctx, product); err != nil {
+			return fmt.Errorf("error re-indexing product %s: %w", product.ID, err)
+		}
+		cache.Invalidate(ctx, product.ID)
+	}
+	logger.Info("Products re-indexed after category change", "category.id", event.Category.ID, "count", len(products))
+	return nil
+}
+
+// processStockEvent keeps the in_stock flag of the indexed product current.
+// The flag is recomputed from Postgres, so replaying or reordering stock
+// events cannot leave it wrong.
+func processStockEvent(ctx context.Context, span trace.Span, logger *slog.Logger, value []byte, esRepo *repositories.ElasticsearchRepository, stockRepo *repositories.StockRepository) error {
+	var event StockEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		return fmt.Errorf("error unmarshaling event: %w", err)
+	}
+	span.SetAttributes(
+		attribute.String("event.type", string(event.Type)),
+		attribute.String("product.id", event.ProductID.String()),
+	)
+
+	ctx = tenant.WithTenant(ctx, event.TenantID)
+	logger = logger.With("organization.id", event.TenantID)
+
+	logger.Info("Processing event", "event.action", event.Type, "product.id", event.ProductID)
+
+	inStock, err := stockRepo.InStock(ctx, event.ProductID)
+	if err != nil {
+		return fmt.Errorf("error fetching stock: %w", err)
+	}
+	if err := esRepo.SetInStock(ctx, event.ProductID, inStock); err != nil {
+		return fmt.Errorf("error updating stock flag: %w", err)
+	}
+	cache.Invalidate(ctx, event.ProductID)
+	logger.Info("Product stock flag updated", "product.id", event.ProductID, "product.in_stock", inStock)
 	return nil
 }
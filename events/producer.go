@@ -1,13 +1,20 @@
 package events
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"go-product-api/config"
+	"go-product-api/logging"
 	"go-product-api/models"
-	"log"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type EventType string
@@ -16,37 +23,163 @@ const (
 	ProductCreated EventType = "product_created"
 	ProductUpdated EventType = "product_updated"
 	ProductDeleted EventType = "product_deleted"
+
+	CategoryCreated EventType = "category_created"
+	CategoryUpdated EventType = "category_updated"
+	CategoryMoved   EventType = "category_moved"
+	CategoryDeleted EventType = "category_deleted"
+
+	StockAdjusted      EventType = "stock_adjusted"
+	StockReserved      EventType = "stock_reserved"
+	StockReleased      EventType = "stock_released"
+	StockCommitted     EventType = "stock_committed"
+	ReservationExpired EventType = "reservation_expired"
+)
+
+// requestIDHeader carries the originating HTTP request ID so consumer logs
+// can be correlated with the request that produced the event.
+const requestIDHeader = "x-request-id"
+
+const tenantIDHeader = "x-tenant-id"
+
+// Headers the consumer adds to a message it sends to the dead letter topic,
+// saying where it came from and why it was given up on.
+const (
+	deadLetterTopicHeader     = "x-dead-letter-topic"
+	deadLetterPartitionHeader = "x-dead-letter-partition"
+	deadLetterOffsetHeader    = "x-dead-letter-offset"
+	deadLetterErrorHeader     = "x-dead-letter-error"
 )
 
+// ProductEvent announces a change to a product. Product is the state after
+// the change, or the last state for a delete, so the events of a product
+// replay to its current state. Audit is the audit entry recorded for the
+// change, if any. OccurredAt is missing from events published before it
+// existed; the consumer falls back to the message timestamp.
 type ProductEvent struct {
-	Type    EventType      `json:"type"`
-	Product models.Product `json:"product"`
+	Type       EventType          `json:"type"`
+	TenantID   string             `json:"tenant_id"`
+	Product    models.Product     `json:"product"`
+	Audit      *models.AuditEntry `json:"audit,omitempty"`
+	OccurredAt time.Time          `json:"occurred_at"`
+}
+
+// CategoryEvent announces a change to the category tree. PreviousPath is
+// set on moves, and ProductIDs lists the products that were filed under a
+// deleted category, so the consumer can re-index the products affected.
+type CategoryEvent struct {
+	Type         EventType       `json:"type"`
+	TenantID     string          `json:"tenant_id"`
+	Category     models.Category `json:"category"`
+	PreviousPath string          `json:"previous_path,omitempty"`
+	ProductIDs   []uuid.UUID     `json:"product_ids,omitempty"`
+}
+
+// StockEvent announces a change to the stock of a product in one warehouse.
+// Level is the stock after the change; Reservation is set for reservation
+// events.
+type StockEvent struct {
+	Type        EventType           `json:"type"`
+	TenantID    string              `json:"tenant_id"`
+	ProductID   uuid.UUID           `json:"product_id"`
+	Level       models.StockLevel   `json:"level"`
+	Reservation *models.Reservation `json:"reservation,omitempty"`
+}
+
+func PublishProductEvent(ctx context.Context, eventType EventType, product models.Product) error {
+	return PublishAuditedProductEvent(ctx, eventType, product, nil)
 }
 
-func PublishProductEvent(eventType EventType, product models.Product) error {
+// PublishAuditedProductEvent publishes a product event together with the
+// audit entry recorded for the change.
+func PublishAuditedProductEvent(ctx context.Context, eventType EventType, product models.Product, entry *models.AuditEntry) error {
 	event := ProductEvent{
-		Type:    eventType,
-		Product: product,
+		Type:       eventType,
+		TenantID:   product.TenantID,
+		Product:    product,
+		Audit:      entry,
+		OccurredAt: time.Now().UTC(),
 	}
+	return publish(ctx, config.ProductTopic, eventType, product.TenantID, product.ID, event,
+		attribute.String("product.id", product.ID.String()))
+}
+
+func PublishCategoryEvent(ctx context.Context, event CategoryEvent) error {
+	event.TenantID = event.Category.TenantID
+	return publish(ctx, config.CategoryTopic, event.Type, event.TenantID, event.Category.ID, event,
+		attribute.String("category.id", event.Category.ID.String()))
+}
+
+func PublishStockEvent(ctx context.Context, event StockEvent) error {
+	event.TenantID = event.Level.TenantID
+	event.ProductID = event.Level.ProductID
+	return publish(ctx, config.StockTopic, event.Type, event.TenantID, event.ProductID, event,
+		attribute.String("product.id", event.ProductID.String()),
+		attribute.String("stock.warehouse", event.Level.Warehouse))
+}
+
+// publish sends event to topic keyed by the entity ID, so events for one
+// entity stay ordered, with trace context, request ID and tenant headers.
+func publish(ctx context.Context, topic string, eventType EventType, tenantID string, key uuid.UUID, event any, attrs ...attribute.KeyValue) error {
+	ctx, span := tracer.Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", topic),
+			attribute.String("messaging.operation", "publish"),
+			attribute.String("event.type", string(eventType)),
+		),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
 
 	payload, err := json.Marshal(event)
 	if err != nil {
-		return fmt.Errorf("error marshaling product event: %w", err)
+		return fmt.Errorf("error marshaling %s event: %w", eventType, err)
 	}
 
 	message := &kafka.Message{
 		TopicPartition: kafka.TopicPartition{
-			Topic:     &config.ProductTopic,
+			Topic:     &topic,
 			Partition: kafka.PartitionAny,
 		},
-		Key:   []byte(product.ID.String()),
+		Key:   []byte(key.String()),
 		Value: payload,
 	}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier{&message.Headers})
+	if id := logging.RequestID(ctx); id != "" {
+		message.Headers = append(message.Headers, kafka.Header{Key: requestIDHeader, Value: []byte(id)})
+	}
+	message.Headers = append(message.Headers, kafka.Header{Key: tenantIDHeader, Value: []byte(tenantID)})
 
-	if err := config.KafkaProducer.Produce(message, nil); err != nil {
+	if err := deliver(ctx, message); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return fmt.Errorf("error publishing to Kafka: %w", err)
 	}
 
-	log.Printf("Published event: %s for product ID: %s\n", eventType, product.ID)
+	logging.FromContext(ctx).Info("Published event", "event.action", eventType, "kafka.topic", topic, "event.key", key)
 	return nil
 }
+
+// deliver produces the message and waits for the broker acknowledgement,
+// giving up once ctx or the configured send timeout expires.
+func deliver(ctx context.Context, message *kafka.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, config.KafkaSendTimeout)
+	defer cancel()
+
+	deliveryChan := make(chan kafka.Event, 1)
+	if err := config.KafkaProducer.Produce(message, deliveryChan); err != nil {
+		return err
+	}
+
+	select {
+	case e := <-deliveryChan:
+		if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+			return m.TopicPartition.Error
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
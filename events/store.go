@@ -0,0 +1,44 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-product-api/config"
+	"go-product-api/models"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// DecodeProductEvent decodes a message from the product topic, filling in
+// what events published by older versions lack: the tenant, which was the
+// default one, and the time the event occurred, taken from the message.
+func DecodeProductEvent(msg *kafka.Message) (ProductEvent, error) {
+	var event ProductEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return ProductEvent{}, fmt.Errorf("error unmarshaling event: %w", err)
+	}
+	if event.TenantID == "" {
+		event.TenantID = config.DefaultTenant
+	}
+	event.Product.TenantID = event.TenantID
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = msg.Timestamp
+	}
+	return event, nil
+}
+
+// StoredEvent returns the event for the event store, keyed by the position
+// of msg in the current epoch of the product topic.
+func StoredEvent(msg *kafka.Message, event ProductEvent) models.StoredProductEvent {
+	return models.StoredProductEvent{
+		Topic:      *msg.TopicPartition.Topic,
+		Epoch:      config.ProductTopicEpoch,
+		Partition:  msg.TopicPartition.Partition,
+		Offset:     int64(msg.TopicPartition.Offset),
+		TenantID:   event.TenantID,
+		ProductID:  event.Product.ID,
+		Type:       string(event.Type),
+		Product:    models.ProductSnapshot(event.Product),
+		OccurredAt: event.OccurredAt,
+	}
+}
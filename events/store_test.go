@@ -0,0 +1,27 @@
+package events
+
+import (
+	"testing"
+
+	"go-product-api/config"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+func TestStoredEventIDsDifferAcrossTopicEpochs(t *testing.T) {
+	topic := config.ProductTopic
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 42}}
+
+	previous := config.ProductTopicEpoch
+	t.Cleanup(func() { config.ProductTopicEpoch = previous })
+
+	config.ProductTopicEpoch = 0
+	before := StoredEvent(msg, ProductEvent{}).EventID()
+	// The topic was recreated and its offsets started over.
+	config.ProductTopicEpoch = 1
+	after := StoredEvent(msg, ProductEvent{}).EventID()
+
+	if before == after {
+		t.Fatalf("events at the same position of two epochs share the ID %q", before)
+	}
+}
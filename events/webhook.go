@@ -0,0 +1,38 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"go-product-api/models"
+	"go-product-api/repositories"
+	"time"
+)
+
+// WebhookPayload is the body sent to webhook subscribers for a product
+// event. ID identifies the event and is also sent in a header.
+type WebhookPayload struct {
+	ID         string         `json:"id"`
+	Type       EventType      `json:"type"`
+	TenantID   string         `json:"tenant_id"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Product    models.Product `json:"product"`
+}
+
+// enqueueWebhooks queues the event for the tenant's webhook subscriptions.
+// The event ID is that of its stored event, derived from the message
+// position in the current epoch, so a redelivered message is not queued
+// twice and an event of a recreated topic is not taken for an older one.
+func enqueueWebhooks(ctx context.Context, repo *repositories.WebhookRepository, stored models.StoredProductEvent, event ProductEvent) (int, error) {
+	payload := WebhookPayload{
+		ID:         stored.EventID(),
+		Type:       event.Type,
+		TenantID:   event.TenantID,
+		OccurredAt: event.OccurredAt,
+		Product:    event.Product,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	return repo.Enqueue(ctx, payload.ID, string(event.Type), body)
+}
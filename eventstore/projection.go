@@ -0,0 +1,50 @@
+package eventstore
+
+import (
+	"context"
+	"go-product-api/events"
+	"go-product-api/models"
+	"go-product-api/repositories"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apply returns the state of a product after an event given the state
+// before it, nil for a product that does not exist. Every event carries the
+// full product, so applying one replaces the state.
+func apply(state *models.Product, eventType events.EventType, product models.Product) *models.Product {
+	switch eventType {
+	case events.ProductCreated, events.ProductUpdated:
+		return &product
+	case events.ProductDeleted:
+		return nil
+	default:
+		return state
+	}
+}
+
+// replay folds events, oldest first, into the state they leave the product
+// in.
+func replay(stored []models.StoredProductEvent) *models.Product {
+	var state *models.Product
+	for _, event := range stored {
+		state = apply(state, events.EventType(event.Type), models.Product(event.Product))
+	}
+	return state
+}
+
+// StateAt returns the product as it was at asOf, replayed from the event
+// store. It fails with repositories.ErrProductNotFound when the product did
+// not exist then.
+func StateAt(ctx context.Context, id uuid.UUID, asOf time.Time) (models.Product, error) {
+	stored, err := repositories.NewProductEventRepository().FindByProduct(ctx, id, asOf)
+	if err != nil {
+		return models.Product{}, err
+	}
+	state := replay(stored)
+	if state == nil {
+		return models.Product{}, repositories.ErrProductNotFound
+	}
+	return *state, nil
+}
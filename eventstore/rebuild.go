@@ -0,0 +1,210 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"go-product-api/config"
+	"go-product-api/events"
+	"go-product-api/models"
+	"go-product-api/repositories"
+	"go-product-api/tenant"
+	"log/slog"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/google/uuid"
+)
+
+// Target is a read model that can be rebuilt from the event log.
+type Target string
+
+const (
+	TargetPostgres      Target = "postgres"
+	TargetElasticsearch Target = "elasticsearch"
+)
+
+// metadataTimeout bounds the broker metadata and watermark queries, in
+// milliseconds.
+const metadataTimeout = 10000
+
+// storePageSize is how many stored events are read per query.
+const storePageSize = 1000
+
+type projected struct {
+	tenantID string
+	state    *models.Product
+}
+
+// Rebuild replays the event store, then the tail of the product topic that
+// was published after the last stored event, and writes the resulting state
+// of every product to target. Products deleted in the log are removed;
+// products the log knows nothing about are left as they are. The tail is
+// read up to the last message present when it starts, so with an empty
+// store the whole retained topic is replayed. Rebuilding Postgres also
+// stores the events of the tail.
+//
+// It is a disaster-recovery operation: run it with the consumer stopped, so
+// the two do not race on the same products. Stock levels are not part of
+// product events and are not rebuilt.
+func Rebuild(ctx context.Context, target Target) error {
+	if target != TargetPostgres && target != TargetElasticsearch {
+		return fmt.Errorf("unknown rebuild target %q", target)
+	}
+	slog.Info("Rebuilding read model from event log", "target", target, "kafka.topic", config.ProductTopic)
+
+	products := make(map[uuid.UUID]*projected)
+	stored, err := readStore(ctx, products)
+	if err != nil {
+		return err
+	}
+	if err := readLog(ctx, target, products, stored); err != nil {
+		return err
+	}
+
+	pgRepo := repositories.NewPostgresRepository()
+	esRepo := repositories.NewElasticsearchRepository()
+	stockRepo := repositories.NewStockRepository()
+	var restored, removed int
+	for id, p := range products {
+		ctx := tenant.WithTenant(ctx, p.tenantID)
+		switch {
+		case target == TargetPostgres && p.state != nil:
+			err = pgRepo.Restore(ctx, *p.state)
+		case target == TargetPostgres:
+			err = pgRepo.Purge(ctx, id)
+		case p.state != nil:
+			p.state.InStock, err = stockRepo.InStock(ctx, id)
+			if err == nil {
+				err = esRepo.Index(ctx, *p.state)
+			}
+		default:
+			err = esRepo.Delete(ctx, id)
+		}
+		if err != nil {
+			return fmt.Errorf("error rebuilding product %s: %w", id, err)
+		}
+		if p.state != nil {
+			restored++
+		} else {
+			removed++
+		}
+	}
+
+	slog.Info("Read model rebuilt from event log", "target", target, "products.restored", restored, "products.removed", removed)
+	return nil
+}
+
+// readStore projects every event stored from the product topic, in the
+// order it was consumed, into products. It returns the offset of the last
+// stored event of each partition in the current epoch.
+func readStore(ctx context.Context, products map[uuid.UUID]*projected) (map[int32]int64, error) {
+	eventRepo := repositories.NewProductEventRepository()
+	stored := make(map[int32]int64)
+	var after *models.StoredProductEvent
+	var count int
+	for {
+		page, err := eventRepo.FindAfter(ctx, config.ProductTopic, after, storePageSize)
+		if err != nil {
+			return nil, fmt.Errorf("error reading event store: %w", err)
+		}
+		for _, event := range page {
+			project(products, event.TenantID, events.EventType(event.Type), models.Product(event.Product))
+			if event.Epoch == config.ProductTopicEpoch {
+				stored[event.Partition] = event.Offset
+			}
+		}
+		count += len(page)
+		if len(page) < storePageSize {
+			break
+		}
+		after = &page[len(page)-1]
+	}
+
+	slog.Info("Event store replayed", "events", count, "products", len(products))
+	return stored, nil
+}
+
+// readLog reads the product topic from after the last stored event of each
+// partition to its current end and projects the events into products.
+func readLog(ctx context.Context, target Target, products map[uuid.UUID]*projected, stored map[int32]int64) error {
+	consumer, err := config.NewReplayConsumer()
+	if err != nil {
+		return fmt.Errorf("error creating consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	topic := config.ProductTopic
+	metadata, err := consumer.GetMetadata(&topic, false, metadataTimeout)
+	if err != nil {
+		return fmt.Errorf("error fetching topic metadata: %w", err)
+	}
+
+	// Stop at the end of each partition as it is now, so events published
+	// while rebuilding do not keep the replay going.
+	ends := make(map[int32]int64)
+	var assignment []kafka.TopicPartition
+	for _, partition := range metadata.Topics[topic].Partitions {
+		low, high, err := consumer.QueryWatermarkOffsets(topic, partition.ID, metadataTimeout)
+		if err != nil {
+			return fmt.Errorf("error fetching offsets of partition %d: %w", partition.ID, err)
+		}
+		start := low
+		if last, ok := stored[partition.ID]; ok {
+			start = max(start, last+1)
+		}
+		if high > start {
+			ends[partition.ID] = high
+			assignment = append(assignment, kafka.TopicPartition{Topic: &topic, Partition: partition.ID, Offset: kafka.Offset(start)})
+		}
+	}
+	if err := consumer.Assign(assignment); err != nil {
+		return fmt.Errorf("error assigning partitions: %w", err)
+	}
+
+	eventRepo := repositories.NewProductEventRepository()
+	var count int
+	for len(ends) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		msg, err := consumer.ReadMessage(time.Second)
+		if err != nil {
+			if kerr, ok := err.(kafka.Error); ok && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			return fmt.Errorf("error reading event log: %w", err)
+		}
+		partition, offset := msg.TopicPartition.Partition, int64(msg.TopicPartition.Offset)
+		if offset+1 >= ends[partition] {
+			delete(ends, partition)
+		}
+
+		event, err := events.DecodeProductEvent(msg)
+		if err != nil {
+			slog.Warn("Skipping undecodable event", "kafka.partition", partition, "kafka.offset", offset, "error.message", err)
+			continue
+		}
+		if target == TargetPostgres {
+			stored := events.StoredEvent(msg, event)
+			if err := eventRepo.Append(ctx, &stored); err != nil {
+				return fmt.Errorf("error storing event: %w", err)
+			}
+		}
+
+		project(products, event.TenantID, event.Type, event.Product)
+		count++
+	}
+
+	slog.Info("Event log tail replayed", "events", count, "products", len(products))
+	return nil
+}
+
+// project applies an event to the state of its product in products.
+func project(products map[uuid.UUID]*projected, tenantID string, eventType events.EventType, product models.Product) {
+	p, ok := products[product.ID]
+	if !ok {
+		p = &projected{tenantID: tenantID}
+		products[product.ID] = p
+	}
+	p.state = apply(p.state, eventType, product)
+}
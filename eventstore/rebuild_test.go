@@ -0,0 +1,66 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-product-api/config"
+	"go-product-api/events"
+	"go-product-api/internal/testutil"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var storedColumns = []string{"topic", "epoch", "partition", "offset", "tenant_id", "product_id", "type", "product", "occurred_at"}
+
+func storedRow(rows *sqlmock.Rows, epoch int, partition int32, offset int64, id uuid.UUID, eventType events.EventType, sku string) *sqlmock.Rows {
+	product := fmt.Sprintf(`{"id":%q,"tenant_id":"tenant-a","sku":%q}`, id, sku)
+	return rows.AddRow(config.ProductTopic, epoch, partition, offset, "tenant-a", id, string(eventType), product, time.Now())
+}
+
+func TestReadStoreReplaysEveryPageInOrder(t *testing.T) {
+	previous := config.ProductTopicEpoch
+	config.ProductTopicEpoch = 1
+	t.Cleanup(func() { config.ProductTopicEpoch = previous })
+
+	mock := testutil.MockDatabase(t)
+	kept, deleted := uuid.New(), uuid.New()
+
+	// A full page, starting in the previous epoch of the topic.
+	first := sqlmock.NewRows(storedColumns)
+	storedRow(first, 0, 0, 7, kept, events.ProductCreated, "SKU-0")
+	storedRow(first, 0, 0, 8, deleted, events.ProductCreated, "SKU-GONE")
+	for offset := range int64(storePageSize - 2) {
+		storedRow(first, 1, 0, offset, kept, events.ProductUpdated, fmt.Sprintf("SKU-%d", offset+1))
+	}
+	mock.ExpectQuery(`FROM "stored_product_events"`).
+		WithArgs(config.ProductTopic, storePageSize).
+		WillReturnRows(first)
+
+	// The next page resumes after the last event of the first one.
+	second := sqlmock.NewRows(storedColumns)
+	storedRow(second, 1, 0, storePageSize-2, deleted, events.ProductDeleted, "SKU-GONE")
+	mock.ExpectQuery(`FROM "stored_product_events"`).
+		WithArgs(config.ProductTopic, 1, 0, storePageSize-3, storePageSize).
+		WillReturnRows(second)
+
+	products := make(map[uuid.UUID]*projected)
+	stored, err := readStore(context.Background(), products)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := products[kept]; p == nil || p.state == nil || p.state.SKU != fmt.Sprintf("SKU-%d", storePageSize-2) || p.tenantID != "tenant-a" {
+		t.Fatalf("kept product was projected as %+v, want its last update", p)
+	}
+	if p := products[deleted]; p == nil || p.state != nil {
+		t.Fatalf("deleted product was projected as %+v, want it removed", p)
+	}
+	// The tail of the topic is read from after this offset.
+	if len(stored) != 1 || stored[0] != storePageSize-2 {
+		t.Fatalf("got last stored offsets %v, want partition 0 at %d", stored, storePageSize-2)
+	}
+}
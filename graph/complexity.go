@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"fmt"
+	"go-product-api/config"
+	"go-product-api/problem"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// paginatedFields return up to first products, so what is selected under
+// them counts once per product.
+var paginatedFields = map[string]bool{"products": true, "search": true}
+
+// mutationComplexity is what a mutation field costs on top of its
+// selection, so a document cannot pack many writes into one request.
+const mutationComplexity = 200
+
+// cost is the estimated complexity and the nesting depth of a selection.
+type cost struct {
+	complexity int
+	depth      int
+}
+
+// checkComplexity rejects a document with an operation whose cost is over
+// the configured limits. It runs after validation, so fragments exist and
+// do not form cycles.
+func checkComplexity(doc *ast.Document, variables map[string]interface{}) *Error {
+	fragments := fragmentsOf(doc)
+	for _, def := range doc.Definitions {
+		operation, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		values := map[string]interface{}{}
+		for _, def := range operation.VariableDefinitions {
+			if value, ok := variables[def.Variable.Name.Value]; ok {
+				values[def.Variable.Name.Value] = value
+			} else if def.DefaultValue != nil {
+				values[def.Variable.Name.Value] = literalValue(def.DefaultValue)
+			}
+		}
+		c := measure(operation.SelectionSet, fragments, values)
+		if operation.Operation == ast.OperationTypeMutation {
+			c.complexity += mutationComplexity * countFields(operation.SelectionSet, fragments)
+		}
+		if c.depth > config.GraphQLMaxDepth {
+			return &Error{
+				Message: fmt.Sprintf("The query nests %d levels deep, more than the limit of %d", c.depth, config.GraphQLMaxDepth),
+				Code:    problem.CodeQueryTooComplex,
+			}
+		}
+		if c.complexity > config.GraphQLMaxComplexity {
+			return &Error{
+				Message: fmt.Sprintf("The query has a complexity of %d, more than the limit of %d", c.complexity, config.GraphQLMaxComplexity),
+				Code:    problem.CodeQueryTooComplex,
+			}
+		}
+	}
+	return nil
+}
+
+func fragmentsOf(doc *ast.Document) map[string]*ast.FragmentDefinition {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+	return fragments
+}
+
+// countFields counts the fields a selection set selects at its own level,
+// through fragments, leaving out introspection fields.
+func countFields(set *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition) int {
+	if set == nil {
+		return 0
+	}
+	n := 0
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if !strings.HasPrefix(selection.Name.Value, "__") {
+				n++
+			}
+		case *ast.InlineFragment:
+			n += countFields(selection.SelectionSet, fragments)
+		case *ast.FragmentSpread:
+			if fragment, ok := fragments[selection.Name.Value]; ok {
+				n += countFields(fragment.SelectionSet, fragments)
+			}
+		}
+	}
+	return n
+}
+
+// measure adds up a selection set. Introspection fields are free since
+// they are answered from the schema in memory.
+func measure(set *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, variables map[string]interface{}) cost {
+	var total cost
+	if set == nil {
+		return total
+	}
+
+	for _, selection := range set.Selections {
+		var c cost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			c = measure(selection.SelectionSet, fragments, variables)
+			if paginatedFields[selection.Name.Value] {
+				c.complexity *= intArgument(selection, "first", variables, defaultPageSize)
+			}
+			c.complexity++
+			c.depth++
+		case *ast.InlineFragment:
+			c = measure(selection.SelectionSet, fragments, variables)
+		case *ast.FragmentSpread:
+			if fragment, ok := fragments[selection.Name.Value]; ok {
+				c = measure(fragment.SelectionSet, fragments, variables)
+			}
+		}
+		total.complexity += c.complexity
+		total.depth = max(total.depth, c.depth)
+	}
+	return total
+}
+
+// intArgument returns an integer argument given inline or as a variable.
+// Values the resolver would reject anyway count as the default.
+func intArgument(field *ast.Field, name string, variables map[string]interface{}, fallback int) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != name {
+			continue
+		}
+		var value interface{}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			value = parseLong(v.Value)
+		case *ast.Variable:
+			value = parseLong(variables[v.Name.Value])
+		}
+		if n, ok := value.(int64); ok && n >= 1 && n <= maxPageSize {
+			return int(n)
+		}
+	}
+	return fallback
+}
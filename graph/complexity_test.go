@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go-product-api/problem"
+)
+
+func TestPrepareCountsMutationFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   Request
+		count int
+	}{
+		{"query", Request{Query: `{ product(id: "1") { id } }`}, 0},
+		{"aliases", Request{Query: `mutation { a: deleteProduct(id: "1") b: deleteProduct(id: "2") }`}, 2},
+		{"fragment", Request{Query: `mutation { ...Deletes } fragment Deletes on Mutation { a: deleteProduct(id: "1") b: deleteProduct(id: "2") __typename }`}, 2},
+		{"named operation", Request{
+			Query:         `query Read { product(id: "1") { id } } mutation Write { deleteProduct(id: "1") }`,
+			OperationName: "Write",
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, failed := Prepare(tt.req)
+			if failed != nil {
+				t.Fatalf("unexpected errors: %v", failed.Errors)
+			}
+			if doc.Mutations != tt.count {
+				t.Fatalf("got %d mutations, want %d", doc.Mutations, tt.count)
+			}
+		})
+	}
+}
+
+func TestPrepareRejectsManyMutations(t *testing.T) {
+	var fields []string
+	for i := range 20 {
+		fields = append(fields, fmt.Sprintf(`d%d: deleteProduct(id: "%d")`, i, i))
+	}
+	_, failed := Prepare(Request{Query: "mutation { " + strings.Join(fields, " ") + " }"})
+	if failed == nil || len(failed.Errors) != 1 {
+		t.Fatal("got no error, want the document rejected as too complex")
+	}
+	if code := failed.Errors[0].Extensions["code"]; code != problem.CodeQueryTooComplex {
+		t.Fatalf("got code %v, want %s", code, problem.CodeQueryTooComplex)
+	}
+}
@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"go-product-api/catalog"
+	"go-product-api/logging"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"strings"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"gorm.io/gorm"
+)
+
+// Error is a resolver error that carries the problem code the REST API
+// answers with, and any invalid fields, in its extensions.
+type Error struct {
+	Message string
+	Code    string
+	Fields  []problem.FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code}
+	if len(e.Fields) > 0 {
+		ext["errors"] = e.Fields
+	}
+	return ext
+}
+
+// invalidFields reports input fields that failed validation, renamed from
+// their JSON names to the camel case the schema uses.
+func invalidFields(errs []problem.FieldError) *Error {
+	fields := make([]problem.FieldError, len(errs))
+	for i, fe := range errs {
+		fe.Field = camelPath(fe.Field)
+		fields[i] = fe
+	}
+	return &Error{Message: "The request contains invalid fields", Code: problem.CodeValidationFailed, Fields: fields}
+}
+
+func invalidField(field, code, message string) *Error {
+	return invalidFields([]problem.FieldError{{Field: field, Code: code, Message: message}})
+}
+
+// resolveError maps a catalog or repository error the way respondError
+// maps it to a problem. The underlying error is logged but never sent to
+// the client; message is used for unexpected failures.
+func resolveError(ctx context.Context, err error, message string) error {
+	var (
+		invalidInput *catalog.ValidationError
+		optionsInUse *catalog.OptionsInUseError
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Message: "A backend service did not respond in time", Code: problem.CodeBackendTimeout}
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, repositories.ErrProductNotFound):
+		return &Error{Message: "Product not found", Code: "product_" + problem.CodeNotFound}
+	case errors.As(err, &invalidInput):
+		return invalidFields(invalidInput.Errors)
+	case errors.As(err, &optionsInUse):
+		return &Error{
+			Message: "The options no longer match variant " + optionsInUse.VariantSKU + "; update or delete it first",
+			Code:    problem.CodeOptionsInUse,
+		}
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &Error{Message: "Product conflicts with an existing one", Code: "product_" + problem.CodeConflict}
+	case errors.Is(err, repositories.ErrUnknownCategory):
+		return invalidField("category_ids", "exists", "refers to a category that does not exist")
+	case errors.Is(err, repositories.ErrElasticsearchUnavailable):
+		return &Error{Message: "The service is temporarily unavailable", Code: problem.CodeServiceUnavailable}
+	case errors.Is(err, catalog.ErrNotPublished):
+		logging.FromContext(ctx).Error(message, "error.message", err)
+		return &Error{Message: message, Code: problem.CodeEventPublishFailed}
+	default:
+		logging.FromContext(ctx).Error(message, "error.message", err)
+		return &Error{Message: message, Code: problem.CodeInternal}
+	}
+}
+
+// withExtensions copies the extensions of errors returned from thunks,
+// which the executor wraps without looking for them.
+func withExtensions(errs []gqlerrors.FormattedError) {
+	for i := range errs {
+		if errs[i].Extensions != nil {
+			continue
+		}
+		var err error = errs[i]
+		for err != nil {
+			if extended, ok := err.(*Error); ok {
+				errs[i].Extensions = extended.Extensions()
+				break
+			}
+			switch e := err.(type) {
+			case gqlerrors.FormattedError:
+				err = e.OriginalError()
+			case *gqlerrors.Error:
+				err = e.OriginalError
+			default:
+				err = errors.Unwrap(err)
+			}
+		}
+	}
+}
+
+// camelPath turns a JSON field path such as prices[0].category_ids into
+// prices[0].categoryIds.
+func camelPath(path string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range path {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
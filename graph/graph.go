@@ -0,0 +1,95 @@
+// Package graph serves the catalog over GraphQL. Resolvers go through the
+// catalog package like the REST handlers, so reads, validation, writes and
+// event publishing behave the same, and lookups made while resolving one
+// request are batched.
+package graph
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request is a GraphQL request as posted to /graphql.
+type Request struct {
+	Query         string                 `json:"query" binding:"required,max=20000"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Document is a request that parsed, passed validation and is within the
+// complexity limits. Mutations is the number of mutation fields of the
+// operation it runs, each of which writes.
+type Document struct {
+	req       Request
+	doc       *ast.Document
+	Mutations int
+}
+
+// Prepare parses and validates a request and rejects it when it is too
+// complex. When the request cannot run it returns the result to answer
+// with instead.
+func Prepare(req Request) (*Document, *graphql.Result) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return nil, &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	validation := graphql.ValidateDocument(&schema, doc, nil)
+	if !validation.IsValid {
+		return nil, &graphql.Result{Errors: validation.Errors}
+	}
+
+	if err := checkComplexity(doc, req.Variables); err != nil {
+		formatted := gqlerrors.FormatError(err)
+		formatted.Extensions = err.Extensions()
+		return nil, &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+	}
+
+	d := &Document{req: req, doc: doc}
+	if operation := selectOperation(doc, req.OperationName); operation != nil && operation.Operation == ast.OperationTypeMutation {
+		d.Mutations = countFields(operation.SelectionSet, fragmentsOf(doc))
+	}
+	return d, nil
+}
+
+// Execute runs the document. Errors are reported in the result, each with
+// the problem code the REST API would use in its extensions.
+func (d *Document) Execute(ctx context.Context) *graphql.Result {
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		AST:           d.doc,
+		OperationName: d.req.OperationName,
+		Args:          d.req.Variables,
+		Context:       withLoaders(ctx),
+	})
+	withExtensions(result.Errors)
+	return result
+}
+
+// selectOperation returns the operation of the document a request runs:
+// the one named, or the only one when no name is given.
+func selectOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		operation, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+	return found
+}
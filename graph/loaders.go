@@ -0,0 +1,152 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"go-product-api/catalog"
+	"go-product-api/models"
+	"go-product-api/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// loader collects the keys asked for while one level of a query resolves
+// and fetches them with a single call once the first result is needed.
+// Resolvers return the thunk from load, which the executor only calls
+// after every sibling field has queued its key. A loader lives for one
+// request and is not safe for concurrent use; the executor resolves fields
+// on one goroutine.
+type loader[K comparable, V any] struct {
+	fetch   func(ctx context.Context, keys []K) (map[K]V, error)
+	pending []K
+	seen    map[K]bool
+	fetched map[K]bool
+	results map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, seen: map[K]bool{}, fetched: map[K]bool{}, results: map[K]V{}, errs: map[K]error{}}
+}
+
+// load queues key, unless it was asked for before, and returns a thunk
+// yielding its value, or false when the fetch did not return it.
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (V, bool, error) {
+	if !l.seen[key] {
+		l.seen[key] = true
+		l.pending = append(l.pending, key)
+	}
+	return func() (V, bool, error) {
+		if !l.fetched[key] {
+			l.dispatch(ctx)
+		}
+		value, ok := l.results[key]
+		return value, ok, l.errs[key]
+	}
+}
+
+func (l *loader[K, V]) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+
+	results, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		l.fetched[key] = true
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		if value, ok := results[key]; ok {
+			l.results[key] = value
+		}
+	}
+}
+
+// loaders are the batch loaders and exchange rates of one request.
+type loaders struct {
+	products   map[bool]*loader[uuid.UUID, models.Product]
+	categories *loader[uuid.UUID, models.Category]
+	pricers    map[catalog.DisplayQuery]*catalog.Pricer
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context) context.Context {
+	l := &loaders{
+		products: map[bool]*loader[uuid.UUID, models.Product]{
+			false: newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.Product, error) {
+				return fetchProducts(ctx, ids, false)
+			}),
+			true: newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.Product, error) {
+				return fetchProducts(ctx, ids, true)
+			}),
+		},
+		categories: newLoader(fetchCategories),
+		pricers:    map[catalog.DisplayQuery]*catalog.Pricer{},
+	}
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// pricer returns the pricer for a display query, reading the exchange rates
+// once per request however many fields ask for display prices.
+func (l *loaders) pricer(ctx context.Context, query catalog.DisplayQuery) (*catalog.Pricer, error) {
+	if pricer, ok := l.pricers[query]; ok {
+		return pricer, nil
+	}
+	pricer, err := catalog.NewPricer(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	l.pricers[query] = pricer
+	return pricer, nil
+}
+
+// fetchProducts reads a batch of products with one list query. Products
+// missing from the search index, such as ones created a moment ago, are
+// looked up one by one the way a single product read would find them.
+func fetchProducts(ctx context.Context, ids []uuid.UUID, strong bool) (map[uuid.UUID]models.Product, error) {
+	products, _, err := catalog.List(ctx, repositories.ProductFilter{IDs: ids, Limit: len(ids)}, strong)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[uuid.UUID]models.Product, len(products))
+	for _, product := range products {
+		found[product.ID] = product
+	}
+	if strong {
+		return found, nil
+	}
+	for _, id := range ids {
+		if _, ok := found[id]; ok {
+			continue
+		}
+		product, _, err := catalog.Get(ctx, id, false)
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, repositories.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found[id] = product
+	}
+	return found, nil
+}
+
+func fetchCategories(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.Category, error) {
+	categories, err := repositories.NewCategoryRepository().FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[uuid.UUID]models.Category, len(categories))
+	for _, category := range categories {
+		found[category.ID] = category
+	}
+	return found, nil
+}
@@ -0,0 +1,376 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-product-api/auth"
+	"go-product-api/catalog"
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// connection is a page of products. EndCursor is nil on an empty page.
+type connection struct {
+	Nodes    []models.Product
+	PageInfo pageInfo
+}
+
+type pageInfo struct {
+	HasNextPage bool
+	EndCursor   *string
+}
+
+// resolveProduct returns nil rather than an error for a product that does
+// not exist, as GraphQL clients expect of a nullable lookup.
+func resolveProduct(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Context
+	display, err := displayQuery(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	pricer, err := loadersFrom(ctx).pricer(ctx, display)
+	if err != nil {
+		return nil, resolveError(ctx, err, "Failed to fetch exchange rates")
+	}
+
+	id, byID := p.Args["id"].(string)
+	sku, bySKU := p.Args["sku"].(string)
+	asOf, _ := p.Args["asOf"].(time.Time)
+	switch {
+	case byID == bySKU:
+		return nil, invalidField("id", "required_without", "exactly one of id and sku is required")
+	case bySKU && !asOf.IsZero():
+		return nil, invalidField("as_of", "excluded_with", "is only supported for lookups by id")
+	case bySKU:
+		product, _, err := catalog.GetBySKU(ctx, sku, p.Args["consistency"] == consistencyStrong)
+		return priced(ctx, product, err, pricer)
+	}
+
+	productID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, invalidField("id", "uuid", "must be a UUID")
+	}
+	if !asOf.IsZero() {
+		product, _, err := catalog.GetAt(ctx, productID, asOf)
+		return priced(ctx, product, err, pricer)
+	}
+
+	thunk := loadersFrom(ctx).products[p.Args["consistency"] == consistencyStrong].load(ctx, productID)
+	return func() (interface{}, error) {
+		product, ok, err := thunk()
+		if err == nil && !ok {
+			err = repositories.ErrProductNotFound
+		}
+		return priced(ctx, product, err, pricer)
+	}, nil
+}
+
+// priced applies the display price to a product that was found. The
+// product is a copy, so lookups of the same product with different display
+// currencies do not interfere.
+func priced(ctx context.Context, product models.Product, err error, pricer *catalog.Pricer) (interface{}, error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, repositories.ErrProductNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolveError(ctx, err, "Failed to fetch product")
+	}
+	pricer.Apply(&product)
+	return product, nil
+}
+
+func resolveProducts(p graphql.ResolveParams) (interface{}, error) {
+	return listProducts(p, "")
+}
+
+func resolveSearch(p graphql.ResolveParams) (interface{}, error) {
+	query, _ := p.Args["query"].(string)
+	if query == "" {
+		return nil, invalidField("query", "required", "is required")
+	}
+	return listProducts(p, query)
+}
+
+// listProducts serves a page of the product list. Cursors are opaque to
+// clients and encode the offset after the last product of a page; one
+// product more than asked for is read to tell whether another page follows.
+func listProducts(p graphql.ResolveParams, text string) (interface{}, error) {
+	ctx := p.Context
+	display, err := displayQuery(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	query := catalog.ListQuery{DisplayQuery: display, Query: text}
+	query.Currency, _ = p.Args["currency"].(string)
+	query.Sort, _ = p.Args["sort"].(string)
+	if minPrice, ok := p.Args["minPrice"].(int64); ok {
+		query.MinPrice = &minPrice
+	}
+	if maxPrice, ok := p.Args["maxPrice"].(int64); ok {
+		query.MaxPrice = &maxPrice
+	}
+	if inStock, ok := p.Args["inStock"].(bool); ok {
+		query.InStock = &inStock
+	}
+	if options, ok := p.Args["option"].([]interface{}); ok {
+		for _, option := range options {
+			query.Option = append(query.Option, option.(string))
+		}
+	}
+	if err := validate(&query); err != nil {
+		return nil, err
+	}
+	filter, err := query.Filter()
+	if err != nil {
+		return nil, resolveError(ctx, err, "Failed to fetch products")
+	}
+
+	first, _ := p.Args["first"].(int)
+	if first < 1 || first > maxPageSize {
+		return nil, invalidField("first", "max", fmt.Sprintf("must be between 1 and %d", maxPageSize))
+	}
+	offset := 0
+	if after, ok := p.Args["after"].(string); ok {
+		if offset, err = decodeCursor(after); err != nil {
+			return nil, invalidField("after", "cursor", "is not a cursor from this API")
+		}
+	}
+	filter.Limit, filter.Offset = first+1, offset
+
+	pricer, err := loadersFrom(ctx).pricer(ctx, display)
+	if err != nil {
+		return nil, resolveError(ctx, err, "Failed to fetch exchange rates")
+	}
+	products, _, err := catalog.List(ctx, filter, p.Args["consistency"] == consistencyStrong)
+	if err != nil {
+		return nil, resolveError(ctx, err, "Failed to fetch products")
+	}
+
+	page := connection{Nodes: products}
+	if len(products) > first {
+		page.Nodes, page.PageInfo.HasNextPage = products[:first], true
+	}
+	for i := range page.Nodes {
+		pricer.Apply(&page.Nodes[i])
+	}
+	if len(page.Nodes) > 0 {
+		cursor := encodeCursor(offset + len(page.Nodes))
+		page.PageInfo.EndCursor = &cursor
+	}
+	return page, nil
+}
+
+// resolveCategories queues the product's categories on the request's
+// category loader, so the categories of every product in a response are
+// read with one query.
+func resolveCategories(p graphql.ResolveParams) (interface{}, error) {
+	product, ok := p.Source.(models.Product)
+	if !ok || len(product.CategoryIDs) == 0 {
+		return []models.Category{}, nil
+	}
+
+	categoryLoader := loadersFrom(p.Context).categories
+	thunks := make([]func() (models.Category, bool, error), len(product.CategoryIDs))
+	for i, id := range product.CategoryIDs {
+		thunks[i] = categoryLoader.load(p.Context, id)
+	}
+	return func() (interface{}, error) {
+		categories := make([]models.Category, 0, len(thunks))
+		for _, thunk := range thunks {
+			category, ok, err := thunk()
+			if err != nil {
+				return nil, resolveError(p.Context, err, "Failed to fetch categories")
+			}
+			if ok {
+				categories = append(categories, category)
+			}
+		}
+		return categories, nil
+	}, nil
+}
+
+// resolveMarket reports the general price, stored with an empty market, as
+// null.
+func resolveMarket(p graphql.ResolveParams) (interface{}, error) {
+	var market string
+	switch price := p.Source.(type) {
+	case models.ProductPrice:
+		market = price.Market
+	case models.VariantPrice:
+		market = price.Market
+	}
+	if market == "" {
+		return nil, nil
+	}
+	return market, nil
+}
+
+func resolveCreateProduct(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireRole(p.Context, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+	input, err := productInput(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := catalog.Create(p.Context, input)
+	if err != nil {
+		return nil, resolveError(p.Context, err, "Failed to create product")
+	}
+	return product, nil
+}
+
+func resolveUpdateProduct(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireRole(p.Context, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, invalidField("id", "uuid", "must be a UUID")
+	}
+	input, err := productInput(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := catalog.Update(p.Context, id, input)
+	if err != nil {
+		return nil, resolveError(p.Context, err, "Failed to update product")
+	}
+	return product, nil
+}
+
+func resolveDeleteProduct(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireRole(p.Context, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, invalidField("id", "uuid", "must be a UUID")
+	}
+
+	if _, err := catalog.Delete(p.Context, id); err != nil {
+		return nil, resolveError(p.Context, err, "Failed to delete product")
+	}
+	return id, nil
+}
+
+// requireRole checks the role of the caller for mutations; the route only
+// requires readers.
+func requireRole(ctx context.Context, required auth.Role) error {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || !principal.Role.Allows(required) {
+		return &Error{Message: "Insufficient permissions", Code: problem.CodeForbidden}
+	}
+	return nil
+}
+
+func displayQuery(args map[string]interface{}) (catalog.DisplayQuery, error) {
+	var query catalog.DisplayQuery
+	query.DisplayCurrency, _ = args["displayCurrency"].(string)
+	query.Market, _ = args["market"].(string)
+	return query, validate(&query)
+}
+
+// productInput converts a ProductInput and validates it with the binding
+// tags of models.Product, as a REST request body would be.
+func productInput(value interface{}) (models.Product, error) {
+	in, _ := value.(map[string]interface{})
+	var product models.Product
+	product.SKU, _ = in["sku"].(string)
+	product.Name, _ = in["name"].(string)
+	product.Description, _ = in["description"].(string)
+	product.Status, _ = in["status"].(string)
+	product.Tags = stringList(in["tags"])
+	if attributes, ok := in["attributes"].(map[string]interface{}); ok {
+		product.Attributes = attributes
+	} else if in["attributes"] != nil {
+		return models.Product{}, invalidField("attributes", "type", "must be an object")
+	}
+	for _, value := range list(in["prices"]) {
+		price, _ := value.(map[string]interface{})
+		currency, _ := price["currency"].(string)
+		market, _ := price["market"].(string)
+		amount, _ := price["amount"].(int64)
+		product.Prices = append(product.Prices, models.ProductPrice{Currency: currency, Market: market, Amount: amount})
+	}
+	for _, value := range list(in["options"]) {
+		option, _ := value.(map[string]interface{})
+		name, _ := option["name"].(string)
+		product.Options = append(product.Options, models.ProductOption{Name: name, Values: stringList(option["values"])})
+	}
+
+	var errs []problem.FieldError
+	for i, value := range stringList(in["categoryIds"]) {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			errs = append(errs, problem.FieldError{Field: fmt.Sprintf("category_ids[%d]", i), Code: "uuid", Message: "must be a UUID"})
+			continue
+		}
+		product.CategoryIDs = append(product.CategoryIDs, id)
+	}
+	if err := binding.Validator.ValidateStruct(&product); err != nil {
+		errs = append(problem.FromBindError(err).Errors, errs...)
+	}
+	if len(errs) > 0 {
+		return models.Product{}, invalidFields(errs)
+	}
+	return product, nil
+}
+
+// validate checks arguments gathered into a struct against its binding
+// tags.
+func validate(obj interface{}) error {
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		return invalidFields(problem.FromBindError(err).Errors)
+	}
+	return nil
+}
+
+func list(value interface{}) []interface{} {
+	values, _ := value.([]interface{})
+	return values
+}
+
+func stringList(value interface{}) []string {
+	values := list(value)
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(b))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
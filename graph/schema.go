@@ -0,0 +1,360 @@
+package graph
+
+import (
+	"encoding/json"
+	"go-product-api/models"
+	"go-product-api/repositories"
+	"math"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	consistencyEventual = "eventual"
+	consistencyStrong   = "strong"
+)
+
+var longType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Long",
+	Description: "A 64-bit integer, such as an amount in minor units of a currency.",
+	Serialize: func(value interface{}) interface{} {
+		switch value := value.(type) {
+		case int64:
+			return value
+		case *int64:
+			if value == nil {
+				return nil
+			}
+			return *value
+		case int:
+			return int64(value)
+		}
+		return nil
+	},
+	ParseValue: parseLong,
+	ParseLiteral: func(value ast.Value) interface{} {
+		if value, ok := value.(*ast.IntValue); ok {
+			return parseLong(value.Value)
+		}
+		return nil
+	},
+})
+
+// parseLong accepts the integers variables decode to, which are float64
+// for JSON numbers, and integer strings from literals.
+func parseLong(value interface{}) interface{} {
+	switch value := value.(type) {
+	case int:
+		return int64(value)
+	case int64:
+		return value
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) <= 1<<53 {
+			return int64(value)
+		}
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+	case string:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return nil
+}
+
+var jsonType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "An arbitrary JSON value, used for product attributes and variant options.",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: literalValue,
+})
+
+// literalValue converts an inline JSON value to what encoding/json would
+// decode it to.
+func literalValue(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.StringValue:
+		return value.Value
+	case *ast.EnumValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.IntValue:
+		n, _ := strconv.ParseFloat(value.Value, 64)
+		return n
+	case *ast.FloatValue:
+		n, _ := strconv.ParseFloat(value.Value, 64)
+		return n
+	case *ast.ListValue:
+		list := make([]interface{}, len(value.Values))
+		for i, v := range value.Values {
+			list[i] = literalValue(v)
+		}
+		return list
+	case *ast.ObjectValue:
+		obj := make(map[string]interface{}, len(value.Fields))
+		for _, field := range value.Fields {
+			obj[field.Name.Value] = literalValue(field.Value)
+		}
+		return obj
+	}
+	return nil
+}
+
+var consistencyEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "Consistency",
+	Description: "STRONG reads go straight to PostgreSQL, so they see the caller's own writes.",
+	Values: graphql.EnumValueConfigMap{
+		"EVENTUAL": &graphql.EnumValueConfig{Value: consistencyEventual},
+		"STRONG":   &graphql.EnumValueConfig{Value: consistencyStrong},
+	},
+})
+
+var priceSortEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "PriceSort",
+	Values: graphql.EnumValueConfigMap{
+		"PRICE_ASC":  &graphql.EnumValueConfig{Value: repositories.SortPriceAsc},
+		"PRICE_DESC": &graphql.EnumValueConfig{Value: repositories.SortPriceDesc},
+	},
+})
+
+var productStatusEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ProductStatus",
+	Values: graphql.EnumValueConfigMap{
+		"DRAFT":    &graphql.EnumValueConfig{Value: models.ProductStatusDraft},
+		"ACTIVE":   &graphql.EnumValueConfig{Value: models.ProductStatusActive},
+		"ARCHIVED": &graphql.EnumValueConfig{Value: models.ProductStatusArchived},
+	},
+})
+
+var priceType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Price",
+	Fields: graphql.Fields{
+		"currency": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"market":   &graphql.Field{Type: graphql.String, Resolve: resolveMarket},
+		"amount":   &graphql.Field{Type: graphql.NewNonNull(longType)},
+	},
+})
+
+var productOptionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductOption",
+	Fields: graphql.Fields{
+		"name":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"values": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+	},
+})
+
+var variantType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Variant",
+	Fields: graphql.Fields{
+		"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"sku":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"options":   &graphql.Field{Type: graphql.NewNonNull(jsonType)},
+		"prices":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(priceType)))},
+		"stock":     &graphql.Field{Type: graphql.NewNonNull(longType)},
+		"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+var categoryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Category",
+	Fields: graphql.Fields{
+		"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"parentId": &graphql.Field{Type: graphql.ID},
+		"name":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"path":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"position": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var productType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Product",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"sku":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":      &graphql.Field{Type: graphql.NewNonNull(productStatusEnum)},
+		"tags":        &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+		"attributes":  &graphql.Field{Type: graphql.NewNonNull(jsonType)},
+		"prices":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(priceType)))},
+		"displayPrice": &graphql.Field{
+			Type:        priceType,
+			Description: "The price in the display currency the product was asked for with.",
+		},
+		"options":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productOptionType)))},
+		"variants":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(variantType)))},
+		"categoryIds":   &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID)))},
+		"categoryPaths": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+		"categories": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))),
+			Description: "The categories the product is filed under, loaded in one query for all products of a response.",
+			Resolve:     resolveCategories,
+		},
+		"inStock":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Pass as after to get the next page.",
+		},
+	},
+})
+
+var productConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductConnection",
+	Fields: graphql.Fields{
+		"nodes":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productType)))},
+		"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+	},
+})
+
+var priceInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "PriceInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"currency": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"market":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"amount":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(longType)},
+	},
+})
+
+var productOptionInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ProductOptionInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"values": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+	},
+})
+
+var productInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "ProductInput",
+	Description: "The editable fields of a product. Status defaults to DRAFT on create and is kept on update when omitted.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"sku":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"status":      &graphql.InputObjectFieldConfig{Type: productStatusEnum},
+		"tags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		"attributes":  &graphql.InputObjectFieldConfig{Type: jsonType},
+		"prices":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(priceInputType)))},
+		"categoryIds": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.ID))},
+		"options":     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(productOptionInputType))},
+	},
+})
+
+// displayArgs ask for a display price like the display_currency and market
+// query parameters of the REST API.
+var displayArgs = graphql.FieldConfigArgument{
+	"displayCurrency": &graphql.ArgumentConfig{Type: graphql.String},
+	"market":          &graphql.ArgumentConfig{Type: graphql.String},
+	"consistency":     &graphql.ArgumentConfig{Type: consistencyEnum, DefaultValue: consistencyEventual},
+}
+
+// listArgs filter and page through products like the query parameters of
+// GET /products. Each option is a name:value pair.
+var listArgs = graphql.FieldConfigArgument{
+	"currency": &graphql.ArgumentConfig{Type: graphql.String},
+	"minPrice": &graphql.ArgumentConfig{Type: longType},
+	"maxPrice": &graphql.ArgumentConfig{Type: longType},
+	"sort":     &graphql.ArgumentConfig{Type: priceSortEnum},
+	"inStock":  &graphql.ArgumentConfig{Type: graphql.Boolean},
+	"option":   &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+	"first":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+	"after":    &graphql.ArgumentConfig{Type: graphql.String},
+}
+
+func args(sets ...graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	merged := graphql.FieldConfigArgument{}
+	for _, set := range sets {
+		for name, arg := range set {
+			merged[name] = arg
+		}
+	}
+	return merged
+}
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"product": &graphql.Field{
+			Type:        productType,
+			Description: "Looks a product up by id or sku, or null when there is none. Lookups by id in one request are batched.",
+			Args: args(displayArgs, graphql.FieldConfigArgument{
+				"id":   &graphql.ArgumentConfig{Type: graphql.ID},
+				"sku":  &graphql.ArgumentConfig{Type: graphql.String},
+				"asOf": &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Replays the product as it was at this time. Only for lookups by id."},
+			}),
+			Resolve: resolveProduct,
+		},
+		"products": &graphql.Field{
+			Type:        graphql.NewNonNull(productConnectionType),
+			Description: "Lists products, filtered and sorted by price in one currency.",
+			Args:        args(displayArgs, listArgs),
+			Resolve:     resolveProducts,
+		},
+		"search": &graphql.Field{
+			Type:        graphql.NewNonNull(productConnectionType),
+			Description: "Searches products by name, SKU, description and tags, best matches first unless sorted by price.",
+			Args: args(displayArgs, listArgs, graphql.FieldConfigArgument{
+				"query": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			}),
+			Resolve: resolveSearch,
+		},
+	},
+})
+
+var mutationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Mutation",
+	Fields: graphql.Fields{
+		"createProduct": &graphql.Field{
+			Type: graphql.NewNonNull(productType),
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(productInputType)},
+			},
+			Resolve: resolveCreateProduct,
+		},
+		"updateProduct": &graphql.Field{
+			Type: graphql.NewNonNull(productType),
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(productInputType)},
+			},
+			Resolve: resolveUpdateProduct,
+		},
+		"deleteProduct": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Deletes a product and returns its id.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: resolveDeleteProduct,
+		},
+	},
+})
+
+var schema = mustSchema()
+
+func mustSchema() graphql.Schema {
+	s, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType, Mutation: mutationType})
+	if err != nil {
+		panic("graph: invalid schema: " + err.Error())
+	}
+	return s
+}
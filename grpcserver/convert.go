@@ -0,0 +1,118 @@
+package grpcserver
+
+import (
+	"fmt"
+	"go-product-api/models"
+	"go-product-api/problem"
+	productv1 "go-product-api/proto/product/v1"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toProto(product models.Product) (*productv1.Product, error) {
+	attributes, err := structpb.NewStruct(product.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("error converting attributes of product %s: %w", product.ID, err)
+	}
+
+	out := &productv1.Product{
+		Id:            product.ID.String(),
+		TenantId:      product.TenantID,
+		Sku:           product.SKU,
+		Name:          product.Name,
+		Description:   product.Description,
+		Status:        product.Status,
+		Tags:          product.Tags,
+		Attributes:    attributes,
+		Prices:        pricesToProto(product.Prices),
+		CategoryIds:   idsToProto(product.CategoryIDs),
+		Options:       optionsToProto(product.Options),
+		CategoryPaths: product.CategoryPaths,
+		InStock:       product.InStock,
+		CreatedAt:     timestamp(product.CreatedAt),
+		UpdatedAt:     timestamp(product.UpdatedAt),
+	}
+	for _, variant := range product.Variants {
+		prices := make([]models.ProductPrice, len(variant.Prices))
+		for i, price := range variant.Prices {
+			prices[i] = models.ProductPrice{Currency: price.Currency, Market: price.Market, Amount: price.Amount}
+		}
+		out.Variants = append(out.Variants, &productv1.Variant{
+			Id:        variant.ID.String(),
+			Sku:       variant.SKU,
+			Options:   variant.Options,
+			Prices:    pricesToProto(prices),
+			Stock:     variant.Stock,
+			CreatedAt: timestamp(variant.CreatedAt),
+			UpdatedAt: timestamp(variant.UpdatedAt),
+		})
+	}
+	if product.DisplayPrice != nil {
+		out.DisplayPrice = pricesToProto([]models.ProductPrice{*product.DisplayPrice})[0]
+	}
+	return out, nil
+}
+
+func pricesToProto(prices []models.ProductPrice) []*productv1.Price {
+	out := make([]*productv1.Price, len(prices))
+	for i, price := range prices {
+		out[i] = &productv1.Price{Currency: price.Currency, Market: price.Market, Amount: price.Amount}
+	}
+	return out
+}
+
+func optionsToProto(options models.ProductOptions) []*productv1.ProductOption {
+	out := make([]*productv1.ProductOption, len(options))
+	for i, option := range options {
+		out[i] = &productv1.ProductOption{Name: option.Name, Values: option.Values}
+	}
+	return out
+}
+
+func idsToProto(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromInput converts the editable fields of a product. Category IDs that
+// are not UUIDs are reported as invalid fields.
+func fromInput(input *productv1.ProductInput) (models.Product, []problem.FieldError) {
+	product := models.Product{
+		SKU:         input.GetSku(),
+		Name:        input.GetName(),
+		Description: input.GetDescription(),
+		Status:      input.GetStatus(),
+		Tags:        input.GetTags(),
+		Attributes:  input.GetAttributes().AsMap(),
+	}
+	for _, price := range input.GetPrices() {
+		product.Prices = append(product.Prices, models.ProductPrice{Currency: price.GetCurrency(), Market: price.GetMarket(), Amount: price.GetAmount()})
+	}
+	for _, option := range input.GetOptions() {
+		product.Options = append(product.Options, models.ProductOption{Name: option.GetName(), Values: option.GetValues()})
+	}
+
+	var errs []problem.FieldError
+	for i, value := range input.GetCategoryIds() {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			errs = append(errs, problem.FieldError{Field: fmt.Sprintf("category_ids[%d]", i), Code: "uuid", Message: "must be a UUID"})
+			continue
+		}
+		product.CategoryIDs = append(product.CategoryIDs, id)
+	}
+	return product, errs
+}
@@ -0,0 +1,88 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"go-product-api/catalog"
+	"go-product-api/config"
+	"go-product-api/logging"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"go-product-api/stream"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// newStatus returns a status error whose ErrorInfo detail carries the
+// problem code the REST API answers with, so clients of either API can
+// switch on the same codes.
+func newStatus(code codes.Code, problemCode, message string) error {
+	st := status.New(code, message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: problemCode, Domain: config.ServiceName}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// invalidArgument reports invalid fields in a BadRequest detail, named as
+// in the JSON API.
+func invalidArgument(message string, errs []problem.FieldError) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(errs))
+	for i, fe := range errs {
+		violations[i] = &errdetails.BadRequest_FieldViolation{Field: fe.Field, Description: fe.Message}
+	}
+
+	st := status.New(codes.InvalidArgument, message)
+	detailed, err := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: problem.CodeValidationFailed, Domain: config.ServiceName},
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+	if err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func invalidField(field, code, message string) error {
+	return invalidArgument("The request contains invalid fields", []problem.FieldError{{Field: field, Code: code, Message: message}})
+}
+
+// statusFromError maps a catalog or repository error to a status the way
+// respondError maps it to a problem. The underlying error is logged but
+// never sent to the client; message is used for unexpected failures.
+func statusFromError(ctx context.Context, err error, message string) error {
+	var (
+		invalidInput *catalog.ValidationError
+		optionsInUse *catalog.OptionsInUseError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		return newStatus(codes.DeadlineExceeded, problem.CodeBackendTimeout, "A backend service did not respond in time")
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, repositories.ErrProductNotFound):
+		return newStatus(codes.NotFound, "product_"+problem.CodeNotFound, "Product not found")
+	case errors.As(err, &invalidInput):
+		return invalidArgument("The request contains invalid fields", invalidInput.Errors)
+	case errors.As(err, &optionsInUse):
+		return newStatus(codes.FailedPrecondition, problem.CodeOptionsInUse,
+			"The options no longer match variant "+optionsInUse.VariantSKU+"; update or delete it first")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return newStatus(codes.AlreadyExists, "product_"+problem.CodeConflict, "Product conflicts with an existing one")
+	case errors.Is(err, repositories.ErrUnknownCategory):
+		return invalidField("category_ids", "exists", "refers to a category that does not exist")
+	case errors.Is(err, repositories.ErrElasticsearchUnavailable):
+		return newStatus(codes.Unavailable, problem.CodeServiceUnavailable, "The service is temporarily unavailable")
+	case errors.Is(err, stream.ErrShutdown):
+		return newStatus(codes.Unavailable, problem.CodeServiceUnavailable, "The server is shutting down")
+	case errors.Is(err, catalog.ErrNotPublished):
+		logging.FromContext(ctx).Error(message, "error.message", err)
+		return newStatus(codes.Internal, problem.CodeEventPublishFailed, message)
+	default:
+		logging.FromContext(ctx).Error(message, "error.message", err)
+		return newStatus(codes.Internal, problem.CodeInternal, message)
+	}
+}
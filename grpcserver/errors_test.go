@@ -0,0 +1,89 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-product-api/catalog"
+	"go-product-api/problem"
+	productv1 "go-product-api/proto/product/v1"
+	"go-product-api/repositories"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// details returns the problem code and the invalid fields carried by a
+// status error.
+func details(err error) (reason string, fields []string) {
+	for _, detail := range status.Convert(err).Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			reason = d.Reason
+		case *errdetails.BadRequest:
+			for _, violation := range d.FieldViolations {
+				fields = append(fields, violation.Field)
+			}
+		}
+	}
+	return reason, fields
+}
+
+func TestStatusFromErrorUsesTheRESTProblemCodes(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{fmt.Errorf("find: %w", repositories.ErrProductNotFound), codes.NotFound, "product_" + problem.CodeNotFound},
+		{gorm.ErrDuplicatedKey, codes.AlreadyExists, "product_" + problem.CodeConflict},
+		{context.DeadlineExceeded, codes.DeadlineExceeded, problem.CodeBackendTimeout},
+		{repositories.ErrElasticsearchUnavailable, codes.Unavailable, problem.CodeServiceUnavailable},
+		{&catalog.OptionsInUseError{VariantSKU: "SKU-1-M"}, codes.FailedPrecondition, problem.CodeOptionsInUse},
+		{fmt.Errorf("%w: broker down", catalog.ErrNotPublished), codes.Internal, problem.CodeEventPublishFailed},
+		{fmt.Errorf("connection reset"), codes.Internal, problem.CodeInternal},
+	}
+	for _, tt := range tests {
+		err := statusFromError(context.Background(), tt.err, "Failed")
+		reason, _ := details(err)
+		if status.Code(err) != tt.code || reason != tt.reason {
+			t.Errorf("%v: got %v with reason %q, want %v with %q", tt.err, status.Code(err), reason, tt.code, tt.reason)
+		}
+	}
+
+	// Unexpected errors are logged, never sent to the client.
+	if msg := status.Convert(statusFromError(context.Background(), fmt.Errorf("password=hunter2"), "Failed")).Message(); msg != "Failed" {
+		t.Errorf("got message %q, want the generic one", msg)
+	}
+}
+
+func TestProductInputReportsFieldsByJSONName(t *testing.T) {
+	_, err := productInput(&productv1.ProductInput{
+		Name:        "Shoe",
+		Prices:      []*productv1.Price{{Currency: "USD", Amount: 1999}},
+		CategoryIds: []string{"not-a-uuid"},
+	})
+	reason, fields := details(err)
+	if status.Code(err) != codes.InvalidArgument || reason != problem.CodeValidationFailed {
+		t.Fatalf("got %v with reason %q, want %v with %q", status.Code(err), reason, codes.InvalidArgument, problem.CodeValidationFailed)
+	}
+	if fmt.Sprint(fields) != "[sku category_ids[0]]" {
+		t.Fatalf("got invalid fields %v, want sku and category_ids[0]", fields)
+	}
+}
+
+func TestAuthRequiresCredentialsForProductMethods(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: productv1.ProductService_GetProduct_FullMethodName}
+	handler := func(context.Context, any) (any, error) {
+		t.Fatal("handler ran without credentials")
+		return nil, nil
+	}
+	_, err := authUnary(context.Background(), nil, info, handler)
+	if reason, _ := details(err); status.Code(err) != codes.Unauthenticated || reason != problem.CodeAuthenticationRequired {
+		t.Fatalf("got %v with reason %q, want %v with %q", status.Code(err), reason, codes.Unauthenticated, problem.CodeAuthenticationRequired)
+	}
+}
@@ -0,0 +1,273 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"go-product-api/auth"
+	"go-product-api/logging"
+	"go-product-api/middleware"
+	"go-product-api/problem"
+	productv1 "go-product-api/proto/product/v1"
+	"go-product-api/ratelimit"
+	"go-product-api/tenant"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys, the gRPC counterparts of the HTTP headers of the same
+// names.
+const (
+	requestIDKey     = "x-request-id"
+	apiKeyKey        = "x-api-key"
+	authorizationKey = "authorization"
+	tenantKey        = "x-tenant-id"
+	retryAfterKey    = "retry-after"
+)
+
+// methodRoles is the role each product method requires. Methods not listed
+// need no credentials, which covers health checking and reflection.
+var methodRoles = map[string]auth.Role{
+	productv1.ProductService_GetProduct_FullMethodName:     auth.RoleReader,
+	productv1.ProductService_ListProducts_FullMethodName:   auth.RoleReader,
+	productv1.ProductService_SearchProducts_FullMethodName: auth.RoleReader,
+	productv1.ProductService_WatchProducts_FullMethodName:  auth.RoleReader,
+	productv1.ProductService_CreateProduct_FullMethodName:  auth.RoleEditor,
+	productv1.ProductService_UpdateProduct_FullMethodName:  auth.RoleEditor,
+	productv1.ProductService_DeleteProduct_FullMethodName:  auth.RoleEditor,
+}
+
+// methodClasses is the rate limit class each product method is charged
+// against, matching the REST route it mirrors.
+var methodClasses = map[string]ratelimit.Class{
+	productv1.ProductService_GetProduct_FullMethodName:     ratelimit.ClassRead,
+	productv1.ProductService_ListProducts_FullMethodName:   ratelimit.ClassSearch,
+	productv1.ProductService_SearchProducts_FullMethodName: ratelimit.ClassSearch,
+	productv1.ProductService_WatchProducts_FullMethodName:  ratelimit.ClassRead,
+	productv1.ProductService_CreateProduct_FullMethodName:  ratelimit.ClassWrite,
+	productv1.ProductService_UpdateProduct_FullMethodName:  ratelimit.ClassWrite,
+	productv1.ProductService_DeleteProduct_FullMethodName:  ratelimit.ClassWrite,
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// The request ID interceptors reuse the caller's x-request-id when present,
+// otherwise generate one, and return it in the response headers.
+func requestIDUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id := requestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	return handler(logging.WithRequestID(ctx, id), req)
+}
+
+func requestIDStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id := requestID(ss.Context())
+	ss.SetHeader(metadata.Pairs(requestIDKey, id))
+	return handler(srv, &serverStream{ServerStream: ss, ctx: logging.WithRequestID(ss.Context(), id)})
+}
+
+func requestID(ctx context.Context) string {
+	id := firstValue(ctx, requestIDKey)
+	if id == "" || len(id) > 128 {
+		id = uuid.NewString()
+	}
+	return id
+}
+
+func loggingUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func loggingStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logCall(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// logCall writes one structured access log entry per call, like
+// middleware.Logger does for HTTP requests.
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	attrs := []slog.Attr{
+		slog.String("rpc.system", "grpc"),
+		slog.String("rpc.service", service),
+		slog.String("rpc.method", name),
+		slog.String("rpc.grpc.status_code", code.String()),
+		slog.Int64("event.duration", time.Since(start).Nanoseconds()),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("client.address", p.Addr.String()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error.message", status.Convert(err).Message()))
+	}
+
+	logging.FromContext(ctx).LogAttrs(ctx, level, "rpc completed", attrs...)
+}
+
+func recoveryUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer recoverCall(ctx, &err)
+	return handler(ctx, req)
+}
+
+func recoveryStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverCall(ss.Context(), &err)
+	return handler(srv, ss)
+}
+
+// recoverCall turns a panicking handler into an INTERNAL error and logs the
+// panic with its stack trace.
+func recoverCall(ctx context.Context, err *error) {
+	if r := recover(); r != nil {
+		logging.FromContext(ctx).Error("Handler panicked",
+			"error.message", r,
+			"error.stack_trace", string(debug.Stack()),
+		)
+		*err = newStatus(codes.Internal, problem.CodeInternal, "Internal server error")
+	}
+}
+
+func authUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func authStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// The rate limit interceptors charge every call against the per-IP limit
+// and, once authenticated, product methods against the limit of their
+// class, like ratelimit.PerIP and ratelimit.Middleware do for HTTP. The IP
+// limit has to run ahead of authentication and the class limit after it.
+func limitIPUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := limit(ctx, ratelimit.ClassIP, "ip:"+peerIP(ctx)); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func limitIPStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := limit(ss.Context(), ratelimit.ClassIP, "ip:"+peerIP(ss.Context())); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func rateLimitUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if class, ok := methodClasses[info.FullMethod]; ok {
+		if err := limit(ctx, class, ratelimit.Client(ctx, peerIP(ctx))); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+func rateLimitStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := ss.Context()
+	if class, ok := methodClasses[info.FullMethod]; ok {
+		if err := limit(ctx, class, ratelimit.Client(ctx, peerIP(ctx))); err != nil {
+			return err
+		}
+	}
+	return handler(srv, ss)
+}
+
+// limit takes a token for the call and fails with RESOURCE_EXHAUSTED, with
+// a retry-after header, once the client's bucket for the class runs dry.
+func limit(ctx context.Context, class ratelimit.Class, client string) error {
+	res, _ := ratelimit.Take(ctx, class, client, 1)
+	if res.Allowed {
+		return nil
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(retryAfterKey, strconv.Itoa(ratelimit.RetryAfterSeconds(res))))
+	return newStatus(codes.ResourceExhausted, problem.CodeRateLimited, "Rate limit exceeded")
+}
+
+// peerIP returns the address the call came from, without its port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+// authorize authenticates the caller of a product method from its
+// x-api-key or authorization metadata, checks its role and scopes the call
+// to its tenant, the same way the REST routes do.
+func authorize(ctx context.Context, method string) (context.Context, error) {
+	required, ok := methodRoles[method]
+	if !ok {
+		return ctx, nil
+	}
+
+	principal, err := auth.Resolve(ctx, firstValue(ctx, apiKeyKey), firstValue(ctx, authorizationKey))
+	if errors.Is(err, auth.ErrNoCredentials) {
+		return nil, newStatus(codes.Unauthenticated, problem.CodeAuthenticationRequired, "Authentication required")
+	}
+	if err != nil {
+		return nil, newStatus(codes.Unauthenticated, problem.CodeInvalidCredentials, "Invalid credentials")
+	}
+	if !principal.Role.Allows(required) {
+		return nil, newStatus(codes.PermissionDenied, problem.CodeForbidden, "Insufficient permissions")
+	}
+	ctx = auth.Bind(ctx, principal)
+
+	tenantID, err := middleware.ResolveTenant(ctx, firstValue(ctx, tenantKey))
+	if errors.Is(err, middleware.ErrTenantForbidden) {
+		return nil, newStatus(codes.PermissionDenied, problem.CodeTenantForbidden, "Access to tenant denied")
+	}
+	if err != nil {
+		return nil, newStatus(codes.InvalidArgument, problem.CodeInvalidTenant, err.Error())
+	}
+	return tenant.WithTenant(ctx, tenantID), nil
+}
+
+func firstValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
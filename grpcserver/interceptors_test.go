@@ -0,0 +1,40 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"go-product-api/auth"
+	"go-product-api/config"
+	productv1 "go-product-api/proto/product/v1"
+	"go-product-api/ratelimit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitChargesTheMethodClassPerPrincipal(t *testing.T) {
+	previousRPS, previousBurst := config.RateLimitSearchRPS, config.RateLimitSearchBurst
+	config.RateLimitSearchRPS, config.RateLimitSearchBurst = 0.001, 1
+	t.Cleanup(func() { config.RateLimitSearchRPS, config.RateLimitSearchBurst = previousRPS, previousBurst })
+	ratelimit.Init()
+
+	info := &grpc.UnaryServerInfo{FullMethod: productv1.ProductService_ListProducts_FullMethodName}
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	call := func(subject string) error {
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: subject, Method: "api_key", Role: auth.RoleReader})
+		_, err := rateLimitUnary(ctx, nil, info, handler)
+		return err
+	}
+
+	if err := call("key-a"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if code := status.Code(call("key-a")); code != codes.ResourceExhausted {
+		t.Fatalf("second call: got %v, want %v", code, codes.ResourceExhausted)
+	}
+	if err := call("key-b"); err != nil {
+		t.Fatalf("another principal: %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"go-product-api/catalog"
+	"go-product-api/models"
+	"go-product-api/problem"
+	productv1 "go-product-api/proto/product/v1"
+	"go-product-api/stream"
+	"go-product-api/tenant"
+	"strconv"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// productService implements the product methods on top of the catalog
+// package, validating requests with the binding tags the REST handlers use.
+type productService struct {
+	productv1.UnimplementedProductServiceServer
+}
+
+func (s *productService) GetProduct(ctx context.Context, req *productv1.GetProductRequest) (*productv1.Product, error) {
+	display, err := displayQuery(req.GetDisplay())
+	if err != nil {
+		return nil, err
+	}
+	pricer, err := catalog.NewPricer(ctx, display)
+	if err != nil {
+		return nil, statusFromError(ctx, err, "Failed to fetch exchange rates")
+	}
+
+	strong := req.GetConsistency() == productv1.Consistency_CONSISTENCY_STRONG
+	var product models.Product
+	switch lookup := req.GetLookup().(type) {
+	case *productv1.GetProductRequest_Id:
+		id, err := uuid.Parse(lookup.Id)
+		if err != nil {
+			return nil, invalidField("id", "uuid", "must be a UUID")
+		}
+		if req.GetAsOf() != nil {
+			product, _, err = catalog.GetAt(ctx, id, req.GetAsOf().AsTime())
+		} else {
+			product, _, err = catalog.Get(ctx, id, strong)
+		}
+		if err != nil {
+			return nil, statusFromError(ctx, err, "Failed to fetch product")
+		}
+	case *productv1.GetProductRequest_Sku:
+		if req.GetAsOf() != nil {
+			return nil, invalidField("as_of", "excluded_with", "is only supported for lookups by id")
+		}
+		if product, _, err = catalog.GetBySKU(ctx, lookup.Sku, strong); err != nil {
+			return nil, statusFromError(ctx, err, "Failed to fetch product")
+		}
+	default:
+		return nil, invalidField("id", "required", "id or sku is required")
+	}
+	pricer.Apply(&product)
+
+	return toProto(product)
+}
+
+func (s *productService) ListProducts(ctx context.Context, req *productv1.ListProductsRequest) (*productv1.ListProductsResponse, error) {
+	return listProducts(ctx, "", req.GetFilter(), req.GetDisplay(), req.GetConsistency(), req.GetPageSize(), req.GetPageToken())
+}
+
+func (s *productService) SearchProducts(ctx context.Context, req *productv1.SearchProductsRequest) (*productv1.ListProductsResponse, error) {
+	if req.GetQuery() == "" {
+		return nil, invalidField("query", "required", "is required")
+	}
+	return listProducts(ctx, req.GetQuery(), req.GetFilter(), req.GetDisplay(), req.GetConsistency(), req.GetPageSize(), req.GetPageToken())
+}
+
+// listProducts serves a page of the product list. Page tokens are opaque
+// to clients and encode the offset of the next page.
+func listProducts(ctx context.Context, text string, in *productv1.ProductFilter, display *productv1.Display, consistency productv1.Consistency, pageSize int32, pageToken string) (*productv1.ListProductsResponse, error) {
+	if in == nil {
+		in = &productv1.ProductFilter{}
+	}
+	query := catalog.ListQuery{
+		DisplayQuery: catalog.DisplayQuery{DisplayCurrency: display.GetCurrency(), Market: display.GetMarket()},
+		Query:        text,
+		Currency:     in.GetCurrency(),
+		MinPrice:     in.MinPrice,
+		MaxPrice:     in.MaxPrice,
+		InStock:      in.InStock,
+		Option:       in.GetOptions(),
+	}
+	switch in.GetSort() {
+	case productv1.PriceSort_PRICE_SORT_ASC:
+		query.Sort = "price_asc"
+	case productv1.PriceSort_PRICE_SORT_DESC:
+		query.Sort = "price_desc"
+	}
+	switch {
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize < 0 || pageSize > maxPageSize:
+		return nil, invalidField("page_size", "max", "must be between 1 and "+strconv.Itoa(maxPageSize))
+	}
+	offset, err := decodePageToken(pageToken)
+	if err != nil {
+		return nil, invalidField("page_token", "page_token", "is not a page token from this service")
+	}
+	// The page goes through the same checks as the REST limit and offset,
+	// so a token cannot page deeper than the list allows.
+	query.Limit, query.Offset = int(pageSize), offset
+
+	if err := validate(&query); err != nil {
+		return nil, err
+	}
+	filter, err := query.Filter()
+	if err != nil {
+		return nil, statusFromError(ctx, err, "Failed to fetch products")
+	}
+
+	pricer, err := catalog.NewPricer(ctx, query.DisplayQuery)
+	if err != nil {
+		return nil, statusFromError(ctx, err, "Failed to fetch exchange rates")
+	}
+	products, _, err := catalog.List(ctx, filter, consistency == productv1.Consistency_CONSISTENCY_STRONG)
+	if err != nil {
+		return nil, statusFromError(ctx, err, "Failed to fetch products")
+	}
+
+	resp := &productv1.ListProductsResponse{Products: make([]*productv1.Product, 0, len(products))}
+	for i := range products {
+		pricer.Apply(&products[i])
+		product, err := toProto(products[i])
+		if err != nil {
+			return nil, statusFromError(ctx, err, "Failed to fetch products")
+		}
+		resp.Products = append(resp.Products, product)
+	}
+	if len(products) == int(pageSize) {
+		resp.NextPageToken = encodePageToken(offset + len(products))
+	}
+	return resp, nil
+}
+
+func (s *productService) CreateProduct(ctx context.Context, req *productv1.CreateProductRequest) (*productv1.Product, error) {
+	input, err := productInput(req.GetProduct())
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := catalog.Create(ctx, input)
+	if err != nil {
+		return nil, statusFromError(ctx, err, "Failed to create product")
+	}
+	return toProto(product)
+}
+
+func (s *productService) UpdateProduct(ctx context.Context, req *productv1.UpdateProductRequest) (*productv1.Product, error) {
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, invalidField("id", "uuid", "must be a UUID")
+	}
+	input, err := productInput(req.GetProduct())
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := catalog.Update(ctx, id, input)
+	if err != nil {
+		return nil, statusFromError(ctx, err, "Failed to update product")
+	}
+	return toProto(product)
+}
+
+func (s *productService) DeleteProduct(ctx context.Context, req *productv1.DeleteProductRequest) (*emptypb.Empty, error) {
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, invalidField("id", "uuid", "must be a UUID")
+	}
+
+	if _, err := catalog.Delete(ctx, id); err != nil {
+		return nil, statusFromError(ctx, err, "Failed to delete product")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// WatchProducts sends the tenant's product events until the client goes
+// away. A client that falls behind, or a server shutting down, ends the
+// stream with UNAVAILABLE so the client reconnects with last_event_id.
+func (s *productService) WatchProducts(req *productv1.WatchProductsRequest, srv productv1.ProductService_WatchProductsServer) error {
+	ctx := srv.Context()
+	query := stream.Query{ProductIDs: req.GetProductIds(), Types: req.GetTypes(), LastEventID: req.GetLastEventId()}
+	if err := validate(&query); err != nil {
+		return err
+	}
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return statusFromError(ctx, err, "Failed to open product stream")
+	}
+	session, err := stream.Open(ctx, tenantID, query.LastEventID, query.Filter())
+	if errors.Is(err, stream.ErrInvalidEventID) {
+		return invalidField("last_event_id", "event_id", "is not an event ID from this stream")
+	}
+	if err != nil {
+		return statusFromError(ctx, err, "Failed to open product stream")
+	}
+
+	for msg := range session.Messages() {
+		event := &productv1.ProductEvent{Id: msg.ID, Type: string(msg.Type), OccurredAt: timestamp(msg.OccurredAt)}
+		if msg.ProductID != uuid.Nil {
+			event.ProductId = msg.ProductID.String()
+		}
+		if msg.Product != nil {
+			if event.Product, err = toProto(*msg.Product); err != nil {
+				return statusFromError(ctx, err, "Failed to stream products")
+			}
+		}
+		if err := srv.Send(event); err != nil {
+			return err
+		}
+	}
+
+	switch err := session.Err(); {
+	case errors.Is(err, stream.ErrLagged):
+		return newStatus(codes.Unavailable, problem.CodeServiceUnavailable, err.Error())
+	case err != nil:
+		return statusFromError(ctx, err, "Failed to stream products")
+	}
+	return ctx.Err()
+}
+
+func displayQuery(display *productv1.Display) (catalog.DisplayQuery, error) {
+	query := catalog.DisplayQuery{DisplayCurrency: display.GetCurrency(), Market: display.GetMarket()}
+	return query, validate(&query)
+}
+
+func productInput(in *productv1.ProductInput) (models.Product, error) {
+	if in == nil {
+		return models.Product{}, invalidField("product", "required", "is required")
+	}
+	product, errs := fromInput(in)
+	if err := binding.Validator.ValidateStruct(&product); err != nil {
+		errs = append(problem.FromBindError(err).Errors, errs...)
+	}
+	if len(errs) > 0 {
+		return models.Product{}, invalidArgument("The request contains invalid fields", errs)
+	}
+	return product, nil
+}
+
+// validate checks a request struct against its binding tags.
+func validate(obj any) error {
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		return invalidArgument("The request contains invalid fields", problem.FromBindError(err).Errors)
+	}
+	return nil
+}
+
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(b))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid page token")
+	}
+	return offset, nil
+}
@@ -0,0 +1,25 @@
+package grpcserver
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListProductsRejectsPageTokensPastTheListWindow(t *testing.T) {
+	tokens := map[string]string{
+		"deep":     encodePageToken(9990),
+		"overflow": encodePageToken(math.MaxInt),
+	}
+	for name, token := range tokens {
+		t.Run(name, func(t *testing.T) {
+			_, err := listProducts(context.Background(), "", nil, nil, 0, 50, token)
+			if code := status.Code(err); code != codes.InvalidArgument {
+				t.Fatalf("got %v (%v), want %v", code, err, codes.InvalidArgument)
+			}
+		})
+	}
+}
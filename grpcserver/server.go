@@ -0,0 +1,44 @@
+// Package grpcserver serves the product operations of the REST API over
+// gRPC for internal services, using the same catalog service layer,
+// validation, credentials and tenants.
+package grpcserver
+
+import (
+	"go-product-api/config"
+	productv1 "go-product-api/proto/product/v1"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+// New returns a server with the product service, health checking and,
+// unless disabled, reflection registered. Health reports SERVING for the
+// server as a whole and for product.v1.ProductService.
+func New() *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(requestIDUnary, loggingUnary, recoveryUnary, limitIPUnary, authUnary, rateLimitUnary),
+		grpc.ChainStreamInterceptor(requestIDStream, loggingStream, recoveryStream, limitIPStream, authStream, rateLimitStream),
+		// Pings keep idle WatchProducts streams alive through proxies, the
+		// way heartbeats do for the HTTP streams.
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    config.StreamHeartbeatInterval,
+			Timeout: config.StreamWriteTimeout,
+		}),
+	)
+
+	productv1.RegisterProductServiceServer(srv, &productService{})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(productv1.ProductService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+
+	if config.GRPCReflection {
+		reflection.Register(srv)
+	}
+	return srv
+}
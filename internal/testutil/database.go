@@ -0,0 +1,46 @@
+// Package testutil holds helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"errors"
+	"testing"
+
+	"go-product-api/config"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ErrStop is returned from an expected statement to end the code under test
+// there, once the statements that matter have been seen to run in order.
+var ErrStop = errors.New("stop")
+
+// MockDatabase points config.DB at a sqlmock connection, configured like
+// the real one, for the duration of the test and checks every expected
+// statement ran. Expectations are
+// regular expressions, so tests match the tables, locks and arguments they
+// are about rather than the full text GORM generates.
+func MockDatabase(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{TranslateError: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previous
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		sqlDB.Close()
+	})
+	return mock
+}
@@ -0,0 +1,67 @@
+package inventory
+
+import (
+	"context"
+	"go-product-api/config"
+	"go-product-api/events"
+	"go-product-api/logging"
+	"go-product-api/repositories"
+	"go-product-api/tenant"
+	"log/slog"
+	"time"
+)
+
+// sweepBatch bounds how many reservations one transaction expires.
+const sweepBatch = 100
+
+// StartReservationExpiry releases expired reservations every
+// ReservationSweepInterval until ctx is cancelled, and publishes a stock
+// event for each. The returned channel is closed once the loop has exited.
+// Running it in several instances is safe: each reservation is locked by
+// exactly one sweep.
+func StartReservationExpiry(ctx context.Context) <-chan struct{} {
+	stockRepo := repositories.NewStockRepository()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(config.ReservationSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("reservation expiry stopped")
+				return
+			case <-ticker.C:
+				sweep(ctx, stockRepo)
+			}
+		}
+	}()
+	slog.Info("reservation expiry started", "interval", config.ReservationSweepInterval.String())
+	return done
+}
+
+func sweep(ctx context.Context, stockRepo *repositories.StockRepository) {
+	for ctx.Err() == nil {
+		expired, err := stockRepo.ExpireReservations(ctx, sweepBatch)
+		if err != nil {
+			slog.Error("Failed to expire reservations", "error.message", err)
+			return
+		}
+
+		for _, e := range expired {
+			reservation := e.Reservation
+			ctx := tenant.WithTenant(ctx, reservation.TenantID)
+			event := events.StockEvent{Type: events.ReservationExpired, Level: e.Level, Reservation: &reservation}
+			if err := events.PublishStockEvent(ctx, event); err != nil {
+				logging.FromContext(ctx).Error("Failed to publish reservation expiry", "reservation.id", reservation.ID, "error.message", err)
+			}
+		}
+		if len(expired) > 0 {
+			slog.Info("Expired reservations", "count", len(expired))
+		}
+		if len(expired) < sweepBatch {
+			return
+		}
+	}
+}
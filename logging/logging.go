@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go-product-api/tenant"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ecsVersion = "8.11.0"
+
+type contextKey struct{}
+
+// Init installs the default slog logger. LOG_FORMAT selects json (default)
+// or text output and LOG_LEVEL one of debug, info (default), warn or error.
+// JSON output uses ECS field names so the Logstash pipeline can index it
+// without extra mapping.
+func Init(serviceName string) {
+	InitTo(os.Stdout, serviceName)
+}
+
+// InitTo is Init writing to w, for commands whose standard output is their
+// result.
+func InitTo(w io.Writer, serviceName string) {
+	slog.SetDefault(New(w, os.Getenv("LOG_FORMAT"), os.Getenv("LOG_LEVEL"), serviceName))
+}
+
+func New(w io.Writer, format, level, serviceName string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		opts.ReplaceAttr = ecsAttr
+		handler = slog.NewJSONHandler(w, opts).WithAttrs([]slog.Attr{
+			slog.String("ecs.version", ecsVersion),
+			slog.String("service.name", serviceName),
+		})
+	}
+	return slog.New(handler)
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func ecsAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "@timestamp"
+	case slog.LevelKey:
+		a.Key = "log.level"
+		a.Value = slog.StringValue(strings.ToLower(a.Value.String()))
+	case slog.MessageKey:
+		a.Key = "message"
+	}
+	return a
+}
+
+// WithRequestID stores the request (correlation) ID on the context.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestID)
+}
+
+// RequestID returns the request ID stored on the context, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromContext returns the default logger enriched with the request ID and
+// the active trace and span IDs found on the context.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := RequestID(ctx); id != "" {
+		logger = logger.With(slog.String("http.request.id", id))
+	}
+	if id, err := tenant.FromContext(ctx); err == nil {
+		logger = logger.With(slog.String("organization.id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		logger = logger.With(
+			slog.String("trace.id", sc.TraceID().String()),
+			slog.String("span.id", sc.SpanID().String()),
+		)
+	}
+	return logger
+}
+
+// Fatal logs at error level and exits, replacing log.Fatalf for startup
+// failures.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"go-product-api/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger writes one structured access log entry per request, replacing
+// gin's default text logger.
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("http.request.method", c.Request.Method),
+			slog.String("url.path", c.Request.URL.Path),
+			slog.String("url.query", c.Request.URL.RawQuery),
+			slog.Int("http.response.status_code", status),
+			slog.Int("http.response.body.bytes", c.Writer.Size()),
+			slog.Int64("event.duration", time.Since(start).Nanoseconds()),
+			slog.String("client.ip", c.ClientIP()),
+			slog.String("user_agent.original", c.Request.UserAgent()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error.message", c.Errors.String()))
+		}
+
+		logging.FromContext(c.Request.Context()).LogAttrs(c.Request.Context(), level, "request completed", attrs...)
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"go-product-api/logging"
+	"go-product-api/problem"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panicking handler into a 500 problem response and logs
+// the panic with its stack trace.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.FromContext(c.Request.Context()).Error("Handler panicked",
+					"error.message", r,
+					"error.stack_trace", string(debug.Stack()),
+				)
+				problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternal, "Internal server error"))
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-product-api/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecoveryAnswersPanicsWithAProblem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery())
+	r.GET("/boom", func(*gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusInternalServerError || p.Code != problem.CodeInternal || p.Detail == "boom" {
+		t.Fatalf("got status %d, problem %+v; want an internal_error that hides the panic", w.Code, p)
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"go-product-api/auth"
+	"go-product-api/config"
+	"go-product-api/problem"
+	"go-product-api/tenant"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const TenantHeader = "X-Tenant-ID"
+
+// ErrTenantForbidden is returned by ResolveTenant when a caller bound to a
+// tenant asks for another one.
+var ErrTenantForbidden = errors.New("access to tenant denied")
+
+// Tenant scopes the request to a tenant. Callers bound to a tenant by their
+// credentials always get that tenant and may only repeat it in X-Tenant-ID;
+// unbound callers, which are only the bootstrap admin key, keys created
+// for all tenants, JWTs when AUTH_JWT_ALLOW_UNSCOPED is set and everyone
+// when AUTH_DISABLED is set, choose one with the header or get the default
+// tenant. It must run after auth.Authenticate.
+func Tenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := ResolveTenant(c.Request.Context(), c.GetHeader(TenantHeader))
+		if errors.Is(err, ErrTenantForbidden) {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeTenantForbidden, "Access to tenant denied"))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidTenant, err.Error()))
+			return
+		}
+
+		c.Header(TenantHeader, tenantID)
+		c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), tenantID))
+		c.Next()
+	}
+}
+
+// ResolveTenant picks the tenant for the authenticated caller on ctx, as
+// Tenant does, given the tenant it requested, if any.
+func ResolveTenant(ctx context.Context, requested string) (string, error) {
+	tenantID := requested
+	if p, ok := auth.PrincipalFromContext(ctx); ok && p.TenantID != "" {
+		if requested != "" && requested != p.TenantID {
+			return "", ErrTenantForbidden
+		}
+		tenantID = p.TenantID
+	}
+	if tenantID == "" {
+		tenantID = config.DefaultTenant
+	}
+
+	if err := tenant.Validate(tenantID); err != nil {
+		return "", err
+	}
+	return tenantID, nil
+}
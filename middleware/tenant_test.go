@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-product-api/auth"
+)
+
+func TestResolveTenantKeepsBoundCallersInTheirTenant(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "key-a", Role: auth.RoleAdmin, TenantID: "tenant-a"})
+
+	for _, requested := range []string{"", "tenant-a"} {
+		tenantID, err := ResolveTenant(ctx, requested)
+		if err != nil || tenantID != "tenant-a" {
+			t.Fatalf("requested %q: got %q, %v; want tenant-a", requested, tenantID, err)
+		}
+	}
+	if tenantID, err := ResolveTenant(ctx, "tenant-b"); !errors.Is(err, ErrTenantForbidden) {
+		t.Fatalf("requested tenant-b: got %q, %v; want %v", tenantID, err, ErrTenantForbidden)
+	}
+}
+
+func TestResolveTenantLetsUnboundCallersChoose(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "bootstrap", Role: auth.RoleAdmin})
+
+	for _, requested := range []string{"tenant-a", "tenant-b"} {
+		tenantID, err := ResolveTenant(ctx, requested)
+		if err != nil || tenantID != requested {
+			t.Fatalf("requested %q: got %q, %v", requested, tenantID, err)
+		}
+	}
+}
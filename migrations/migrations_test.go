@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"go-product-api/internal/testutil"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestEmbeddedMigrationsAreComplete(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 {
+		t.Fatal("no migrations are embedded")
+	}
+	for i, m := range all {
+		if i > 0 && m.Version <= all[i-1].Version {
+			t.Errorf("migration %d_%s is out of order", m.Version, m.Name)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %d_%s cannot be reverted", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		if m.Checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("migration %d_%s has checksum %s, want that of its up file", m.Version, m.Name, m.Checksum)
+		}
+	}
+}
+
+// expectApplied expects the applied migrations to be read and returns rows
+// to fill them in.
+func expectApplied(mock sqlmock.Sqlmock) *sqlmock.Rows {
+	mock.ExpectQuery(`FROM information_schema.tables`).
+		WithArgs("schema_migrations", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"})
+	mock.ExpectQuery(`FROM "schema_migrations"`).WillReturnRows(rows)
+	return rows
+}
+
+func TestCheck(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := all[0]
+	appliedAt := time.Now()
+
+	t.Run("pending", func(t *testing.T) {
+		expectApplied(testutil.MockDatabase(t))
+		if err := Check(context.Background()); !errors.Is(err, ErrSchemaOutdated) {
+			t.Fatalf("got error %v, want %v", err, ErrSchemaOutdated)
+		}
+	})
+	t.Run("modified", func(t *testing.T) {
+		rows := expectApplied(testutil.MockDatabase(t))
+		rows.AddRow(first.Version, first.Name, "edited", appliedAt)
+		for _, m := range all[1:] {
+			rows.AddRow(m.Version, m.Name, m.Checksum, appliedAt)
+		}
+		if err := Check(context.Background()); err == nil || errors.Is(err, ErrSchemaOutdated) {
+			t.Fatalf("got error %v, want the modified migration reported", err)
+		}
+	})
+	t.Run("applied by a newer binary", func(t *testing.T) {
+		rows := expectApplied(testutil.MockDatabase(t))
+		for _, m := range all {
+			rows.AddRow(m.Version, m.Name, m.Checksum, appliedAt)
+		}
+		rows.AddRow(all[len(all)-1].Version+1, "from_the_future", "unknown", appliedAt)
+		if err := Check(context.Background()); err != nil {
+			t.Fatalf("got error %v, want the newer migration tolerated", err)
+		}
+	})
+}
@@ -0,0 +1,278 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-product-api/config"
+	"go-product-api/money"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockKey identifies the advisory lock migrations hold, so that instances
+// starting together migrate one after the other.
+const lockKey int64 = 0x70726f6475637473 // "products"
+
+// ErrSchemaOutdated is returned by Check when migrations are pending.
+var ErrSchemaOutdated = errors.New("database schema is not up to date")
+
+// Status is a migration as known to the binary, the database or both.
+// AppliedAt is nil for a pending migration. Modified means the migration
+// was changed after it was applied; Unknown that it was applied by a newer
+// binary and is not embedded in this one.
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Modified  bool
+	Unknown   bool
+}
+
+// Pending reports whether the migration has yet to be applied.
+func (s Status) Pending() bool {
+	return s.AppliedAt == nil
+}
+
+type appliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// List returns the status of every migration, embedded or applied, in
+// version order.
+func List(ctx context.Context) ([]Status, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	// Reading the status never writes, so a database that was never
+	// migrated simply has nothing applied.
+	db := config.DB.WithContext(ctx)
+	applied := map[int]appliedMigration{}
+	if db.Migrator().HasTable("schema_migrations") {
+		if applied, err = appliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+	return statuses(all, applied), nil
+}
+
+// Check returns ErrSchemaOutdated when a migration is pending, and an error
+// as well when an applied migration was modified since. Migrations applied
+// by a newer binary are only logged, so an older instance keeps serving
+// during a rolling deploy.
+func Check(ctx context.Context) error {
+	statuses, err := List(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range statuses {
+		switch {
+		case s.Modified:
+			return fmt.Errorf("migration %d_%s was modified after it was applied", s.Version, s.Name)
+		case s.Unknown:
+			slog.Warn("Database has a migration this binary does not know", "migration.version", s.Version, "migration.name", s.Name)
+		case s.Pending():
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d migrations pending", ErrSchemaOutdated, pending)
+	}
+	return nil
+}
+
+// Up applies the pending migrations up to and including version to, or all
+// of them when to is 0, each in its own transaction. It returns the
+// migrations it applied, which are all applied when the error is nil.
+func Up(ctx context.Context, to int) ([]Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = withLock(ctx, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses(all, applied) {
+			if s.Modified {
+				return fmt.Errorf("migration %d_%s was modified after it was applied", s.Version, s.Name)
+			}
+		}
+
+		for _, m := range all {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if to > 0 && m.Version > to {
+				break
+			}
+			slog.Info("Applying migration", "migration.version", m.Version, "migration.name", m.Name)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := setParameters(tx); err != nil {
+					return err
+				}
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+				return tx.Table("schema_migrations").Create(&appliedMigration{
+					Version:   m.Version,
+					Name:      m.Name,
+					Checksum:  m.Checksum,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, each in
+// its own transaction. It returns the migrations it reverted.
+func Down(ctx context.Context, steps int) ([]Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	embedded := map[int]Migration{}
+	for _, m := range all {
+		embedded[m.Version] = m
+	}
+
+	var done []Migration
+	err = withLock(ctx, func(conn *gorm.DB) error {
+		var applied []appliedMigration
+		err := conn.Table("schema_migrations").Order("version DESC").Limit(steps).Find(&applied).Error
+		if err != nil {
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+
+		for _, a := range applied {
+			m, ok := embedded[a.Version]
+			switch {
+			case !ok:
+				return fmt.Errorf("migration %d_%s is not known to this binary", a.Version, a.Name)
+			case m.Down == "":
+				return fmt.Errorf("migration %d_%s cannot be reverted", m.Version, m.Name)
+			}
+			slog.Info("Reverting migration", "migration.version", m.Version, "migration.name", m.Name)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := setParameters(tx); err != nil {
+					return err
+				}
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+				return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// withLock runs fn on a single connection holding the migration lock,
+// waiting for any other runner to finish first.
+func withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return config.DB.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", lockKey).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if !locked {
+			slog.Info("Waiting for another migration run to finish")
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+				return fmt.Errorf("failed to take migration lock: %w", err)
+			}
+		}
+		// Unlock even when ctx is done, or the lock stays with the
+		// connection in the pool.
+		defer func() {
+			unlock := conn.WithContext(context.WithoutCancel(ctx))
+			if err := unlock.Exec("SELECT pg_advisory_unlock(?)", lockKey).Error; err != nil {
+				slog.Error("Failed to release migration lock", "error.message", err)
+			}
+		}()
+
+		if err := createTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func createTable(db *gorm.DB) error {
+	err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		checksum text NOT NULL,
+		applied_at timestamptz NOT NULL
+	)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := db.Table("schema_migrations").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := make(map[int]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func statuses(all []Migration, applied map[int]appliedMigration) []Status {
+	out := make([]Status, 0, len(all)+len(applied))
+	embedded := map[int]bool{}
+	for _, m := range all {
+		embedded[m.Version] = true
+		s := Status{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			s.AppliedAt = &a.AppliedAt
+			s.Modified = a.Checksum != m.Checksum
+		}
+		out = append(out, s)
+	}
+	for _, a := range applied {
+		if !embedded[a.Version] {
+			out = append(out, Status{Version: a.Version, Name: a.Name, AppliedAt: &a.AppliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// setParameters makes settings migrations may need available to their SQL
+// through current_setting, for the rest of the transaction.
+func setParameters(tx *gorm.DB) error {
+	factor := int64(math.Pow10(money.Exponent(config.DefaultCurrency)))
+	return tx.Exec("SELECT set_config('product_api.default_currency', ?, true), set_config('product_api.default_currency_factor', ?, true)",
+		config.DefaultCurrency, strconv.FormatInt(factor, 10)).Error
+}
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS
+    api_keys,
+    webhook_deliveries,
+    webhook_subscriptions,
+    stored_product_events,
+    audit_entries,
+    reservations,
+    stock_levels,
+    variant_prices,
+    product_variants,
+    product_categories,
+    categories,
+    price_histories,
+    scheduled_price_changes,
+    exchange_rates,
+    product_prices,
+    products;
@@ -0,0 +1,257 @@
+-- The schema as AutoMigrate left it. Every statement is idempotent so a
+-- database created by AutoMigrate is adopted as is; products tables from
+-- before SKUs and prices existed are brought up to date on the way.
+
+CREATE TABLE IF NOT EXISTS products (
+    id uuid PRIMARY KEY,
+    tenant_id text NOT NULL DEFAULT 'default',
+    sku varchar(64) NOT NULL,
+    name text,
+    description text,
+    status varchar(16) NOT NULL DEFAULT 'draft',
+    tags jsonb NOT NULL DEFAULT '[]',
+    attributes jsonb NOT NULL DEFAULT '{}',
+    options jsonb NOT NULL DEFAULT '[]',
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+-- Products created before SKUs get their ID as SKU and stay live as active.
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS tenant_id text NOT NULL DEFAULT 'default',
+    ADD COLUMN IF NOT EXISTS sku varchar(64),
+    ADD COLUMN IF NOT EXISTS status varchar(16) NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS tags jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS attributes jsonb NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS options jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();
+UPDATE products SET sku = id::text WHERE sku IS NULL;
+ALTER TABLE products
+    ALTER COLUMN sku SET NOT NULL,
+    ALTER COLUMN status SET DEFAULT 'draft',
+    ALTER COLUMN created_at DROP DEFAULT,
+    ALTER COLUMN updated_at DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_products_tenant_id ON products (tenant_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_tenant_sku ON products (tenant_id, sku);
+CREATE INDEX IF NOT EXISTS idx_products_status ON products (status);
+
+CREATE TABLE IF NOT EXISTS product_prices (
+    id uuid PRIMARY KEY,
+    product_id uuid NOT NULL,
+    currency varchar(3) NOT NULL,
+    market varchar(2) NOT NULL DEFAULT '',
+    amount bigint NOT NULL,
+    CONSTRAINT fk_products_prices FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_prices_currency_market ON product_prices (product_id, currency, market);
+
+-- The old products.price column held whole units of the default currency;
+-- it moves into product_prices as minor units.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'products' AND column_name = 'price') THEN
+        INSERT INTO product_prices (id, product_id, currency, market, amount)
+        SELECT gen_random_uuid(), id, current_setting('product_api.default_currency'), '',
+               price * current_setting('product_api.default_currency_factor')::bigint
+        FROM products
+        WHERE price IS NOT NULL
+        ON CONFLICT DO NOTHING;
+        ALTER TABLE products DROP COLUMN price;
+    END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    base_currency varchar(3),
+    quote_currency varchar(3),
+    rate decimal NOT NULL,
+    updated_at timestamptz,
+    PRIMARY KEY (base_currency, quote_currency)
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_price_changes (
+    id uuid PRIMARY KEY,
+    tenant_id text NOT NULL,
+    product_id uuid NOT NULL,
+    prices jsonb NOT NULL,
+    effective_at timestamptz NOT NULL,
+    status varchar(16) NOT NULL,
+    actor text NOT NULL,
+    created_at timestamptz NOT NULL,
+    applied_at timestamptz,
+    CONSTRAINT fk_scheduled_price_changes_product FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_price_changes_product_id ON scheduled_price_changes (product_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_price_changes_due ON scheduled_price_changes (status, effective_at);
+
+CREATE TABLE IF NOT EXISTS price_histories (
+    id uuid PRIMARY KEY,
+    tenant_id text NOT NULL,
+    product_id uuid NOT NULL,
+    currency varchar(3) NOT NULL,
+    market varchar(2) NOT NULL DEFAULT '',
+    amount bigint NOT NULL,
+    effective_from timestamptz NOT NULL,
+    effective_to timestamptz,
+    actor text NOT NULL,
+    scheduled_change_id uuid,
+    CONSTRAINT fk_price_histories_product FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_price_history_product ON price_histories (product_id, currency, market);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id uuid PRIMARY KEY,
+    tenant_id text NOT NULL,
+    parent_id uuid,
+    name text NOT NULL,
+    path text NOT NULL,
+    position bigint NOT NULL,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_categories_tenant_id ON categories (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+CREATE INDEX IF NOT EXISTS idx_categories_path ON categories (path);
+
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id uuid,
+    category_id uuid,
+    PRIMARY KEY (product_id, category_id),
+    CONSTRAINT fk_product_categories_product FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE,
+    CONSTRAINT fk_product_categories_category FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_product_categories_category_id ON product_categories (category_id);
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    id uuid PRIMARY KEY,
+    product_id uuid NOT NULL,
+    tenant_id text NOT NULL,
+    sku varchar(64) NOT NULL,
+    options jsonb NOT NULL DEFAULT '{}',
+    options_key text NOT NULL,
+    stock bigint NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    CONSTRAINT fk_products_variants FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants (product_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_options ON product_variants (product_id, options_key);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_tenant_sku ON product_variants (tenant_id, sku);
+
+CREATE TABLE IF NOT EXISTS variant_prices (
+    id uuid PRIMARY KEY,
+    variant_id uuid NOT NULL,
+    currency varchar(3) NOT NULL,
+    market varchar(2) NOT NULL DEFAULT '',
+    amount bigint NOT NULL,
+    CONSTRAINT fk_product_variants_prices FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_variant_prices_currency_market ON variant_prices (variant_id, currency, market);
+
+CREATE TABLE IF NOT EXISTS stock_levels (
+    product_id uuid,
+    warehouse varchar(64),
+    tenant_id text NOT NULL,
+    on_hand bigint NOT NULL DEFAULT 0,
+    reserved bigint NOT NULL DEFAULT 0,
+    updated_at timestamptz NOT NULL,
+    PRIMARY KEY (product_id, warehouse),
+    CONSTRAINT chk_stock_levels_on_hand CHECK (on_hand >= reserved),
+    CONSTRAINT chk_stock_levels_reserved CHECK (reserved >= 0),
+    CONSTRAINT fk_stock_levels_product FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_stock_levels_tenant_id ON stock_levels (tenant_id);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id uuid PRIMARY KEY,
+    tenant_id text NOT NULL,
+    product_id uuid NOT NULL,
+    warehouse varchar(64) NOT NULL,
+    quantity bigint NOT NULL,
+    status varchar(16) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    CONSTRAINT fk_reservations_product FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_reservations_tenant_id ON reservations (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_product_id ON reservations (product_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_status_expires_at ON reservations (status, expires_at);
+
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id uuid PRIMARY KEY,
+    tenant_id text NOT NULL,
+    resource_type varchar(32) NOT NULL,
+    resource_id uuid NOT NULL,
+    action varchar(16) NOT NULL,
+    actor text NOT NULL,
+    request_id text,
+    changes jsonb NOT NULL,
+    created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_resource ON audit_entries (tenant_id, resource_type, resource_id, created_at);
+
+CREATE TABLE IF NOT EXISTS stored_product_events (
+    "partition" integer,
+    "offset" bigint,
+    tenant_id text NOT NULL,
+    product_id uuid NOT NULL,
+    type varchar(32) NOT NULL,
+    product jsonb NOT NULL,
+    occurred_at timestamptz NOT NULL,
+    PRIMARY KEY ("partition", "offset")
+);
+CREATE INDEX IF NOT EXISTS idx_stored_product_events_product ON stored_product_events (tenant_id, product_id, occurred_at);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id uuid PRIMARY KEY,
+    tenant_id text NOT NULL,
+    url text NOT NULL,
+    event_types jsonb NOT NULL DEFAULT '[]',
+    secret text NOT NULL,
+    active boolean NOT NULL,
+    consecutive_failures bigint NOT NULL DEFAULT 0,
+    disabled_at timestamptz,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_tenant_id ON webhook_subscriptions (tenant_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id uuid PRIMARY KEY,
+    tenant_id text NOT NULL,
+    subscription_id uuid NOT NULL,
+    event_id text NOT NULL,
+    event_type varchar(32) NOT NULL,
+    payload jsonb NOT NULL,
+    redelivery_of uuid,
+    status varchar(16) NOT NULL,
+    attempts bigint NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_status_code bigint,
+    last_error text,
+    delivered_at timestamptz,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries (subscription_id, event_id) WHERE redelivery_of IS NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id uuid PRIMARY KEY,
+    name text NOT NULL,
+    prefix text NOT NULL,
+    key_hash text NOT NULL,
+    role text NOT NULL,
+    tenant_id text,
+    created_at timestamptz,
+    last_used_at timestamptz,
+    revoked_at timestamptz
+);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id text;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
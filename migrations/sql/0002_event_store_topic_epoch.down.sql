@@ -0,0 +1,9 @@
+-- Fails while events of more than one epoch share a position; delete those
+-- that should go first.
+
+ALTER TABLE stored_product_events DROP CONSTRAINT stored_product_events_pkey;
+ALTER TABLE stored_product_events ADD PRIMARY KEY ("partition", "offset");
+
+ALTER TABLE stored_product_events
+    DROP COLUMN topic,
+    DROP COLUMN epoch;
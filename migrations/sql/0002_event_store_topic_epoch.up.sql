@@ -0,0 +1,14 @@
+-- Key stored product events by topic and epoch as well as by partition and
+-- offset, so the events of a recreated product topic, whose offsets start
+-- over, are not taken for redeliveries of the events already stored. The
+-- events stored so far are those of the first epoch of product_events.
+
+ALTER TABLE stored_product_events
+    ADD COLUMN topic text NOT NULL DEFAULT 'product_events',
+    ADD COLUMN epoch integer NOT NULL DEFAULT 0;
+ALTER TABLE stored_product_events
+    ALTER COLUMN topic DROP DEFAULT,
+    ALTER COLUMN epoch DROP DEFAULT;
+
+ALTER TABLE stored_product_events DROP CONSTRAINT stored_product_events_pkey;
+ALTER TABLE stored_product_events ADD PRIMARY KEY (topic, epoch, "partition", "offset");
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_scheduled_price_changes_unpublished;
+ALTER TABLE scheduled_price_changes DROP COLUMN published_at;
@@ -0,0 +1,11 @@
+-- Record when the product_updated event of an applied scheduled price change
+-- was published, so the scheduler can publish it again after a failure
+-- instead of leaving the search index and consumers behind. Changes applied
+-- so far are taken as published.
+
+ALTER TABLE scheduled_price_changes ADD COLUMN published_at timestamptz;
+UPDATE scheduled_price_changes SET published_at = applied_at WHERE status = 'applied';
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_price_changes_unpublished
+    ON scheduled_price_changes (applied_at)
+    WHERE status = 'applied' AND published_at IS NULL;
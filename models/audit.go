@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+const AuditResourceProduct = "product"
+
+// FieldChange is the JSON value of a field before and after a change. Before
+// is null for creates and After is null for deletes.
+type FieldChange struct {
+	Before any `json:"before" swaggertype:"object"`
+	After  any `json:"after" swaggertype:"object"`
+}
+
+// FieldChanges maps the JSON name of each changed field to its change,
+// stored as a JSONB object.
+type FieldChanges map[string]FieldChange
+
+func (f FieldChanges) Value() (driver.Value, error) {
+	if f == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]FieldChange(f))
+	return string(b), err
+}
+
+func (f *FieldChanges) Scan(value any) error {
+	return scanJSON(value, f)
+}
+
+// AuditEntry records who created, updated or deleted a resource, when, in
+// which request and what changed. Entries outlive the resource they are
+// about.
+type AuditEntry struct {
+	ID           uuid.UUID    `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID     string       `gorm:"not null;index:idx_audit_entries_resource,priority:1" json:"tenant_id"`
+	ResourceType string       `gorm:"size:32;not null;index:idx_audit_entries_resource,priority:2" json:"resource_type"`
+	ResourceID   uuid.UUID    `gorm:"type:uuid;not null;index:idx_audit_entries_resource,priority:3" json:"resource_id"`
+	Action       string       `gorm:"size:16;not null" json:"action"`
+	Actor        string       `gorm:"not null" json:"actor"`
+	RequestID    string       `json:"request_id,omitempty"`
+	Changes      FieldChanges `gorm:"type:jsonb;not null" json:"changes"`
+	CreatedAt    time.Time    `gorm:"not null;index:idx_audit_entries_resource,priority:4" json:"created_at"`
+}
+
+func (e *AuditEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	e.ID, err = uuid.NewV7()
+	return
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Category is a node in a tenant's category tree. Path is the materialized
+// path of IDs from the root down to and including the category, e.g.
+// "/<root id>/<child id>/", so a subtree is every category whose path starts
+// with the path of its root. Position orders siblings.
+type Category struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID  string     `gorm:"not null;index" json:"tenant_id"`
+	ParentID  *uuid.UUID `gorm:"type:uuid;index" json:"parent_id"`
+	Name      string     `gorm:"not null" json:"name"`
+	Path      string     `gorm:"not null;index" json:"path"`
+	Position  int        `gorm:"not null" json:"position"`
+	CreatedAt time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"not null" json:"updated_at"`
+
+	Children []Category `gorm:"-" json:"children,omitempty"`
+}
+
+// BeforeCreate assigns a UUIDv7 unless the repository already picked one to
+// build the path from.
+func (c *Category) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID, err = uuid.NewV7()
+	}
+	return
+}
+
+// ProductCategory links a product to a category it is directly filed under.
+type ProductCategory struct {
+	ProductID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CategoryID uuid.UUID `gorm:"type:uuid;primaryKey;index"`
+
+	Product  Product  `gorm:"constraint:OnDelete:CASCADE"`
+	Category Category `gorm:"constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONColumnsStoreEmptyValuesAsEmptyJSON(t *testing.T) {
+	if v, err := Tags(nil).Value(); err != nil || v != "[]" {
+		t.Errorf("nil tags stored as %v, %v; want []", v, err)
+	}
+	if v, err := Attributes(nil).Value(); err != nil || v != "{}" {
+		t.Errorf("nil attributes stored as %v, %v; want {}", v, err)
+	}
+}
+
+func TestJSONColumnsRoundTrip(t *testing.T) {
+	tags := Tags{"sale", "new"}
+	stored, err := tags.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scannedTags Tags
+	if err := scannedTags.Scan([]byte(stored.(string))); err != nil || !reflect.DeepEqual(scannedTags, tags) {
+		t.Fatalf("tags came back as %v, %v; want %v", scannedTags, err, tags)
+	}
+
+	attributes := Attributes{"colour": "red", "weight": 1.5, "waterproof": true}
+	stored, err = attributes.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scannedAttributes Attributes
+	if err := scannedAttributes.Scan(stored); err != nil || !reflect.DeepEqual(scannedAttributes, attributes) {
+		t.Fatalf("attributes came back as %v, %v; want %v", scannedAttributes, err, attributes)
+	}
+
+	if err := scannedAttributes.Scan(42); err == nil {
+		t.Fatal("scanning a number succeeded, want an error")
+	}
+}
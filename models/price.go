@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProductPrice is a price in the minor units of an ISO 4217 currency, e.g.
+// 1999 with currency USD is $19.99 and 1999 with JPY is ¥1999. Market is an
+// optional ISO 3166-1 alpha-2 country code for market-specific prices; a
+// product has at most one price per currency and market.
+type ProductPrice struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	ProductID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_product_prices_currency_market" json:"-"`
+	Currency  string    `gorm:"size:3;not null;uniqueIndex:idx_product_prices_currency_market" json:"currency" binding:"required,iso4217"`
+	Market    string    `gorm:"size:2;not null;default:'';uniqueIndex:idx_product_prices_currency_market" json:"market,omitempty" binding:"omitempty,iso3166_1_alpha2"`
+	Amount    int64     `gorm:"not null" json:"amount" binding:"gte=0"`
+}
+
+func (p *ProductPrice) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID, err = uuid.NewV7()
+	return
+}
+
+// ExchangeRate converts amounts from BaseCurrency to QuoteCurrency: one unit
+// of the base currency is worth Rate units of the quote currency.
+type ExchangeRate struct {
+	BaseCurrency  string    `gorm:"size:3;primaryKey" json:"base_currency"`
+	QuoteCurrency string    `gorm:"size:3;primaryKey" json:"quote_currency"`
+	Rate          float64   `gorm:"not null" json:"rate"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
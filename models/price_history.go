@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PriceHistory is one period during which a product had a price in a
+// currency and market. The current price has no EffectiveTo.
+// ScheduledChangeID is set when the price was applied by the scheduler.
+type PriceHistory struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID          string     `gorm:"not null" json:"-"`
+	ProductID         uuid.UUID  `gorm:"type:uuid;not null;index:idx_price_history_product,priority:1" json:"product_id"`
+	Currency          string     `gorm:"size:3;not null;index:idx_price_history_product,priority:2" json:"currency"`
+	Market            string     `gorm:"size:2;not null;default:'';index:idx_price_history_product,priority:3" json:"market,omitempty"`
+	Amount            int64      `gorm:"not null" json:"amount"`
+	EffectiveFrom     time.Time  `gorm:"not null" json:"effective_from"`
+	EffectiveTo       *time.Time `json:"effective_to"`
+	Actor             string     `gorm:"not null" json:"actor"`
+	ScheduledChangeID *uuid.UUID `gorm:"type:uuid" json:"scheduled_change_id,omitempty"`
+
+	Product Product `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (h *PriceHistory) BeforeCreate(tx *gorm.DB) (err error) {
+	h.ID, err = uuid.NewV7()
+	return
+}
+
+const (
+	ScheduledChangePending   = "pending"
+	ScheduledChangeApplied   = "applied"
+	ScheduledChangeCancelled = "cancelled"
+)
+
+// ScheduledPrices is a list of prices stored as a JSONB array.
+type ScheduledPrices []ProductPrice
+
+func (p ScheduledPrices) Value() (driver.Value, error) {
+	if p == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]ProductPrice(p))
+	return string(b), err
+}
+
+func (p *ScheduledPrices) Scan(value any) error {
+	return scanJSON(value, p)
+}
+
+// ScheduledPriceChange sets Prices on a product once EffectiveAt is reached.
+// Each price replaces the product's price in the same currency and market
+// or is added; prices not listed are left as they are. PublishedAt is set
+// once the product_updated event for an applied change was published.
+type ScheduledPriceChange struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    string          `gorm:"not null" json:"-"`
+	ProductID   uuid.UUID       `gorm:"type:uuid;not null;index" json:"product_id"`
+	Prices      ScheduledPrices `gorm:"type:jsonb;not null" json:"prices"`
+	EffectiveAt time.Time       `gorm:"not null;index:idx_scheduled_price_changes_due,priority:2" json:"effective_at"`
+	Status      string          `gorm:"size:16;not null;index:idx_scheduled_price_changes_due,priority:1" json:"status"`
+	Actor       string          `gorm:"not null" json:"actor"`
+	CreatedAt   time.Time       `gorm:"not null" json:"created_at"`
+	AppliedAt   *time.Time      `json:"applied_at,omitempty"`
+	PublishedAt *time.Time      `json:"-"`
+
+	Product Product `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (c *ScheduledPriceChange) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID, err = uuid.NewV7()
+	return
+}
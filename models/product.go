@@ -1,18 +1,54 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+const (
+	ProductStatusDraft    = "draft"
+	ProductStatusActive   = "active"
+	ProductStatusArchived = "archived"
+)
+
+// Product is unique by SKU within a tenant. Status defaults to draft.
 type Product struct {
-	ID          uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
-	Name        string 		`json:"name"`
-	Description string 		`json:"description"`
-	Price       int    		`json:"price"`
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    string         `gorm:"not null;default:default;index;uniqueIndex:idx_products_tenant_sku,priority:1" json:"tenant_id"`
+	SKU         string         `gorm:"size:64;not null;uniqueIndex:idx_products_tenant_sku,priority:2" json:"sku" binding:"required,min=1,max=64"`
+	Name        string         `json:"name" binding:"required,min=1,max=200"`
+	Description string         `json:"description" binding:"max=5000"`
+	Status      string         `gorm:"size:16;not null;default:draft;index" json:"status" binding:"omitempty,oneof=draft active archived"`
+	Tags        Tags           `gorm:"type:jsonb;not null;default:'[]'" json:"tags" binding:"max=50,dive,min=1,max=50" swaggertype:"array,string"`
+	Attributes  Attributes     `gorm:"type:jsonb;not null;default:'{}'" json:"attributes" binding:"max=100" swaggertype:"object"`
+	Prices      []ProductPrice `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"prices" binding:"required,min=1,max=50,dive"`
+	CategoryIDs []uuid.UUID    `gorm:"-" json:"category_ids" binding:"max=20"`
+	Options     ProductOptions `gorm:"type:jsonb;not null;default:'[]'" json:"options" binding:"max=10,dive"`
+	CreatedAt   time.Time      `gorm:"not null" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"not null" json:"updated_at"`
+
+	// CategoryPaths are the materialized paths of the product's categories,
+	// denormalised so a search by category path prefix also finds products
+	// filed under descendants.
+	CategoryPaths []string `gorm:"-" json:"category_paths,omitempty" readonly:"true"`
+
+	// Variants are managed under /products/{id}/variants and ignored in
+	// product request bodies.
+	Variants []ProductVariant `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"variants" binding:"-" readonly:"true"`
+
+	// InStock reports whether any warehouse has stock available to sell.
+	InStock bool `gorm:"-" json:"in_stock" readonly:"true"`
+
+	// DisplayPrice is computed per request when a display currency is asked
+	// for and is never stored.
+	DisplayPrice *ProductPrice `gorm:"-" json:"display_price,omitempty"`
 }
 
+// BeforeCreate assigns a time-ordered UUIDv7 so the creation time can be
+// recovered from the ID alone.
 func (p *Product) BeforeCreate(tx *gorm.DB) (err error) {
-    p.ID = uuid.New()
-    return
-}
\ No newline at end of file
+	p.ID, err = uuid.NewV7()
+	return
+}
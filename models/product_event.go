@@ -0,0 +1,46 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductSnapshot is the state of a product carried by an event, stored as
+// a JSONB object.
+type ProductSnapshot Product
+
+func (s ProductSnapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(Product(s))
+	return string(b), err
+}
+
+func (s *ProductSnapshot) Scan(value any) error {
+	return scanJSON(value, (*Product)(s))
+}
+
+// StoredProductEvent is a product event as consumed from the product topic,
+// identified by its position in the topic. Epoch tells apart the positions
+// of a topic that was recreated. Events of one product share a partition,
+// so their offsets order them within an epoch. Product is the state after
+// the event, or the last state for a delete.
+type StoredProductEvent struct {
+	Topic      string          `gorm:"primaryKey" json:"topic"`
+	Epoch      int             `gorm:"primaryKey;autoIncrement:false" json:"epoch"`
+	Partition  int32           `gorm:"primaryKey;autoIncrement:false" json:"partition"`
+	Offset     int64           `gorm:"primaryKey;autoIncrement:false" json:"offset"`
+	TenantID   string          `gorm:"not null;index:idx_stored_product_events_product,priority:1" json:"tenant_id"`
+	ProductID  uuid.UUID       `gorm:"type:uuid;not null;index:idx_stored_product_events_product,priority:2" json:"product_id"`
+	Type       string          `gorm:"size:32;not null" json:"type"`
+	Product    ProductSnapshot `gorm:"type:jsonb;not null" json:"product"`
+	OccurredAt time.Time       `gorm:"not null;index:idx_stored_product_events_product,priority:3" json:"occurred_at"`
+}
+
+// EventID identifies the event by its position, including the epoch, so
+// that an event of a recreated topic never shares the ID of an older one.
+func (e StoredProductEvent) EventID() string {
+	return fmt.Sprintf("%s-%d-%d-%d", e.Topic, e.Epoch, e.Partition, e.Offset)
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StockLevel is the stock of a product in one warehouse. Reserved units are
+// held by pending reservations and are not available to sell.
+type StockLevel struct {
+	ProductID uuid.UUID `gorm:"type:uuid;primaryKey" json:"product_id"`
+	Warehouse string    `gorm:"size:64;primaryKey" json:"warehouse"`
+	TenantID  string    `gorm:"not null;index" json:"-"`
+	OnHand    int64     `gorm:"not null;default:0;check:chk_stock_levels_on_hand,on_hand >= reserved" json:"on_hand"`
+	Reserved  int64     `gorm:"not null;default:0;check:chk_stock_levels_reserved,reserved >= 0" json:"reserved"`
+	Available int64     `gorm:"-" json:"available"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+
+	Product Product `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (s *StockLevel) AfterFind(tx *gorm.DB) error {
+	s.Available = s.OnHand - s.Reserved
+	return nil
+}
+
+const (
+	ReservationPending   = "pending"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// Reservation holds Quantity units of a product in a warehouse until it is
+// committed, which takes them out of stock, or released or expired, which
+// makes them available again.
+type Reservation struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID  string    `gorm:"not null;index" json:"-"`
+	ProductID uuid.UUID `gorm:"type:uuid;not null;index" json:"product_id"`
+	Warehouse string    `gorm:"size:64;not null" json:"warehouse"`
+	Quantity  int64     `gorm:"not null" json:"quantity"`
+	Status    string    `gorm:"size:16;not null;index:idx_reservations_status_expires_at,priority:1" json:"status"`
+	ExpiresAt time.Time `gorm:"not null;index:idx_reservations_status_expires_at,priority:2" json:"expires_at"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+
+	Product Product `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (r *Reservation) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID, err = uuid.NewV7()
+	return
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProductOption defines one dimension a product varies in, such as size
+// with the values S, M and L.
+type ProductOption struct {
+	Name   string   `json:"name" binding:"required,min=1,max=50"`
+	Values []string `json:"values" binding:"required,min=1,max=100,dive,min=1,max=100"`
+}
+
+// ProductOptions is stored as a JSONB array on the product.
+type ProductOptions []ProductOption
+
+func (o ProductOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]ProductOption(o))
+	return string(b), err
+}
+
+func (o *ProductOptions) Scan(value any) error {
+	return scanJSON(value, o)
+}
+
+// VariantOptions maps each option name of the product to the value the
+// variant has, e.g. {"size": "M", "colour": "red"}.
+type VariantOptions map[string]string
+
+func (o VariantOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]string(o))
+	return string(b), err
+}
+
+func (o *VariantOptions) Scan(value any) error {
+	return scanJSON(value, o)
+}
+
+// Key is a canonical form of the options, used to keep variants of a
+// product unique.
+func (o VariantOptions) Key() string {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(o[name])
+		b.WriteByte(';')
+	}
+	return b.String()
+}
+
+// ProductVariant is a purchasable version of a product with its own SKU,
+// unique within the tenant like product SKUs. Prices, when given, override
+// the product prices. Stock is a plain count on hand; warehouse stock and
+// reservations are tracked per product.
+type ProductVariant struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	ProductID  uuid.UUID      `gorm:"type:uuid;not null;index;uniqueIndex:idx_product_variants_options,priority:1" json:"product_id"`
+	TenantID   string         `gorm:"not null;uniqueIndex:idx_product_variants_tenant_sku,priority:1" json:"-"`
+	SKU        string         `gorm:"size:64;not null;uniqueIndex:idx_product_variants_tenant_sku,priority:2" json:"sku" binding:"required,min=1,max=64"`
+	Options    VariantOptions `gorm:"type:jsonb;not null;default:'{}'" json:"options" swaggertype:"object,string"`
+	OptionsKey string         `gorm:"not null;uniqueIndex:idx_product_variants_options,priority:2" json:"-"`
+	Prices     []VariantPrice `gorm:"foreignKey:VariantID;constraint:OnDelete:CASCADE" json:"prices" binding:"max=50,dive"`
+	Stock      int64          `gorm:"not null;default:0" json:"stock" binding:"gte=0"`
+	CreatedAt  time.Time      `gorm:"not null" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"not null" json:"updated_at"`
+}
+
+func (v *ProductVariant) BeforeCreate(tx *gorm.DB) (err error) {
+	v.ID, err = uuid.NewV7()
+	return
+}
+
+func (v *ProductVariant) BeforeSave(tx *gorm.DB) error {
+	v.OptionsKey = v.Options.Key()
+	return nil
+}
+
+// VariantPrice is a ProductPrice for a single variant.
+type VariantPrice struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	VariantID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_variant_prices_currency_market" json:"-"`
+	Currency  string    `gorm:"size:3;not null;uniqueIndex:idx_variant_prices_currency_market" json:"currency" binding:"required,iso4217"`
+	Market    string    `gorm:"size:2;not null;default:'';uniqueIndex:idx_variant_prices_currency_market" json:"market,omitempty" binding:"omitempty,iso3166_1_alpha2"`
+	Amount    int64     `gorm:"not null" json:"amount" binding:"gte=0"`
+}
+
+func (p *VariantPrice) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID, err = uuid.NewV7()
+	return
+}
@@ -0,0 +1,21 @@
+package models
+
+import "testing"
+
+func TestVariantOptionsKeyIgnoresOrder(t *testing.T) {
+	a := VariantOptions{"size": "M", "colour": "red"}
+	b := VariantOptions{"colour": "red", "size": "M"}
+	if a.Key() != b.Key() {
+		t.Fatalf("the same options got keys %q and %q", a.Key(), b.Key())
+	}
+
+	for _, other := range []VariantOptions{
+		{"size": "L", "colour": "red"},
+		{"size": "M"},
+		{"size": "M", "colour": "red", "fit": "slim"},
+	} {
+		if other.Key() == a.Key() {
+			t.Errorf("options %v share the key %q of %v", other, a.Key(), a)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription sends the tenant's product events of EventTypes, or
+// of every type when empty, to URL. Payloads are signed with Secret, which
+// is only returned when the subscription is created. A subscription that
+// keeps failing is disabled with DisabledAt set until it is re-enabled.
+type WebhookSubscription struct {
+	ID                  uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID            string     `gorm:"not null;index" json:"-"`
+	URL                 string     `gorm:"not null" json:"url"`
+	EventTypes          Tags       `gorm:"type:jsonb;not null;default:'[]'" json:"event_types" swaggertype:"array,string"`
+	Secret              string     `gorm:"not null" json:"-"`
+	Active              bool       `gorm:"not null" json:"active"`
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt           time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+func (s *WebhookSubscription) BeforeCreate(tx *gorm.DB) (err error) {
+	s.ID, err = uuid.NewV7()
+	return
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event to be sent to one subscription, with the
+// outcome of the latest attempt. EventID identifies the event to receivers
+// and stays the same for a redelivery, which is a new delivery pointing at
+// the one it repeats.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       string          `gorm:"not null" json:"-"`
+	SubscriptionID uuid.UUID       `gorm:"type:uuid;not null;index:idx_webhook_deliveries_subscription,priority:1;uniqueIndex:idx_webhook_deliveries_event,priority:1,where:redelivery_of IS NULL" json:"subscription_id"`
+	EventID        string          `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event,priority:2,where:redelivery_of IS NULL" json:"event_id"`
+	EventType      string          `gorm:"size:32;not null" json:"event_type"`
+	Payload        json.RawMessage `gorm:"type:jsonb;not null" json:"payload" swaggertype:"object"`
+	RedeliveryOf   *uuid.UUID      `gorm:"type:uuid" json:"redelivery_of,omitempty"`
+	Status         string          `gorm:"size:16;not null;index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int             `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time       `gorm:"not null;index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `gorm:"not null;index:idx_webhook_deliveries_subscription,priority:2" json:"created_at"`
+	UpdatedAt      time.Time       `gorm:"not null" json:"updated_at"`
+
+	Subscription WebhookSubscription `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) (err error) {
+	d.ID, err = uuid.NewV7()
+	return
+}
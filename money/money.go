@@ -0,0 +1,35 @@
+package money
+
+import "math"
+
+// Currencies whose minor unit is not a hundredth of the major unit. Every
+// other ISO 4217 currency uses two decimal places.
+var minorUnitExponents = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+// Exponent returns the number of decimal places of the currency's minor unit.
+func Exponent(currency string) int {
+	if exp, ok := minorUnitExponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Convert converts an amount in minor units of from into minor units of to,
+// where rate is the value of one major unit of from in major units of to.
+// The result is rounded half away from zero.
+func Convert(amount int64, from, to string, rate float64) int64 {
+	// Scale by a whole power of ten rather than going through major units,
+	// whose fractions are not exact in binary and would tip halves down.
+	converted := float64(amount) * rate
+	if shift := Exponent(to) - Exponent(from); shift >= 0 {
+		converted *= math.Pow10(shift)
+	} else {
+		converted /= math.Pow10(-shift)
+	}
+	return int64(math.Round(converted))
+}
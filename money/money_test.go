@@ -0,0 +1,24 @@
+package money
+
+import "testing"
+
+func TestConvertScalesBetweenMinorUnits(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		from, to string
+		rate     float64
+		want     int64
+	}{
+		{1999, "USD", "EUR", 0.9, 1799},   // $19.99 is €17.991
+		{1999, "USD", "JPY", 150, 2999},   // $19.99 is ¥2998.5, rounded up
+		{1000, "JPY", "USD", 0.0067, 670}, // ¥1000 is $6.70
+		{1500, "KWD", "USD", 3.25, 488},   // 1.500 KWD is $4.875, rounded up
+		{-1999, "USD", "JPY", 150, -2999}, // half away from zero
+		{0, "USD", "EUR", 0.9, 0},
+	}
+	for _, tt := range tests {
+		if got := Convert(tt.amount, tt.from, tt.to, tt.rate); got != tt.want {
+			t.Errorf("Convert(%d %s to %s at %v) = %d, want %d", tt.amount, tt.from, tt.to, tt.rate, got, tt.want)
+		}
+	}
+}
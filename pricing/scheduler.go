@@ -0,0 +1,115 @@
+package pricing
+
+import (
+	"context"
+	"go-product-api/cache"
+	"go-product-api/config"
+	"go-product-api/events"
+	"go-product-api/logging"
+	"go-product-api/models"
+	"go-product-api/repositories"
+	"go-product-api/tenant"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// applyBatch bounds how many scheduled changes one transaction applies and
+// one round publishes.
+const applyBatch = 100
+
+// StartPriceScheduler applies due scheduled price changes every
+// PriceSchedulerInterval until ctx is cancelled, and publishes a
+// product_updated event for each product it changed. An event that fails to
+// publish is retried on the next run until it goes out. The returned channel
+// is closed once the loop has exited. Running it in several instances is
+// safe: each change is locked by exactly one run, though an event may be
+// published twice when runs retry the same change at once.
+func StartPriceScheduler(ctx context.Context) <-chan struct{} {
+	priceRepo := repositories.NewPriceRepository()
+	pgRepo := repositories.NewPostgresRepository()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(config.PriceSchedulerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("price scheduler stopped")
+				return
+			case <-ticker.C:
+				apply(ctx, priceRepo)
+				publish(ctx, priceRepo, pgRepo)
+			}
+		}
+	}()
+	slog.Info("price scheduler started", "interval", config.PriceSchedulerInterval.String())
+	return done
+}
+
+func apply(ctx context.Context, priceRepo *repositories.PriceRepository) {
+	for ctx.Err() == nil {
+		applied, err := priceRepo.ApplyDue(ctx, applyBatch)
+		if err != nil {
+			slog.Error("Failed to apply scheduled price changes", "error.message", err)
+			return
+		}
+
+		for _, change := range applied {
+			cache.Invalidate(tenant.WithTenant(ctx, change.TenantID), change.ProductID)
+		}
+		if len(applied) > 0 {
+			slog.Info("Applied scheduled price changes", "count", len(applied))
+		}
+		if len(applied) < applyBatch {
+			return
+		}
+	}
+}
+
+// publish publishes product_updated for the products of applied changes
+// whose event has not gone out yet, once per product, and marks the changes
+// published. Changes whose event fails are left for the next run.
+func publish(ctx context.Context, priceRepo *repositories.PriceRepository, pgRepo *repositories.PostgresRepository) {
+	for ctx.Err() == nil {
+		changes, err := priceRepo.FindUnpublished(ctx, applyBatch)
+		if err != nil {
+			slog.Error("Failed to find unpublished scheduled price changes", "error.message", err)
+			return
+		}
+
+		byProduct := map[uuid.UUID][]models.ScheduledPriceChange{}
+		for _, change := range changes {
+			byProduct[change.ProductID] = append(byProduct[change.ProductID], change)
+		}
+		failed := false
+		for productID, changes := range byProduct {
+			ctx := tenant.WithTenant(ctx, changes[0].TenantID)
+			logger := logging.FromContext(ctx).With("product.id", productID)
+			product, err := pgRepo.FindByID(ctx, productID)
+			if err == nil {
+				err = events.PublishProductEvent(ctx, events.ProductUpdated, product)
+			}
+			if err != nil {
+				logger.Error("Failed to publish scheduled price change, will retry", "error.message", err)
+				failed = true
+				continue
+			}
+
+			ids := make([]uuid.UUID, len(changes))
+			for i, change := range changes {
+				ids[i] = change.ID
+			}
+			if err := priceRepo.MarkPublished(ctx, ids); err != nil {
+				logger.Error("Failed to mark scheduled price change published", "error.message", err)
+				failed = true
+			}
+		}
+		if failed || len(changes) < applyBatch {
+			return
+		}
+	}
+}
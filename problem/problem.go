@@ -0,0 +1,75 @@
+package problem
+
+import (
+	"go-product-api/logging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Code is a stable,
+// machine-readable identifier clients can switch on; Type is derived from it.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Stable error codes.
+const (
+	CodeValidationFailed       = "validation_failed"
+	CodeMalformedBody          = "malformed_body"
+	CodeInvalidID              = "invalid_id"
+	CodeNotFound               = "not_found"
+	CodeConflict               = "conflict"
+	CodeCategoryNotEmpty       = "category_not_empty"
+	CodeInvalidMove            = "invalid_move"
+	CodeInsufficientStock      = "insufficient_stock"
+	CodeOptionsInUse           = "options_in_use"
+	CodeReservationClosed      = "reservation_closed"
+	CodeReservationExpired     = "reservation_expired"
+	CodeScheduledChangeClosed  = "scheduled_change_closed"
+	CodeAuthenticationRequired = "authentication_required"
+	CodeInvalidCredentials     = "invalid_credentials"
+	CodeForbidden              = "forbidden"
+	CodeTenantForbidden        = "tenant_forbidden"
+	CodeInvalidTenant          = "invalid_tenant"
+	CodeRateLimited            = "rate_limited"
+	CodeBackendTimeout         = "backend_timeout"
+	CodeServiceUnavailable     = "service_unavailable"
+	CodeEventPublishFailed     = "event_publish_failed"
+	CodeQueryTooComplex        = "query_too_complex"
+	CodeInternal               = "internal_error"
+)
+
+func New(status int, code, detail string) Problem {
+	return Problem{
+		Type:   "/problems/" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Abort writes p as application/problem+json and stops the handler chain.
+func Abort(c *gin.Context, p Problem) {
+	p.Instance = c.Request.URL.Path
+	p.RequestID = logging.RequestID(c.Request.Context())
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
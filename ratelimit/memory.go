@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore keeps buckets in process. Limits apply per replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{buckets: make(map[string]*bucket)}
+	go s.sweep(time.Minute)
+	return s
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	var res Result
+	b.tokens, res = refill(b.tokens, now.Sub(b.last), limit)
+	b.last = now
+	return res, nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again, so
+// one-off clients do not accumulate forever.
+func (s *MemoryStore) sweep(interval time.Duration) {
+	for range time.Tick(interval) {
+		s.mu.Lock()
+		for key, b := range s.buckets {
+			if time.Since(b.last) > 10*interval {
+				delete(s.buckets, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
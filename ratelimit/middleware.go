@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"go-product-api/auth"
+	"go-product-api/logging"
+	"go-product-api/problem"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware limits requests in the given class per client. Authenticated
+// clients are keyed by their credential, everyone else by IP, so it should
+// run after auth.Authenticate. If the store fails the request is let through.
+func Middleware(class Class) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if Charge(c, class, 1) {
+			c.Next()
+		}
+	}
+}
+
+// PerIP limits all requests per client IP. It runs ahead of auth.Authenticate
+// so that requests with bad or missing credentials, which never reach the
+// per-client limits, still use up a budget.
+func PerIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if charge(c, ClassIP, "ip:"+c.ClientIP(), 1) {
+			c.Next()
+		}
+	}
+}
+
+// Charge takes n tokens from the client's bucket for the class, for
+// requests that count as more than one, such as a GraphQL document with
+// several mutations. It aborts with 429 and returns false once the bucket
+// runs dry; tokens already taken stay taken.
+func Charge(c *gin.Context, class Class, n int) bool {
+	return charge(c, class, Client(c.Request.Context(), c.ClientIP()), n)
+}
+
+func charge(c *gin.Context, class Class, client string, n int) bool {
+	res, counted := Take(c.Request.Context(), class, client, n)
+	if !counted {
+		return true
+	}
+
+	limit := limits[class]
+	c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	c.Header("RateLimit-Policy", strconv.Itoa(limit.Burst)+";w="+strconv.Itoa(ceilSeconds(time.Duration(float64(limit.Burst)/limit.Rate*float64(time.Second)))))
+
+	if !res.Allowed {
+		c.Header("Retry-After", strconv.Itoa(RetryAfterSeconds(res)))
+		problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, "Rate limit exceeded"))
+		return false
+	}
+	return true
+}
+
+// Take takes n tokens from client's bucket for the class, stopping at the
+// first one refused, and returns the result of the last take. counted is
+// false when nothing was taken because n is 0, rate limiting is disabled or
+// the store failed; the call is then allowed.
+func Take(ctx context.Context, class Class, client string, n int) (res Result, counted bool) {
+	if store == nil || n <= 0 {
+		return Result{Allowed: true}, false
+	}
+
+	limit := limits[class]
+	key := string(class) + ":" + client
+	for range n {
+		var err error
+		if res, err = store.Take(ctx, key, limit); err != nil {
+			logging.FromContext(ctx).Warn("Rate limit store failed", "error.message", err)
+			return Result{Allowed: true}, false
+		}
+		if !res.Allowed {
+			break
+		}
+	}
+	return res, true
+}
+
+// Client returns the key a caller's buckets are kept under: its credential
+// when it is authenticated, otherwise ip.
+func Client(ctx context.Context, ip string) string {
+	if p, ok := auth.PrincipalFromContext(ctx); ok && p.Method != "none" {
+		return p.Subject
+	}
+	return "ip:" + ip
+}
+
+// RetryAfterSeconds is how long a refused caller should wait, in whole
+// seconds.
+func RetryAfterSeconds(res Result) int {
+	return ceilSeconds(res.RetryAfter)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,31 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPerIPLimitsUnauthenticatedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previousStore, previousLimits := store, limits
+	store, limits = NewMemoryStore(), map[Class]Limit{ClassIP: {Rate: 0.001, Burst: 2}}
+	t.Cleanup(func() { store, limits = previousStore, previousLimits })
+
+	router := gin.New()
+	// Stands in for auth.Authenticate rejecting a bad credential.
+	router.GET("/", PerIP(), func(c *gin.Context) { c.AbortWithStatus(http.StatusUnauthorized) })
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, status := range want {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Fatalf("request %d: got status %d, want %d", i+1, w.Code, status)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"go-product-api/config"
+	"go-product-api/logging"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: Rate tokens are added per second up to a
+// maximum of Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking one token from a bucket.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // until the next token is available; zero when allowed
+	Reset      time.Duration // until the bucket is full again
+}
+
+// Store keeps bucket state. Implementations must make Take atomic per key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Class groups endpoints that share a limit.
+type Class string
+
+const (
+	ClassRead   Class = "read"
+	ClassSearch Class = "search"
+	ClassWrite  Class = "write"
+	ClassIP     Class = "ip"
+)
+
+var (
+	store  Store
+	limits map[Class]Limit
+)
+
+func Init() {
+	limits = map[Class]Limit{
+		ClassRead:   {Rate: config.RateLimitReadRPS, Burst: config.RateLimitReadBurst},
+		ClassSearch: {Rate: config.RateLimitSearchRPS, Burst: config.RateLimitSearchBurst},
+		ClassWrite:  {Rate: config.RateLimitWriteRPS, Burst: config.RateLimitWriteBurst},
+		ClassIP:     {Rate: config.RateLimitIPRPS, Burst: config.RateLimitIPBurst},
+	}
+
+	if !config.RateLimitEnabled {
+		slog.Info("Rate limiting disabled")
+		return
+	}
+
+	// A bucket that never refills or never holds a token would divide by
+	// zero or reject everything.
+	for class, limit := range limits {
+		if limit.Rate <= 0 || limit.Burst <= 0 {
+			logging.Fatal("Invalid rate limit", "rate_limit.class", string(class), "rate_limit.rps", limit.Rate, "rate_limit.burst", limit.Burst)
+		}
+	}
+
+	switch config.RateLimitStore {
+	case "memory":
+		store = NewMemoryStore()
+	case "redis":
+		config.ConnectRedis()
+		store = NewRedisStore(config.Redis)
+	default:
+		logging.Fatal("Unknown rate limit store", "rate_limit.store", config.RateLimitStore)
+	}
+	slog.Info("Rate limiting enabled", "rate_limit.store", config.RateLimitStore)
+}
+
+// refill computes the bucket level after elapsed time and tries to take one
+// token. It is shared by the memory store and mirrored by the Redis script.
+func refill(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	tokens = math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+
+	res := Result{}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate)
+	return tokens, res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"go-product-api/config"
+	"go-product-api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct{}
+
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{}
+}
+
+// FindAll returns the keys bound to tenantID, or every key when tenantID is
+// empty, which only callers not bound to a tenant may ask for.
+func (r *APIKeyRepository) FindAll(ctx context.Context, tenantID string) ([]models.APIKey, error) {
+	ctx, span := startPostgresSpan(ctx, "api_keys", "FindAll")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db := config.DB.WithContext(ctx)
+	if tenantID != "" {
+		db = db.Where("tenant_id = ?", tenantID)
+	}
+	var keys []models.APIKey
+	result := db.Order("created_at").Find(&keys)
+	return keys, endSpan(span, result.Error)
+}
+
+// FindActiveByPrefix returns the non-revoked key with the given prefix.
+func (r *APIKeyRepository) FindActiveByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	ctx, span := startPostgresSpan(ctx, "api_keys", "FindActiveByPrefix")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	var key models.APIKey
+	result := config.DB.WithContext(ctx).First(&key, "prefix = ? AND revoked_at IS NULL", prefix)
+	return key, endSpan(span, result.Error)
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	ctx, span := startPostgresSpan(ctx, "api_keys", "Create")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	return endSpan(span, config.DB.WithContext(ctx).Create(key).Error)
+}
+
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startPostgresSpan(ctx, "api_keys", "TouchLastUsed", attribute.String("api_key.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	result := config.DB.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", time.Now())
+	return endSpan(span, result.Error)
+}
+
+// Revoke marks the key as revoked. It returns gorm.ErrRecordNotFound when
+// there is no active key with that ID, or none bound to tenantID when it is
+// not empty.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uuid.UUID, tenantID string) error {
+	ctx, span := startPostgresSpan(ctx, "api_keys", "Revoke", attribute.String("api_key.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db := config.DB.WithContext(ctx).Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", id)
+	if tenantID != "" {
+		db = db.Where("tenant_id = ?", tenantID)
+	}
+	result := db.Update("revoked_at", time.Now())
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = gorm.ErrRecordNotFound
+	}
+	return endSpan(span, result.Error)
+}
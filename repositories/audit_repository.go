@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"go-product-api/audit"
+	"go-product-api/config"
+	"go-product-api/logging"
+	"go-product-api/models"
+	"sort"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// AuditRepository reads the audit trail. Entries are written by the
+// repository making the change, in the same transaction.
+type AuditRepository struct{}
+
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+// FindByResource returns the audit trail of a resource, newest first. It
+// is empty, not an error, for a resource that never existed.
+func (r *AuditRepository) FindByResource(ctx context.Context, resourceType string, id uuid.UUID) ([]models.AuditEntry, error) {
+	ctx, span := startPostgresSpan(ctx, "audit_entries", "FindByResource", attribute.String("audit.resource_id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	var entries []models.AuditEntry
+	err = db.Where("resource_type = ? AND resource_id = ?", resourceType, id).
+		Order("created_at DESC").
+		Find(&entries).Error
+	return entries, endSpan(span, err)
+}
+
+// productAuditIgnored are product fields that are derived, managed through
+// other endpoints or change on every write, and so are left out of diffs.
+var productAuditIgnored = []string{
+	"id", "tenant_id", "created_at", "updated_at", "category_paths", "variants", "in_stock", "display_price",
+}
+
+// recordProductAudit writes an audit entry for a product going from before
+// to after, either of which is nil for a create or a delete. An update that
+// changed nothing is not recorded and returns nil.
+func recordProductAudit(ctx context.Context, tx *gorm.DB, action string, before, after *models.Product) (*models.AuditEntry, error) {
+	changes, err := audit.Diff(auditState(before), auditState(after), productAuditIgnored...)
+	if err != nil {
+		return nil, err
+	}
+	if action == models.AuditActionUpdate && len(changes) == 0 {
+		return nil, nil
+	}
+
+	product := after
+	if product == nil {
+		product = before
+	}
+	entry := models.AuditEntry{
+		TenantID:     product.TenantID,
+		ResourceType: models.AuditResourceProduct,
+		ResourceID:   product.ID,
+		Action:       action,
+		Actor:        audit.Actor(ctx),
+		RequestID:    logging.RequestID(ctx),
+		Changes:      changes,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// auditState returns a copy of the product with its prices in a stable
+// order, so reordered prices do not show up as a change.
+func auditState(product *models.Product) *models.Product {
+	if product == nil {
+		return nil
+	}
+	state := *product
+	state.Prices = append([]models.ProductPrice(nil), product.Prices...)
+	sort.Slice(state.Prices, func(i, j int) bool {
+		if state.Prices[i].Currency != state.Prices[j].Currency {
+			return state.Prices[i].Currency < state.Prices[j].Currency
+		}
+		return state.Prices[i].Market < state.Prices[j].Market
+	})
+	return &state
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"go-product-api/audit"
+	"go-product-api/config"
+	"go-product-api/internal/testutil"
+	"go-product-api/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestRecordProductAuditSkipsUpdatesThatChangeNothing(t *testing.T) {
+	testutil.MockDatabase(t) // no statements are expected
+	before := &models.Product{ID: uuid.New(), TenantID: "tenant-a", Prices: []models.ProductPrice{
+		{Currency: "EUR", Amount: 900}, {Currency: "USD", Amount: 1000},
+	}}
+	// Reordered prices and a new write time are not changes.
+	after := *before
+	after.Prices = []models.ProductPrice{before.Prices[1], before.Prices[0]}
+	after.UpdatedAt = before.UpdatedAt.Add(1)
+
+	entry, err := recordProductAudit(context.Background(), config.DB, models.AuditActionUpdate, before, &after)
+	if err != nil || entry != nil {
+		t.Fatalf("got entry %+v, error %v; want nothing recorded", entry, err)
+	}
+}
+
+func TestRecordProductAuditRecordsTheActorAndChangedFields(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "audit_entries"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	before := &models.Product{ID: uuid.New(), TenantID: "tenant-a", SKU: "SKU-1", Name: "Shoe"}
+	after := *before
+	after.Name = "Boot"
+
+	ctx := audit.WithActor(context.Background(), "key-a")
+	entry, err := recordProductAudit(ctx, config.DB, models.AuditActionUpdate, before, &after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Actor != "key-a" || entry.ResourceID != before.ID || entry.TenantID != "tenant-a" {
+		t.Fatalf("got entry by %q for %s of %q, want key-a, the product and tenant-a", entry.Actor, entry.ResourceID, entry.TenantID)
+	}
+	if len(entry.Changes) != 1 || entry.Changes["name"] != (models.FieldChange{Before: "Shoe", After: "Boot"}) {
+		t.Fatalf("got changes %v, want only name", entry.Changes)
+	}
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-product-api/config"
+	"go-product-api/tenant"
+	"log/slog"
+	"net/http"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+// ErrElasticsearchUnavailable is returned by reads that Elasticsearch cannot
+// serve right now: while the read circuit breaker is open, without calling
+// it, and when a request gets no response, times out or gets a 5xx or 429
+// response.
+var ErrElasticsearchUnavailable = errors.New("elasticsearch unavailable")
+
+var esBreaker = gobreaker.NewCircuitBreaker[any](gobreaker.Settings{
+	Name:        "elasticsearch",
+	MaxRequests: uint32(config.ESBreakerHalfOpenRequests),
+	Interval:    config.ESBreakerInterval,
+	Timeout:     config.ESBreakerOpenTimeout,
+	ReadyToTrip: func(counts gobreaker.Counts) bool {
+		return counts.ConsecutiveFailures >= uint32(config.ESBreakerMaxFailures)
+	},
+	IsSuccessful: func(err error) bool {
+		// A missing document, a rejected query or a client hanging up says
+		// nothing about the health of the cluster.
+		var resErr *responseError
+		return err == nil || errors.Is(err, ErrProductNotFound) || errors.Is(err, context.Canceled) ||
+			errors.Is(err, tenant.ErrMissingTenant) || (errors.As(err, &resErr) && !unavailable(err))
+	},
+	OnStateChange: func(name string, from, to gobreaker.State) {
+		slog.Warn("Circuit breaker state changed", "breaker", name, "from", from.String(), "to", to.String())
+	},
+})
+
+func withBreaker[T any](fn func() (T, error)) (T, error) {
+	res, err := esBreaker.Execute(func() (any, error) {
+		return fn()
+	})
+	if err != nil {
+		var zero T
+		if unavailable(err) {
+			return zero, fmt.Errorf("%w: %s", ErrElasticsearchUnavailable, err)
+		}
+		return zero, err
+	}
+	return res.(T), nil
+}
+
+// unavailable reports whether a read failed because of the state of the
+// cluster rather than the read itself, so another store should serve it.
+func unavailable(err error) bool {
+	var (
+		resErr       *responseError
+		transportErr *transportError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+		return true
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &transportErr):
+		return true
+	case errors.As(err, &resErr):
+		return resErr.statusCode >= http.StatusInternalServerError || resErr.statusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
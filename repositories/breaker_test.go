@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go-product-api/tenant"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+func TestUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"open breaker", gobreaker.ErrOpenState, true},
+		{"half-open breaker", gobreaker.ErrTooManyRequests, true},
+		{"no response", &transportError{errors.New("connection refused")}, true},
+		{"timeout", &transportError{context.DeadlineExceeded}, true},
+		{"server error", &responseError{op: "search error", statusCode: http.StatusServiceUnavailable}, true},
+		{"throttled", &responseError{op: "search error", statusCode: http.StatusTooManyRequests}, true},
+		{"bad query", &responseError{op: "search error", statusCode: http.StatusBadRequest}, false},
+		{"client gone", &transportError{context.Canceled}, false},
+		{"not found", ErrProductNotFound, false},
+		{"no tenant", fmt.Errorf("search: %w", tenant.ErrMissingTenant), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unavailable(tt.err); got != tt.want {
+				t.Fatalf("unavailable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
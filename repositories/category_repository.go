@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-product-api/config"
+	"go-product-api/models"
+	"go-product-api/tenant"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+	ErrCategoryCycle          = errors.New("category cannot be moved under itself or one of its descendants")
+	ErrCategoryNotEmpty       = errors.New("category has subcategories")
+	ErrUnknownCategory        = errors.New("unknown category")
+)
+
+// CategoryRepository manages the category tree of the tenant on the
+// context. Every write that changes sibling order or paths runs in one
+// transaction holding the tenant's tree lock, so such writes are serialized
+// per tenant, including those among root categories, which have no parent
+// row to lock.
+type CategoryRepository struct{}
+
+func NewCategoryRepository() *CategoryRepository {
+	return &CategoryRepository{}
+}
+
+// FindAll returns every category of the tenant, parents before children and
+// siblings in position order.
+func (r *CategoryRepository) FindAll(ctx context.Context) ([]models.Category, error) {
+	ctx, span := startPostgresSpan(ctx, "categories", "FindAll")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	var categories []models.Category
+	result := db.Order("length(path), position").Find(&categories)
+	return categories, endSpan(span, result.Error)
+}
+
+func (r *CategoryRepository) FindByID(ctx context.Context, id uuid.UUID) (models.Category, error) {
+	ctx, span := startPostgresSpan(ctx, "categories", "FindByID", attribute.String("category.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return models.Category{}, endSpan(span, err)
+	}
+
+	var category models.Category
+	result := db.First(&category, "id = ?", id)
+	return category, endSpan(span, result.Error)
+}
+
+// FindByIDs returns the categories with the given IDs in path order. IDs
+// that do not exist are left out.
+func (r *CategoryRepository) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]models.Category, error) {
+	ctx, span := startPostgresSpan(ctx, "categories", "FindByIDs", attribute.Int("category.count", len(ids)))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	var categories []models.Category
+	result := db.Where("id IN ?", ids).Order("path").Find(&categories)
+	return categories, endSpan(span, result.Error)
+}
+
+// Create inserts the category under its parent at position, or after the
+// last sibling when position is nil.
+func (r *CategoryRepository) Create(ctx context.Context, category *models.Category, position *int) error {
+	ctx, span := startPostgresSpan(ctx, "categories", "Create")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+	category.TenantID = tenantID
+	if category.ID, err = uuid.NewV7(); err != nil {
+		return endSpan(span, err)
+	}
+
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockTree(tx, tenantID); err != nil {
+			return err
+		}
+		parentPath, err := lockParent(tx, tenantID, category.ParentID)
+		if err != nil {
+			return err
+		}
+		category.Path = parentPath + category.ID.String() + "/"
+		if category.Position, err = insertPosition(tx, tenantID, category.ParentID, uuid.Nil, position); err != nil {
+			return err
+		}
+		return tx.Create(category).Error
+	})
+	return endSpan(span, err)
+}
+
+func (r *CategoryRepository) Rename(ctx context.Context, id uuid.UUID, name string) (models.Category, error) {
+	ctx, span := startPostgresSpan(ctx, "categories", "Rename", attribute.String("category.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return models.Category{}, endSpan(span, err)
+	}
+
+	var category models.Category
+	result := db.Model(&category).Clauses(clause.Returning{}).Where("id = ?", id).Update("name", name)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = gorm.ErrRecordNotFound
+	}
+	return category, endSpan(span, result.Error)
+}
+
+// Move re-parents the category, or reorders it among its siblings when the
+// parent is unchanged, and rewrites the paths of its whole subtree. A nil
+// parentID moves it to the root and a nil position puts it last. It returns
+// the path the category had before the move.
+func (r *CategoryRepository) Move(ctx context.Context, id uuid.UUID, parentID *uuid.UUID, position *int) (models.Category, string, error) {
+	ctx, span := startPostgresSpan(ctx, "categories", "Move", attribute.String("category.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Category{}, "", endSpan(span, err)
+	}
+
+	var category models.Category
+	var previousPath string
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockTree(tx, tenantID); err != nil {
+			return err
+		}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&category, "id = ? AND tenant_id = ?", id, tenantID).Error
+		if err != nil {
+			return err
+		}
+		previousPath = category.Path
+
+		parentPath, err := lockParent(tx, tenantID, parentID)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(parentPath, category.Path) {
+			return ErrCategoryCycle
+		}
+
+		// Close the gap left among the old siblings, then open one among the
+		// new siblings.
+		err = siblings(tx, tenantID, category.ParentID).
+			Where("position > ?", category.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return err
+		}
+		newPosition, err := insertPosition(tx, tenantID, parentID, category.ID, position)
+		if err != nil {
+			return err
+		}
+
+		newPath := parentPath + category.ID.String() + "/"
+		if newPath != category.Path {
+			err = tx.Model(&models.Category{}).
+				Where("tenant_id = ? AND path LIKE ?", tenantID, category.Path+"%").
+				Update("path", gorm.Expr("? || substr(path, ?)", newPath, len(category.Path)+1)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		category.ParentID = parentID
+		category.Path = newPath
+		category.Position = newPosition
+		return tx.Model(&category).Select("parent_id", "path", "position").Updates(&category).Error
+	})
+	return category, previousPath, endSpan(span, err)
+}
+
+// Delete removes a category without subcategories and returns the IDs of
+// the products that were filed under it.
+func (r *CategoryRepository) Delete(ctx context.Context, id uuid.UUID) (models.Category, []uuid.UUID, error) {
+	ctx, span := startPostgresSpan(ctx, "categories", "Delete", attribute.String("category.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Category{}, nil, endSpan(span, err)
+	}
+
+	var category models.Category
+	var productIDs []uuid.UUID
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockTree(tx, tenantID); err != nil {
+			return err
+		}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&category, "id = ? AND tenant_id = ?", id, tenantID).Error
+		if err != nil {
+			return err
+		}
+
+		var children int64
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrCategoryNotEmpty
+		}
+
+		err = tx.Model(&models.ProductCategory{}).Where("category_id = ?", id).Pluck("product_id", &productIDs).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&category).Error; err != nil {
+			return err
+		}
+		return siblings(tx, tenantID, category.ParentID).
+			Where("position > ?", category.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+	})
+	return category, productIDs, endSpan(span, err)
+}
+
+// lockTree takes the tenant's category tree lock until tx ends. It has to
+// be taken before any category row, so that writers queue on it rather
+// than deadlock on each other's rows.
+func lockTree(tx *gorm.DB, tenantID string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext('categories'), hashtext(?))", tenantID).Error
+}
+
+// lockParent returns the path of the parent category, or "/" for the root,
+// locking the parent so it cannot be moved concurrently. Writes among the
+// root categories rely on the tree lock alone.
+func lockParent(tx *gorm.DB, tenantID string, parentID *uuid.UUID) (string, error) {
+	if parentID == nil {
+		return "/", nil
+	}
+
+	var parent models.Category
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&parent, "id = ? AND tenant_id = ?", *parentID, tenantID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrParentCategoryNotFound
+	}
+	return parent.Path, err
+}
+
+// insertPosition makes room for a category at position among the children
+// of parentID, excluding the category itself, and returns the position it
+// should take. Positions past the end are clamped and nil means last.
+func insertPosition(tx *gorm.DB, tenantID string, parentID *uuid.UUID, self uuid.UUID, position *int) (int, error) {
+	var count int64
+	if err := siblings(tx, tenantID, parentID).Where("id <> ?", self).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if position == nil || *position >= int(count) {
+		return int(count), nil
+	}
+
+	err := siblings(tx, tenantID, parentID).
+		Where("id <> ? AND position >= ?", self, *position).
+		Update("position", gorm.Expr("position + 1")).Error
+	return *position, err
+}
+
+func siblings(tx *gorm.DB, tenantID string, parentID *uuid.UUID) *gorm.DB {
+	db := tx.Model(&models.Category{}).Where("tenant_id = ?", tenantID)
+	if parentID == nil {
+		return db.Where("parent_id IS NULL")
+	}
+	return db.Where("parent_id = ?", *parentID)
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-product-api/internal/testutil"
+	"go-product-api/models"
+	"go-product-api/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCategoryRepositoryCreateAtRootTakesTreeLock(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+
+	// Root categories have no parent row to lock, so the siblings are only
+	// counted once the tenant's tree lock is held.
+	mock.ExpectBegin()
+	mock.ExpectExec(`pg_advisory_xact_lock`).
+		WithArgs("tenant-a").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM "categories"`).
+		WillReturnError(testutil.ErrStop)
+	mock.ExpectRollback()
+
+	category := models.Category{Name: "Shoes"}
+	err := NewCategoryRepository().Create(tenant.WithTenant(context.Background(), "tenant-a"), &category, nil)
+	if !errors.Is(err, testutil.ErrStop) {
+		t.Fatalf("got error %v, want %v", err, testutil.ErrStop)
+	}
+}
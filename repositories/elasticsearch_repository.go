@@ -8,49 +8,146 @@ import (
 	"fmt"
 	"go-product-api/config"
 	"go-product-api/models"
+	"go-product-api/tenant"
 	"strings"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/google/uuid"
 )
 
+var ErrProductNotFound = errors.New("product not found")
+
+// transportError is a request that got no response from Elasticsearch.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return "error getting response: " + e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+// responseError is an error response from Elasticsearch.
+type responseError struct {
+	op         string
+	statusCode int
+	body       string
+}
+
+func (e *responseError) Error() string { return e.op + ": " + e.body }
+
+func newResponseError(op string, res *esapi.Response) *responseError {
+	return &responseError{op: op, statusCode: res.StatusCode, body: res.String()}
+}
+
+// ElasticsearchRepository reads and writes the index (or filtered alias) of
+// the tenant on the context.
 type ElasticsearchRepository struct{}
 
 func NewElasticsearchRepository() *ElasticsearchRepository {
 	return &ElasticsearchRepository{}
 }
 
-func (r *ElasticsearchRepository) FindAll() ([]models.Product, error) {
+// FindAll and FindByID go through the read circuit breaker. They fail with
+// ErrElasticsearchUnavailable, so the caller can fall back to Postgres, while
+// it is open and when the cluster does not answer or answers with a 5xx.
+func (r *ElasticsearchRepository) FindAll(ctx context.Context, filter ProductFilter) ([]models.Product, error) {
+	return withBreaker(func() ([]models.Product, error) {
+		return r.findAll(ctx, filter)
+	})
+}
+
+func (r *ElasticsearchRepository) FindByID(ctx context.Context, id uuid.UUID) (models.Product, error) {
+	return withBreaker(func() (models.Product, error) {
+		return r.findByID(ctx, id)
+	})
+}
+
+func (r *ElasticsearchRepository) FindBySKU(ctx context.Context, sku string) (models.Product, error) {
+	return withBreaker(func() (models.Product, error) {
+		return r.findBySKU(ctx, sku)
+	})
+}
+
+func (r *ElasticsearchRepository) findAll(ctx context.Context, filter ProductFilter) ([]models.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ESSearchTimeout)
+	defer cancel()
+
+	readIndex, _, err := tenantIndices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var buf bytes.Buffer
 	query := map[string]interface{}{
 		"query": map[string]interface{}{
 			"match_all": map[string]interface{}{},
 		},
-		"size": 100,
+		"size": defaultSearchSize,
+	}
+	applyFilter(query, filter)
+	if filter.Limit > 0 {
+		query["size"] = filter.Limit
+	}
+	if filter.Offset > 0 {
+		query["from"] = filter.Offset
 	}
 
 	if err := json.NewEncoder(&buf).Encode(query); err != nil {
 		return nil, fmt.Errorf("error encoding query: %s", err)
 	}
 
+	return search(ctx, readIndex, &buf)
+}
+
+func (r *ElasticsearchRepository) findBySKU(ctx context.Context, sku string) (models.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ESSearchTimeout)
+	defer cancel()
+
+	readIndex, _, err := tenantIndices(ctx)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	var buf bytes.Buffer
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"sku": sku},
+		},
+		"size": 1,
+	}
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return models.Product{}, fmt.Errorf("error encoding query: %s", err)
+	}
+
+	products, err := search(ctx, readIndex, &buf)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if len(products) == 0 {
+		return models.Product{}, ErrProductNotFound
+	}
+	return products[0], nil
+}
+
+func search(ctx context.Context, index string, body *bytes.Buffer) ([]models.Product, error) {
 	res, err := config.ES.Search(
-		config.ES.Search.WithContext(context.Background()),
-		config.ES.Search.WithIndex("products"),
-		config.ES.Search.WithBody(&buf),
+		config.ES.Search.WithContext(ctx),
+		config.ES.Search.WithIndex(index),
+		config.ES.Search.WithBody(body),
 		config.ES.Search.WithTrackTotalHits(true),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting response: %s", err)
+		return nil, &transportError{err}
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return nil, fmt.Errorf("search error: %s", res.String())
+		return nil, newResponseError("search error", res)
 	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("error parsing the response body: %s", err)
+		return nil, fmt.Errorf("error parsing the response body: %w", err)
 	}
 
 	var products []models.Product
@@ -58,57 +155,67 @@ func (r *ElasticsearchRepository) FindAll() ([]models.Product, error) {
 
 	for _, hit := range hits {
 		source := hit.(map[string]interface{})["_source"].(map[string]interface{})
-
-		product := models.Product{
-			ID:          uuid.MustParse(source["id"].(string)),
-			Name:        source["name"].(string),
-			Description: source["description"].(string),
-			Price:       int(source["price"].(float64)),
-		}
-
-		products = append(products, product)
+		products = append(products, decodeProduct(source))
 	}
 
 	return products, nil
 }
 
-func (r *ElasticsearchRepository) FindByID(id uuid.UUID) (models.Product, error) {
+func (r *ElasticsearchRepository) findByID(ctx context.Context, id uuid.UUID) (models.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ESGetTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Product{}, err
+	}
+	_, writeIndex, err := tenantIndices(ctx)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	// A get by ID ignores alias filters, so read the concrete index and check
+	// the owner below instead.
 	req := esapi.GetRequest{
-		Index:      "products",
+		Index:      writeIndex,
 		DocumentID: id.String(),
 	}
 
-	res, err := req.Do(context.Background(), config.ES)
+	res, err := req.Do(ctx, config.ES)
 	if err != nil {
-		return models.Product{}, fmt.Errorf("error getting response: %s", err)
+		return models.Product{}, &transportError{err}
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode == 404 {
-		return models.Product{}, errors.New("product not found")
+		return models.Product{}, ErrProductNotFound
 	}
 
 	if res.IsError() {
-		return models.Product{}, fmt.Errorf("error response: %s", res.String())
+		return models.Product{}, newResponseError("error response", res)
 	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return models.Product{}, fmt.Errorf("error parsing response body: %s", err)
+		return models.Product{}, fmt.Errorf("error parsing response body: %w", err)
 	}
 
 	source := result["_source"].(map[string]interface{})
-	product := models.Product{
-		ID:          uuid.MustParse(source["id"].(string)),
-		Name:        source["name"].(string),
-		Description: source["description"].(string),
-		Price:       int(source["price"].(float64)),
+	if owner, _ := source["tenant_id"].(string); owner != tenantID {
+		return models.Product{}, ErrProductNotFound
 	}
 
-	return product, nil
+	return decodeProduct(source), nil
 }
 
-func (r *ElasticsearchRepository) Index(product models.Product) error {
+func (r *ElasticsearchRepository) Index(ctx context.Context, product models.Product) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ESWriteTimeout)
+	defer cancel()
+
+	_, writeIndex, err := tenantIndices(ctx)
+	if err != nil {
+		return err
+	}
 
 	productJSON, err := json.Marshal(product)
 	if err != nil {
@@ -116,15 +223,15 @@ func (r *ElasticsearchRepository) Index(product models.Product) error {
 	}
 
 	req := esapi.IndexRequest{
-		Index:      "products",
+		Index:      writeIndex,
 		DocumentID: product.ID.String(),
 		Body:       strings.NewReader(string(productJSON)),
 		Refresh:    "true",
 	}
 
-	res, err := req.Do(context.Background(), config.ES)
+	res, err := req.Do(ctx, config.ES)
 	if err != nil {
-		return err
+		return fmt.Errorf("error getting response: %w", err)
 	}
 	defer res.Body.Close()
 
@@ -135,17 +242,64 @@ func (r *ElasticsearchRepository) Index(product models.Product) error {
 	return nil
 }
 
-func (r *ElasticsearchRepository) Delete(id uuid.UUID) error {
-	req := esapi.DeleteRequest{
-		Index:      "products",
+// SetInStock updates the in_stock flag of an indexed product in place. A
+// product that is not indexed yet is left alone; it picks the flag up when
+// its create event is indexed.
+func (r *ElasticsearchRepository) SetInStock(ctx context.Context, id uuid.UUID, inStock bool) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ESWriteTimeout)
+	defer cancel()
+
+	_, writeIndex, err := tenantIndices(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"doc": map[string]interface{}{"in_stock": inStock},
+	})
+	if err != nil {
+		return err
+	}
+
+	req := esapi.UpdateRequest{
+		Index:      writeIndex,
 		DocumentID: id.String(),
+		Body:       bytes.NewReader(body),
 		Refresh:    "true",
 	}
 
-	res, err := req.Do(context.Background(), config.ES)
+	res, err := req.Do(ctx, config.ES)
+	if err != nil {
+		return fmt.Errorf("error getting response: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != 404 {
+		return fmt.Errorf("update error: %s", res.String())
+	}
+
+	return nil
+}
+
+func (r *ElasticsearchRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ESWriteTimeout)
+	defer cancel()
+
+	_, writeIndex, err := tenantIndices(ctx)
 	if err != nil {
 		return err
 	}
+
+	req := esapi.DeleteRequest{
+		Index:      writeIndex,
+		DocumentID: id.String(),
+		Refresh:    "true",
+	}
+
+	res, err := req.Do(ctx, config.ES)
+	if err != nil {
+		return fmt.Errorf("error getting response: %w", err)
+	}
 	defer res.Body.Close()
 
 	if res.IsError() && res.StatusCode != 404 {
@@ -154,3 +308,279 @@ func (r *ElasticsearchRepository) Delete(id uuid.UUID) error {
 
 	return nil
 }
+
+// IndexAuditEntry copies an audit entry into config.AuditIndex.
+func (r *ElasticsearchRepository) IndexAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ESWriteTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	req := esapi.IndexRequest{
+		Index:      config.AuditIndex,
+		DocumentID: entry.ID.String(),
+		Body:       bytes.NewReader(body),
+	}
+
+	res, err := req.Do(ctx, config.ES)
+	if err != nil {
+		return fmt.Errorf("error getting response: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("index error: %s", res.String())
+	}
+
+	return nil
+}
+
+func tenantIndices(ctx context.Context) (read, write string, err error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return config.TenantProductIndices(ctx, tenantID)
+}
+
+// applyFilter restricts the query to the filter IDs, to products with a
+// price in the filter currency within the bounds, to products under the
+// filter category, by availability and to products with a variant matching
+// all filter options, and sorts by the lowest price in the filter currency.
+func applyFilter(query map[string]interface{}, filter ProductFilter) {
+	var filters []interface{}
+
+	if len(filter.IDs) > 0 {
+		ids := make([]string, len(filter.IDs))
+		for i, id := range filter.IDs {
+			ids[i] = id.String()
+		}
+		filters = append(filters, map[string]interface{}{
+			"terms": map[string]interface{}{"id": ids},
+		})
+	}
+
+	if filter.CategoryPath != "" {
+		filters = append(filters, map[string]interface{}{
+			"prefix": map[string]interface{}{"category_paths": filter.CategoryPath},
+		})
+	}
+
+	if filter.InStock != nil {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"in_stock": *filter.InStock},
+		})
+	}
+
+	if len(filter.Options) > 0 {
+		var optionFilter []interface{}
+		for name, value := range filter.Options {
+			optionFilter = append(optionFilter, map[string]interface{}{
+				"term": map[string]interface{}{"variants.options." + name: value},
+			})
+		}
+		filters = append(filters, map[string]interface{}{
+			"nested": map[string]interface{}{
+				"path":  "variants",
+				"query": map[string]interface{}{"bool": map[string]interface{}{"filter": optionFilter}},
+			},
+		})
+	}
+
+	if filter.Currency != "" {
+		amount := map[string]interface{}{}
+		if filter.MinPrice != nil {
+			amount["gte"] = *filter.MinPrice
+		}
+		if filter.MaxPrice != nil {
+			amount["lte"] = *filter.MaxPrice
+		}
+		priceFilter := []interface{}{
+			map[string]interface{}{"term": map[string]interface{}{"prices.currency": filter.Currency}},
+		}
+		if len(amount) > 0 {
+			priceFilter = append(priceFilter, map[string]interface{}{"range": map[string]interface{}{"prices.amount": amount}})
+		}
+		filters = append(filters, map[string]interface{}{
+			"nested": map[string]interface{}{
+				"path":  "prices",
+				"query": map[string]interface{}{"bool": map[string]interface{}{"filter": priceFilter}},
+			},
+		})
+
+		if filter.Sort == SortPriceAsc || filter.Sort == SortPriceDesc {
+			order := "asc"
+			if filter.Sort == SortPriceDesc {
+				order = "desc"
+			}
+			query["sort"] = []interface{}{
+				map[string]interface{}{
+					"prices.amount": map[string]interface{}{
+						"order": order,
+						"mode":  "min",
+						"nested": map[string]interface{}{
+							"path":   "prices",
+							"filter": map[string]interface{}{"term": map[string]interface{}{"prices.currency": filter.Currency}},
+						},
+					},
+				},
+			}
+		}
+	}
+
+	// Ties are broken by ID so pages do not overlap.
+	sort, _ := query["sort"].([]interface{})
+	if filter.Query != "" && len(sort) == 0 {
+		sort = append(sort, "_score")
+	}
+	query["sort"] = append(sort, map[string]interface{}{"id": "asc"})
+
+	var must []interface{}
+	if filter.Query != "" {
+		must = append(must, map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":  filter.Query,
+				"fields": []string{"name^3", "sku^2", "description", "tags"},
+			},
+		})
+	}
+
+	if len(filters) > 0 || len(must) > 0 {
+		boolQuery := map[string]interface{}{}
+		if len(filters) > 0 {
+			boolQuery["filter"] = filters
+		}
+		if len(must) > 0 {
+			boolQuery["must"] = must
+		}
+		query["query"] = map[string]interface{}{"bool": boolQuery}
+	}
+}
+
+// decodeProduct builds a product from an indexed document. Documents
+// indexed before a field existed simply leave it at its zero value.
+func decodeProduct(source map[string]interface{}) models.Product {
+	tenantID, _ := source["tenant_id"].(string)
+	sku, _ := source["sku"].(string)
+	status, _ := source["status"].(string)
+	inStock, _ := source["in_stock"].(bool)
+	attributes, _ := source["attributes"].(map[string]interface{})
+	id := uuid.MustParse(source["id"].(string))
+
+	product := models.Product{
+		ID:          id,
+		TenantID:    tenantID,
+		SKU:         sku,
+		Name:        source["name"].(string),
+		Description: source["description"].(string),
+		Status:      status,
+		Tags:        decodeTags(source["tags"]),
+		Attributes:  attributes,
+		Prices:      decodePrices(source["prices"]),
+
+		CategoryIDs:   decodeCategoryIDs(source["category_ids"]),
+		CategoryPaths: decodeStrings(source["category_paths"]),
+		InStock:       inStock,
+		Options:       decodeOptions(source["options"]),
+		Variants:      decodeVariants(id, source["variants"]),
+	}
+	if createdAt, ok := source["created_at"].(string); ok {
+		product.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	}
+	if updatedAt, ok := source["updated_at"].(string); ok {
+		product.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+	}
+	return product
+}
+
+func decodeTags(value interface{}) models.Tags {
+	return models.Tags(decodeStrings(value))
+}
+
+func decodeStrings(value interface{}) []string {
+	items, _ := value.([]interface{})
+	strs := make([]string, 0, len(items))
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
+
+func decodeCategoryIDs(value interface{}) []uuid.UUID {
+	strs := decodeStrings(value)
+	ids := make([]uuid.UUID, 0, len(strs))
+	for _, str := range strs {
+		if id, err := uuid.Parse(str); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func decodeOptions(value interface{}) models.ProductOptions {
+	items, _ := value.([]interface{})
+	options := make(models.ProductOptions, 0, len(items))
+	for _, item := range items {
+		o, _ := item.(map[string]interface{})
+		name, _ := o["name"].(string)
+		options = append(options, models.ProductOption{Name: name, Values: decodeStrings(o["values"])})
+	}
+	return options
+}
+
+func decodeVariants(productID uuid.UUID, value interface{}) []models.ProductVariant {
+	items, _ := value.([]interface{})
+	variants := make([]models.ProductVariant, 0, len(items))
+	for _, item := range items {
+		v := item.(map[string]interface{})
+		sku, _ := v["sku"].(string)
+		stock, _ := v["stock"].(float64)
+
+		variant := models.ProductVariant{
+			ID:        uuid.MustParse(v["id"].(string)),
+			ProductID: productID,
+			SKU:       sku,
+			Options:   models.VariantOptions{},
+			Stock:     int64(stock),
+		}
+		options, _ := v["options"].(map[string]interface{})
+		for name, value := range options {
+			variant.Options[name], _ = value.(string)
+		}
+		for _, price := range decodePrices(v["prices"]) {
+			variant.Prices = append(variant.Prices, models.VariantPrice{
+				Currency: price.Currency,
+				Market:   price.Market,
+				Amount:   price.Amount,
+			})
+		}
+		if createdAt, ok := v["created_at"].(string); ok {
+			variant.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+		}
+		if updatedAt, ok := v["updated_at"].(string); ok {
+			variant.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+		}
+		variants = append(variants, variant)
+	}
+	return variants
+}
+
+func decodePrices(value interface{}) []models.ProductPrice {
+	items, _ := value.([]interface{})
+	prices := make([]models.ProductPrice, 0, len(items))
+	for _, item := range items {
+		p := item.(map[string]interface{})
+		market, _ := p["market"].(string)
+		prices = append(prices, models.ProductPrice{
+			Currency: p["currency"].(string),
+			Market:   market,
+			Amount:   int64(p["amount"].(float64)),
+		})
+	}
+	return prices
+}
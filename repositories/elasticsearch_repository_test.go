@@ -0,0 +1,213 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-product-api/config"
+	"go-product-api/tenant"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/google/uuid"
+)
+
+// fakeElasticsearch keeps documents per concrete index and applies the term
+// filter of an alias the way Elasticsearch does, so a search through the
+// wrong index or an unfiltered alias shows up as a leak.
+type fakeElasticsearch struct {
+	mu      sync.Mutex
+	indices map[string]map[string]map[string]interface{}
+	aliases map[string]fakeAlias
+}
+
+type fakeAlias struct {
+	index    string
+	tenantID string
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 3 && (parts[1] == "_alias" || parts[1] == "_aliases") && r.Method == http.MethodPut:
+		var body struct {
+			Filter struct {
+				Term map[string]string `json:"term"`
+			} `json:"filter"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.aliases[parts[2]] = fakeAlias{index: parts[0], tenantID: body.Filter.Term["tenant_id"]}
+		w.Write([]byte(`{"acknowledged":true}`))
+	case len(parts) == 1 && r.Method == http.MethodHead:
+		if _, ok := f.indices[parts[0]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		f.indices[parts[0]] = map[string]map[string]interface{}{}
+		w.Write([]byte(`{"acknowledged":true}`))
+	case len(parts) == 2 && parts[1] == "_mapping":
+		w.Write([]byte(`{"acknowledged":true}`))
+	case len(parts) == 2 && parts[1] == "_search":
+		var body struct {
+			Query struct {
+				Term map[string]string `json:"term"`
+			} `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		hits := []interface{}{}
+		for _, doc := range f.visible(parts[0]) {
+			if sku, ok := body.Query.Term["sku"]; ok && doc["sku"] != sku {
+				continue
+			}
+			hits = append(hits, map[string]interface{}{"_source": doc})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"hits": map[string]interface{}{"hits": hits}})
+	case len(parts) == 3 && parts[1] == "_doc" && r.Method == http.MethodGet:
+		doc, ok := f.indices[parts[0]][parts[2]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"found":false}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"found": true, "_source": doc})
+	default:
+		http.Error(w, `{"error":"unexpected request"}`, http.StatusBadRequest)
+	}
+}
+
+// visible returns the documents a search on name sees: those of the index,
+// or those of the aliased index that pass the alias filter.
+func (f *fakeElasticsearch) visible(name string) []map[string]interface{} {
+	var docs []map[string]interface{}
+	if alias, ok := f.aliases[name]; ok {
+		for _, doc := range f.indices[alias.index] {
+			if doc["tenant_id"] == alias.tenantID {
+				docs = append(docs, doc)
+			}
+		}
+		return docs
+	}
+	for _, doc := range f.indices[name] {
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func (f *fakeElasticsearch) put(index string, id uuid.UUID, tenantID, sku string) {
+	if f.indices[index] == nil {
+		f.indices[index] = map[string]map[string]interface{}{}
+	}
+	f.indices[index][id.String()] = map[string]interface{}{
+		"id":          id.String(),
+		"tenant_id":   tenantID,
+		"sku":         sku,
+		"name":        sku,
+		"description": "",
+	}
+}
+
+// useFakeElasticsearch points config.ES at a fake cluster in the given
+// tenant mode for the duration of the test.
+func useFakeElasticsearch(t *testing.T, mode string) *fakeElasticsearch {
+	t.Helper()
+	fake := &fakeElasticsearch{
+		indices: map[string]map[string]map[string]interface{}{config.ProductIndex: {}},
+		aliases: map[string]fakeAlias{},
+	}
+	srv := httptest.NewServer(fake)
+	client, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousClient, previousMode := config.ES, config.ESTenantMode
+	config.ES, config.ESTenantMode = client, mode
+	t.Cleanup(func() {
+		config.ES, config.ESTenantMode = previousClient, previousMode
+		srv.Close()
+	})
+	return fake
+}
+
+func TestElasticsearchRepositoryAliasModeIsolatesTenants(t *testing.T) {
+	fake := useFakeElasticsearch(t, "alias")
+	repo := NewElasticsearchRepository()
+	ctxA := tenant.WithTenant(context.Background(), "alias-a")
+	ctxB := tenant.WithTenant(context.Background(), "alias-b")
+	idA, idB := uuid.New(), uuid.New()
+	fake.put(config.ProductIndex, idA, "alias-a", "SKU-A")
+	fake.put(config.ProductIndex, idB, "alias-b", "SKU-B")
+
+	products, err := repo.FindAll(ctxA, ProductFilter{})
+	if err != nil {
+		t.Fatalf("alias-a: unexpected error: %v", err)
+	}
+	if len(products) != 1 || products[0].ID != idA {
+		t.Fatalf("alias-a: got %v, want only its own product", products)
+	}
+	if alias := fake.aliases[config.ProductIndex+"_alias-a"]; alias.tenantID != "alias-a" {
+		t.Fatalf("alias-a: alias filters on tenant %q", alias.tenantID)
+	}
+
+	products, err = repo.FindAll(ctxB, ProductFilter{})
+	if err != nil {
+		t.Fatalf("alias-b: unexpected error: %v", err)
+	}
+	if len(products) != 1 || products[0].ID != idB {
+		t.Fatalf("alias-b: got %v, want only its own product", products)
+	}
+
+	// Gets by ID bypass the alias filter, so the owner check has to hold.
+	if _, err := repo.FindByID(ctxB, idA); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("alias-b: FindByID of alias-a's product got error %v, want %v", err, ErrProductNotFound)
+	}
+	if _, err := repo.FindBySKU(ctxB, "SKU-A"); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("alias-b: FindBySKU of alias-a's product got error %v, want %v", err, ErrProductNotFound)
+	}
+	if product, err := repo.FindByID(ctxA, idA); err != nil || product.ID != idA {
+		t.Fatalf("alias-a: FindByID of its own product got %v, %v", product.ID, err)
+	}
+}
+
+func TestElasticsearchRepositoryIndexModeIsolatesTenants(t *testing.T) {
+	fake := useFakeElasticsearch(t, "index")
+	repo := NewElasticsearchRepository()
+	ctxA := tenant.WithTenant(context.Background(), "index-a")
+	ctxB := tenant.WithTenant(context.Background(), "index-b")
+	idA, idB := uuid.New(), uuid.New()
+	fake.put(config.ProductIndex+"_index-a", idA, "index-a", "SKU-A")
+	fake.put(config.ProductIndex+"_index-b", idB, "index-b", "SKU-B")
+
+	products, err := repo.FindAll(ctxA, ProductFilter{})
+	if err != nil {
+		t.Fatalf("index-a: unexpected error: %v", err)
+	}
+	if len(products) != 1 || products[0].ID != idA {
+		t.Fatalf("index-a: got %v, want only its own product", products)
+	}
+
+	products, err = repo.FindAll(ctxB, ProductFilter{})
+	if err != nil {
+		t.Fatalf("index-b: unexpected error: %v", err)
+	}
+	if len(products) != 1 || products[0].ID != idB {
+		t.Fatalf("index-b: got %v, want only its own product", products)
+	}
+
+	if _, err := repo.FindByID(ctxB, idA); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("index-b: FindByID of index-a's product got error %v, want %v", err, ErrProductNotFound)
+	}
+	if _, err := repo.FindBySKU(ctxB, "SKU-A"); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("index-b: FindBySKU of index-a's product got error %v, want %v", err, ErrProductNotFound)
+	}
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"go-product-api/config"
+	"go-product-api/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExchangeRateRepository manages the conversion rates used for display
+// prices. Rates are shared by all tenants.
+type ExchangeRateRepository struct{}
+
+func NewExchangeRateRepository() *ExchangeRateRepository {
+	return &ExchangeRateRepository{}
+}
+
+func (r *ExchangeRateRepository) FindAll(ctx context.Context) ([]models.ExchangeRate, error) {
+	ctx, span := startPostgresSpan(ctx, "exchange_rates", "FindAll")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	var rates []models.ExchangeRate
+	result := config.DB.WithContext(ctx).Order("base_currency, quote_currency").Find(&rates)
+	return rates, endSpan(span, result.Error)
+}
+
+// Upsert creates the rate or replaces the existing one for the same pair.
+func (r *ExchangeRateRepository) Upsert(ctx context.Context, rate *models.ExchangeRate) error {
+	ctx, span := startPostgresSpan(ctx, "exchange_rates", "Upsert",
+		attribute.String("currency.base", rate.BaseCurrency),
+		attribute.String("currency.quote", rate.QuoteCurrency),
+	)
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	result := config.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base_currency"}, {Name: "quote_currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(rate)
+	return endSpan(span, result.Error)
+}
+
+// Delete removes the rate for the pair. It returns gorm.ErrRecordNotFound
+// when there is none.
+func (r *ExchangeRateRepository) Delete(ctx context.Context, base, quote string) error {
+	ctx, span := startPostgresSpan(ctx, "exchange_rates", "Delete",
+		attribute.String("currency.base", base),
+		attribute.String("currency.quote", quote),
+	)
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	result := config.DB.WithContext(ctx).Delete(&models.ExchangeRate{}, "base_currency = ? AND quote_currency = ?", base, quote)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = gorm.ErrRecordNotFound
+	}
+	return endSpan(span, result.Error)
+}
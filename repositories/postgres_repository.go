@@ -1,38 +1,530 @@
 package repositories
 
 import (
+	"context"
+	"encoding/json"
+	"go-product-api/audit"
 	"go-product-api/config"
 	"go-product-api/models"
+	"go-product-api/tenant"
+	"strings"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// PostgresRepository scopes every query to the tenant on the context and
+// fails with tenant.ErrMissingTenant when there is none.
 type PostgresRepository struct{}
 
 func NewPostgresRepository() *PostgresRepository {
 	return &PostgresRepository{}
 }
 
-func (r *PostgresRepository) FindAll() ([]models.Product, error) {
+func (r *PostgresRepository) FindAll(ctx context.Context, filter ProductFilter) ([]models.Product, error) {
+	ctx, span := startPostgresSpan(ctx, "products", "FindAll")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	if len(filter.IDs) > 0 {
+		db = db.Where("products.id IN ?", filter.IDs)
+	}
+
+	if filter.Currency != "" {
+		cond := "EXISTS (SELECT 1 FROM product_prices pp WHERE pp.product_id = products.id AND pp.currency = ?"
+		args := []any{filter.Currency}
+		if filter.MinPrice != nil {
+			cond += " AND pp.amount >= ?"
+			args = append(args, *filter.MinPrice)
+		}
+		if filter.MaxPrice != nil {
+			cond += " AND pp.amount <= ?"
+			args = append(args, *filter.MaxPrice)
+		}
+		db = db.Where(cond+")", args...)
+
+		if filter.Sort == SortPriceAsc || filter.Sort == SortPriceDesc {
+			order := "ASC"
+			if filter.Sort == SortPriceDesc {
+				order = "DESC"
+			}
+			db = db.Order(clause.OrderBy{Expression: clause.Expr{
+				SQL:  "(SELECT MIN(pp.amount) FROM product_prices pp WHERE pp.product_id = products.id AND pp.currency = ?) " + order,
+				Vars: []any{filter.Currency},
+			}})
+		}
+	}
+
+	if filter.InStock != nil {
+		cond := "(" + inStockCondition + ")"
+		if !*filter.InStock {
+			cond = "NOT " + cond
+		}
+		db = db.Where(cond)
+	}
+
+	for name, value := range filter.Options {
+		option, err := json.Marshal(map[string]string{name: value})
+		if err != nil {
+			return nil, endSpan(span, err)
+		}
+		db = db.Where("EXISTS (SELECT 1 FROM product_variants pv WHERE pv.product_id = products.id AND pv.options @> ?::jsonb)", string(option))
+	}
+
+	if filter.CategoryPath != "" {
+		db = db.Where(`EXISTS (SELECT 1 FROM product_categories pc JOIN categories c ON c.id = pc.category_id
+			WHERE pc.product_id = products.id AND c.path LIKE ?)`, filter.CategoryPath+"%")
+	}
+
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		db = db.Where("(products.name ILIKE ? OR products.sku ILIKE ? OR products.description ILIKE ? OR products.tags::text ILIKE ?)",
+			pattern, pattern, pattern, pattern)
+	}
+
+	db = db.Order("products.id")
+	if filter.Limit > 0 {
+		db = db.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		db = db.Offset(filter.Offset)
+	}
+
 	var products []models.Product
-	result := config.DB.Find(&products)
-	return products, result.Error
+	if err := preloadProduct(db).Find(&products).Error; err != nil {
+		return nil, endSpan(span, err)
+	}
+	return products, endSpan(span, loadDetails(config.DB.WithContext(ctx), products))
+}
+
+func (r *PostgresRepository) FindByID(ctx context.Context, id uuid.UUID) (models.Product, error) {
+	ctx, span := startPostgresSpan(ctx, "products", "FindByID", attribute.String("product.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return models.Product{}, endSpan(span, err)
+	}
+
+	products := make([]models.Product, 1)
+	if err := preloadProduct(db).First(&products[0], "id = ?", id).Error; err != nil {
+		return models.Product{}, endSpan(span, err)
+	}
+	err = loadDetails(config.DB.WithContext(ctx), products)
+	return products[0], endSpan(span, err)
+}
+
+func (r *PostgresRepository) FindBySKU(ctx context.Context, sku string) (models.Product, error) {
+	ctx, span := startPostgresSpan(ctx, "products", "FindBySKU", attribute.String("product.sku", sku))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return models.Product{}, endSpan(span, err)
+	}
+
+	products := make([]models.Product, 1)
+	if err := preloadProduct(db).First(&products[0], "sku = ?", sku).Error; err != nil {
+		return models.Product{}, endSpan(span, err)
+	}
+	err = loadDetails(config.DB.WithContext(ctx), products)
+	return products[0], endSpan(span, err)
+}
+
+// Create stores the product and records its creation in the audit trail.
+func (r *PostgresRepository) Create(ctx context.Context, product *models.Product) (*models.AuditEntry, error) {
+	ctx, span := startPostgresSpan(ctx, "products", "Create")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	product.TenantID = tenantID
+
+	var entry *models.AuditEntry
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		source := priceSource{actor: audit.Actor(ctx)}
+		if err := recordPriceChanges(tx, tenantID, product.ID, nil, product.Prices, source); err != nil {
+			return err
+		}
+		if err := replaceCategories(tx, tenantID, product); err != nil {
+			return err
+		}
+		entry, err = recordProductAudit(ctx, tx, models.AuditActionCreate, nil, product)
+		return err
+	})
+	return entry, endSpan(span, err)
+}
+
+// Update loads the product with its row locked, applies change to it and
+// saves it, recording the fields that changed in the audit trail, all in one
+// transaction. A concurrent writer of the product waits for it rather than
+// saving or auditing against a stale copy. An error from change aborts the
+// update. The returned entry is nil when nothing changed.
+func (r *PostgresRepository) Update(ctx context.Context, id uuid.UUID, change func(product *models.Product) error) (models.Product, *models.AuditEntry, error) {
+	ctx, span := startPostgresSpan(ctx, "products", "Update", attribute.String("product.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Product{}, nil, endSpan(span, err)
+	}
+
+	var product models.Product
+	var entry *models.AuditEntry
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		previous, err := lockProduct(tx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		product = previous
+		if err := change(&product); err != nil {
+			return err
+		}
+		product.ID, product.TenantID = id, tenantID
+
+		err = tx.Model(&product).
+			Where("tenant_id = ?", tenantID).
+			Select("*").
+			Omit(clause.Associations).
+			Updates(&product).Error
+		if err != nil {
+			return err
+		}
+		if err := replacePrices(tx, &product, priceSource{actor: audit.Actor(ctx)}); err != nil {
+			return err
+		}
+		if err := replaceCategories(tx, tenantID, &product); err != nil {
+			return err
+		}
+		entry, err = recordProductAudit(ctx, tx, models.AuditActionUpdate, &previous, &product)
+		return err
+	})
+	if err != nil {
+		return models.Product{}, nil, endSpan(span, err)
+	}
+	return product, entry, nil
+}
+
+// lockProduct loads a product of the tenant with all its details inside tx,
+// with its row locked for update until tx ends.
+func lockProduct(tx *gorm.DB, tenantID string, id uuid.UUID) (models.Product, error) {
+	products := make([]models.Product, 1)
+	err := preloadProduct(tx.Clauses(clause.Locking{Strength: "UPDATE"})).
+		Where("tenant_id = ? AND id = ?", tenantID, id).
+		Take(&products[0]).Error
+	if err != nil {
+		return models.Product{}, err
+	}
+	return products[0], loadDetails(tx, products)
+}
+
+// replacePrices swaps the stored prices of a product for product.Prices
+// and records the difference in the price history.
+func replacePrices(tx *gorm.DB, product *models.Product, source priceSource) error {
+	var old []models.ProductPrice
+	if err := tx.Where("product_id = ?", product.ID).Find(&old).Error; err != nil {
+		return err
+	}
+	if err := recordPriceChanges(tx, product.TenantID, product.ID, old, product.Prices, source); err != nil {
+		return err
+	}
+	if err := tx.Where("product_id = ?", product.ID).Delete(&models.ProductPrice{}).Error; err != nil {
+		return err
+	}
+	if len(product.Prices) == 0 {
+		return nil
+	}
+	for i := range product.Prices {
+		product.Prices[i].ID = uuid.Nil
+		product.Prices[i].ProductID = product.ID
+	}
+	return tx.Create(&product.Prices).Error
+}
+
+// replaceCategories files the product under exactly product.CategoryIDs and
+// fills in their paths. It fails with ErrUnknownCategory when an ID does not
+// name a category of the tenant.
+func replaceCategories(tx *gorm.DB, tenantID string, product *models.Product) error {
+	if err := tx.Where("product_id = ?", product.ID).Delete(&models.ProductCategory{}).Error; err != nil {
+		return err
+	}
+	product.CategoryPaths = nil
+	if len(product.CategoryIDs) == 0 {
+		return nil
+	}
+
+	var categories []models.Category
+	err := tx.Where("tenant_id = ? AND id IN ?", tenantID, product.CategoryIDs).Order("path").Find(&categories).Error
+	if err != nil {
+		return err
+	}
+	if len(categories) != len(uniqueIDs(product.CategoryIDs)) {
+		return ErrUnknownCategory
+	}
+
+	links := make([]models.ProductCategory, len(categories))
+	product.CategoryIDs = make([]uuid.UUID, len(categories))
+	product.CategoryPaths = make([]string, len(categories))
+	for i, category := range categories {
+		links[i] = models.ProductCategory{ProductID: product.ID, CategoryID: category.ID}
+		product.CategoryIDs[i] = category.ID
+		product.CategoryPaths[i] = category.Path
+	}
+	return tx.Omit(clause.Associations).Create(&links).Error
 }
 
-func (r *PostgresRepository) FindByID(id uuid.UUID) (models.Product, error) {
+// inStockCondition matches products with available warehouse stock or a
+// variant with stock on hand.
+const inStockCondition = `EXISTS (SELECT 1 FROM stock_levels sl WHERE sl.product_id = products.id AND sl.on_hand > sl.reserved)
+	OR EXISTS (SELECT 1 FROM product_variants pv WHERE pv.product_id = products.id AND pv.stock > 0)`
+
+func preloadProduct(db *gorm.DB) *gorm.DB {
+	return db.Preload("Prices").
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("Variants.Prices")
+}
+
+// loadDetails fills in the fields of the products that are not columns of
+// the products table.
+func loadDetails(db *gorm.DB, products []models.Product) error {
+	if err := loadCategories(db, products); err != nil {
+		return err
+	}
+	return loadInStock(db, products)
+}
+
+func loadInStock(db *gorm.DB, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+	}
+
+	var inStock []uuid.UUID
+	err := db.Model(&models.Product{}).
+		Where("products.id IN ? AND ("+inStockCondition+")", ids).
+		Pluck("products.id", &inStock).Error
+	if err != nil {
+		return err
+	}
+
+	set := uniqueIDs(inStock)
+	for i := range products {
+		_, products[i].InStock = set[products[i].ID]
+	}
+	return nil
+}
+
+// loadCategories fills in CategoryIDs and CategoryPaths of the products.
+func loadCategories(db *gorm.DB, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(products))
+	byID := make(map[uuid.UUID]*models.Product, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+		byID[products[i].ID] = &products[i]
+	}
+
+	var rows []struct {
+		ProductID  uuid.UUID
+		CategoryID uuid.UUID
+		Path       string
+	}
+	err := db.Table("product_categories pc").
+		Select("pc.product_id, pc.category_id, c.path").
+		Joins("JOIN categories c ON c.id = pc.category_id").
+		Where("pc.product_id IN ?", ids).
+		Order("c.path").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		product := byID[row.ProductID]
+		product.CategoryIDs = append(product.CategoryIDs, row.CategoryID)
+		product.CategoryPaths = append(product.CategoryPaths, row.Path)
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uuid.UUID) map[uuid.UUID]struct{} {
+	set := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+// Delete loads the product with its row locked, deletes it and records its
+// last state in the audit trail, all in one transaction. It returns the
+// product as it was.
+func (r *PostgresRepository) Delete(ctx context.Context, id uuid.UUID) (models.Product, *models.AuditEntry, error) {
+	ctx, span := startPostgresSpan(ctx, "products", "Delete", attribute.String("product.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Product{}, nil, endSpan(span, err)
+	}
+
 	var product models.Product
-	result := config.DB.First(&product, "id = ?", id)
-	return product, result.Error
+	var entry *models.AuditEntry
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if product, err = lockProduct(tx, tenantID, id); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Product{}, "tenant_id = ? AND id = ?", tenantID, id).Error; err != nil {
+			return err
+		}
+		entry, err = recordProductAudit(ctx, tx, models.AuditActionDelete, &product, nil)
+		return err
+	})
+	if err != nil {
+		return models.Product{}, nil, endSpan(span, err)
+	}
+	return product, entry, nil
+}
+
+// Restore writes product as it is, keeping its ID, timestamps and variants,
+// over whatever is stored for it. It is meant for rebuilding the read model
+// from the event log: no price history or audit entry is recorded, and
+// categories that no longer exist are dropped.
+func (r *PostgresRepository) Restore(ctx context.Context, product models.Product) error {
+	ctx, span := startPostgresSpan(ctx, "products", "Restore", attribute.String("product.id", product.ID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+	product.TenantID = tenantID
+
+	// Hooks would assign fresh IDs, so they are skipped and the IDs of rows
+	// the event does not carry are assigned here.
+	err = config.DB.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Omit(clause.Associations).Create(&product).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("product_id = ?", product.ID).Delete(&models.ProductPrice{}).Error; err != nil {
+			return err
+		}
+		for i := range product.Prices {
+			product.Prices[i].ProductID = product.ID
+			if product.Prices[i].ID, err = uuid.NewV7(); err != nil {
+				return err
+			}
+		}
+		if len(product.Prices) > 0 {
+			if err := tx.Create(&product.Prices).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("product_id = ?", product.ID).Delete(&models.ProductVariant{}).Error; err != nil {
+			return err
+		}
+		for _, variant := range product.Variants {
+			variant.ProductID = product.ID
+			variant.TenantID = tenantID
+			variant.OptionsKey = variant.Options.Key()
+			for i := range variant.Prices {
+				variant.Prices[i].VariantID = variant.ID
+				if variant.Prices[i].ID, err = uuid.NewV7(); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(&variant).Error; err != nil {
+				return err
+			}
+		}
+
+		var categoryIDs []uuid.UUID
+		if len(product.CategoryIDs) > 0 {
+			err := tx.Model(&models.Category{}).
+				Where("tenant_id = ? AND id IN ?", tenantID, product.CategoryIDs).
+				Pluck("id", &categoryIDs).Error
+			if err != nil {
+				return err
+			}
+		}
+		product.CategoryIDs = categoryIDs
+		return replaceCategories(tx, tenantID, &product)
+	})
+	return endSpan(span, err)
 }
 
-func (r *PostgresRepository) Create(product *models.Product) error {
-	return config.DB.Create(product).Error
+// Purge deletes a product, if it is stored, without recording it in the
+// audit trail. Like Restore it is meant for rebuilding the read model.
+func (r *PostgresRepository) Purge(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startPostgresSpan(ctx, "products", "Purge", attribute.String("product.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+	return endSpan(span, db.Delete(&models.Product{}, "id = ?", id).Error)
+}
+
+// FindTenantIDs lists every tenant that owns at least one product. It is
+// the only unscoped query and is meant for maintenance jobs such as the
+// Elasticsearch sync.
+func (r *PostgresRepository) FindTenantIDs(ctx context.Context) ([]string, error) {
+	ctx, span := startPostgresSpan(ctx, "products", "FindTenantIDs")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	var tenantIDs []string
+	result := config.DB.WithContext(ctx).Model(&models.Product{}).Distinct().Pluck("tenant_id", &tenantIDs)
+	return tenantIDs, endSpan(span, result.Error)
 }
 
-func (r *PostgresRepository) Update(product *models.Product) error {
-	return config.DB.Save(product).Error
+func scoped(ctx context.Context) (*gorm.DB, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return config.DB.WithContext(ctx).Where("tenant_id = ?", tenantID), nil
 }
 
-func (r *PostgresRepository) Delete(id uuid.UUID) error {
-	return config.DB.Delete(&models.Product{}, "id = ?", id).Error
+// escapeLike escapes the wildcards of a LIKE pattern so s matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-product-api/internal/testutil"
+	"go-product-api/models"
+	"go-product-api/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// expectProductDetails expects the queries that fill in the prices,
+// variants, categories and stock of a product that was found.
+func expectProductDetails(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`FROM "product_prices"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`FROM "product_variants"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`FROM product_categories pc`).WillReturnRows(sqlmock.NewRows([]string{"product_id"}))
+	mock.ExpectQuery(`FROM "products" WHERE products.id IN`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func TestPostgresRepositoryFindByIDIsScopedToTenant(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	repo := NewPostgresRepository()
+	id := uuid.New()
+	query := `FROM "products" WHERE tenant_id = \$1`
+
+	mock.ExpectQuery(query).
+		WithArgs("tenant-a", id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "sku"}).AddRow(id, "tenant-a", "SKU-1"))
+	expectProductDetails(mock)
+	mock.ExpectQuery(query).
+		WithArgs("tenant-b", id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "sku"}))
+
+	product, err := repo.FindByID(tenant.WithTenant(context.Background(), "tenant-a"), id)
+	if err != nil {
+		t.Fatalf("tenant-a: unexpected error: %v", err)
+	}
+	if product.ID != id || product.TenantID != "tenant-a" {
+		t.Fatalf("tenant-a: got product %s of %q", product.ID, product.TenantID)
+	}
+
+	_, err = repo.FindByID(tenant.WithTenant(context.Background(), "tenant-b"), id)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("tenant-b: got error %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestPostgresRepositoryFindBySKUIsScopedToTenant(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	repo := NewPostgresRepository()
+	query := `FROM "products" WHERE tenant_id = \$1`
+
+	mock.ExpectQuery(query).
+		WithArgs("tenant-b", "SKU-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "sku"}))
+
+	_, err := repo.FindBySKU(tenant.WithTenant(context.Background(), "tenant-b"), "SKU-1")
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("got error %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestPostgresRepositoryFindAllIsScopedToTenant(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	repo := NewPostgresRepository()
+	id := uuid.New()
+	query := `FROM "products" WHERE tenant_id = \$1`
+
+	mock.ExpectQuery(query).
+		WithArgs("tenant-a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "sku"}).AddRow(id, "tenant-a", "SKU-1"))
+	expectProductDetails(mock)
+	mock.ExpectQuery(query).
+		WithArgs("tenant-b").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "sku"}))
+
+	products, err := repo.FindAll(tenant.WithTenant(context.Background(), "tenant-a"), ProductFilter{})
+	if err != nil {
+		t.Fatalf("tenant-a: unexpected error: %v", err)
+	}
+	if len(products) != 1 || products[0].ID != id {
+		t.Fatalf("tenant-a: got %d products, want its own product", len(products))
+	}
+
+	products, err = repo.FindAll(tenant.WithTenant(context.Background(), "tenant-b"), ProductFilter{})
+	if err != nil {
+		t.Fatalf("tenant-b: unexpected error: %v", err)
+	}
+	if len(products) != 0 {
+		t.Fatalf("tenant-b: got %d products of another tenant", len(products))
+	}
+}
+
+func TestPostgresRepositoryDeleteIsScopedToTenant(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	repo := NewPostgresRepository()
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM "products" WHERE tenant_id = \$1 .* FOR UPDATE`).
+		WithArgs("tenant-b", id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "sku"}))
+	mock.ExpectRollback()
+
+	_, _, err := repo.Delete(tenant.WithTenant(context.Background(), "tenant-b"), id)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("got error %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestPostgresRepositoryUpdateChangesTheLockedRow(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	repo := NewPostgresRepository()
+	id := uuid.New()
+	errRejected := errors.New("rejected")
+
+	// The change sees the row as locked in the transaction, and nothing is
+	// written when it fails.
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM "products" WHERE tenant_id = \$1 .* FOR UPDATE`).
+		WithArgs("tenant-a", id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "sku"}).AddRow(id, "tenant-a", "SKU-LOCKED"))
+	expectProductDetails(mock)
+	mock.ExpectRollback()
+
+	var seen string
+	_, _, err := repo.Update(tenant.WithTenant(context.Background(), "tenant-a"), id, func(product *models.Product) error {
+		seen = product.SKU
+		return errRejected
+	})
+	if !errors.Is(err, errRejected) {
+		t.Fatalf("got error %v, want %v", err, errRejected)
+	}
+	if seen != "SKU-LOCKED" {
+		t.Fatalf("change saw SKU %q, want the locked row's", seen)
+	}
+}
+
+func TestPostgresRepositoryRequiresTenant(t *testing.T) {
+	testutil.MockDatabase(t)
+	repo := NewPostgresRepository()
+
+	if _, err := repo.FindAll(context.Background(), ProductFilter{}); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("FindAll: got error %v, want %v", err, tenant.ErrMissingTenant)
+	}
+	if _, err := repo.FindByID(context.Background(), uuid.New()); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("FindByID: got error %v, want %v", err, tenant.ErrMissingTenant)
+	}
+}
@@ -0,0 +1,334 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-product-api/audit"
+	"go-product-api/config"
+	"go-product-api/models"
+	"go-product-api/tenant"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrScheduledChangeNotFound = errors.New("scheduled price change not found")
+	ErrScheduledChangeClosed   = errors.New("scheduled price change is no longer pending")
+)
+
+// PriceRepository reads the price history of products and manages their
+// scheduled price changes. Price history is written by whichever repository
+// changes the prices, in the same transaction.
+type PriceRepository struct{}
+
+func NewPriceRepository() *PriceRepository {
+	return &PriceRepository{}
+}
+
+// FindHistory returns the price history of the product, newest first.
+func (r *PriceRepository) FindHistory(ctx context.Context, productID uuid.UUID) ([]models.PriceHistory, error) {
+	ctx, span := startPostgresSpan(ctx, "price_histories", "FindHistory", attribute.String("product.id", productID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	var history []models.PriceHistory
+	err = db.Where("product_id = ?", productID).
+		Order("effective_from DESC, currency, market").
+		Find(&history).Error
+	return history, endSpan(span, err)
+}
+
+// FindScheduled returns the pending price changes of the product, soonest
+// first.
+func (r *PriceRepository) FindScheduled(ctx context.Context, productID uuid.UUID) ([]models.ScheduledPriceChange, error) {
+	ctx, span := startPostgresSpan(ctx, "scheduled_price_changes", "FindScheduled", attribute.String("product.id", productID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	var changes []models.ScheduledPriceChange
+	err = db.Where("product_id = ? AND status = ?", productID, models.ScheduledChangePending).
+		Order("effective_at").
+		Find(&changes).Error
+	return changes, endSpan(span, err)
+}
+
+// Schedule stores a pending price change for change.ProductID. It fails
+// with gorm.ErrRecordNotFound when the tenant has no such product.
+func (r *PriceRepository) Schedule(ctx context.Context, change *models.ScheduledPriceChange) error {
+	ctx, span := startPostgresSpan(ctx, "scheduled_price_changes", "Schedule", attribute.String("product.id", change.ProductID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+	change.TenantID = tenantID
+	change.Status = models.ScheduledChangePending
+	change.Actor = audit.Actor(ctx)
+	change.AppliedAt = nil
+
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := productExists(tx, tenantID, change.ProductID); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Create(change).Error
+	})
+	return endSpan(span, err)
+}
+
+// Cancel cancels a pending price change of the product. It fails with
+// ErrScheduledChangeNotFound or, once the change was applied or cancelled,
+// ErrScheduledChangeClosed.
+func (r *PriceRepository) Cancel(ctx context.Context, productID, id uuid.UUID) (models.ScheduledPriceChange, error) {
+	ctx, span := startPostgresSpan(ctx, "scheduled_price_changes", "Cancel", attribute.String("scheduled_change.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return models.ScheduledPriceChange{}, endSpan(span, err)
+	}
+	var change models.ScheduledPriceChange
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ? AND id = ?", productID, id).
+			Take(&change).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrScheduledChangeNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if change.Status != models.ScheduledChangePending {
+			return ErrScheduledChangeClosed
+		}
+		change.Status = models.ScheduledChangeCancelled
+		return tx.Model(&change).Update("status", change.Status).Error
+	})
+	return change, endSpan(span, err)
+}
+
+// ApplyDue applies up to limit pending price changes of any tenant whose
+// effective time has passed, and returns them. Like ExpireReservations it
+// is unscoped and meant for the background scheduler only; changes locked
+// by a concurrent run are skipped.
+func (r *PriceRepository) ApplyDue(ctx context.Context, limit int) ([]models.ScheduledPriceChange, error) {
+	ctx, span := startPostgresSpan(ctx, "scheduled_price_changes", "ApplyDue")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	var changes []models.ScheduledPriceChange
+	err := config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND effective_at <= ?", models.ScheduledChangePending, time.Now()).
+			Order("effective_at").
+			Limit(limit).
+			Find(&changes).Error
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		// The products are locked before their prices are read, so an
+		// update cannot change them between the diff and the commit. They
+		// are locked in ID order, as two runs may share products.
+		productIDs := make([]uuid.UUID, 0, len(changes))
+		for id := range uniqueIDs(changeProductIDs(changes)) {
+			productIDs = append(productIDs, id)
+		}
+		var locked []uuid.UUID
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Model(&models.Product{}).
+			Where("id IN ?", productIDs).
+			Order("id").
+			Pluck("id", &locked).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range changes {
+			if err := applyScheduledChange(tx, &changes[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return changes, endSpan(span, err)
+}
+
+func changeProductIDs(changes []models.ScheduledPriceChange) []uuid.UUID {
+	ids := make([]uuid.UUID, len(changes))
+	for i, change := range changes {
+		ids[i] = change.ProductID
+	}
+	return ids
+}
+
+// FindUnpublished returns up to limit applied price changes of any tenant
+// whose event has not been published yet, oldest first. Like ApplyDue it is
+// unscoped and meant for the background scheduler only.
+func (r *PriceRepository) FindUnpublished(ctx context.Context, limit int) ([]models.ScheduledPriceChange, error) {
+	ctx, span := startPostgresSpan(ctx, "scheduled_price_changes", "FindUnpublished")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	var changes []models.ScheduledPriceChange
+	err := config.DB.WithContext(ctx).
+		Where("status = ? AND published_at IS NULL", models.ScheduledChangeApplied).
+		Order("applied_at").
+		Limit(limit).
+		Find(&changes).Error
+	return changes, endSpan(span, err)
+}
+
+// MarkPublished records that the events of the given applied price changes
+// were published.
+func (r *PriceRepository) MarkPublished(ctx context.Context, ids []uuid.UUID) error {
+	ctx, span := startPostgresSpan(ctx, "scheduled_price_changes", "MarkPublished")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	err := config.DB.WithContext(ctx).
+		Model(&models.ScheduledPriceChange{}).
+		Where("id IN ?", ids).
+		Update("published_at", time.Now()).Error
+	return endSpan(span, err)
+}
+
+// applyScheduledChange merges the prices of a locked pending change into
+// the prices of its locked product and marks it applied.
+func applyScheduledChange(tx *gorm.DB, change *models.ScheduledPriceChange) error {
+	var current []models.ProductPrice
+	if err := tx.Where("product_id = ?", change.ProductID).Find(&current).Error; err != nil {
+		return err
+	}
+
+	prices := make([]models.ProductPrice, len(change.Prices))
+	for i, price := range change.Prices {
+		prices[i] = models.ProductPrice{ProductID: change.ProductID, Currency: price.Currency, Market: price.Market, Amount: price.Amount}
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "currency"}, {Name: "market"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount"}),
+	}).Create(&prices).Error
+	if err != nil {
+		return err
+	}
+
+	merged := make(map[priceKey]models.ProductPrice, len(current)+len(prices))
+	for _, price := range current {
+		merged[keyOf(price)] = price
+	}
+	for _, price := range prices {
+		merged[keyOf(price)] = price
+	}
+	next := make([]models.ProductPrice, 0, len(merged))
+	for _, price := range merged {
+		next = append(next, price)
+	}
+	source := priceSource{actor: change.Actor, scheduledChangeID: &change.ID}
+	if err := recordPriceChanges(tx, change.TenantID, change.ProductID, current, next, source); err != nil {
+		return err
+	}
+
+	err = tx.Model(&models.Product{}).Where("id = ?", change.ProductID).Update("updated_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	change.Status = models.ScheduledChangeApplied
+	change.AppliedAt = &now
+	return tx.Model(change).Select("status", "applied_at").Updates(change).Error
+}
+
+// priceSource says who changed a price and, for scheduled changes, which
+// change it was.
+type priceSource struct {
+	actor             string
+	scheduledChangeID *uuid.UUID
+}
+
+type priceKey struct {
+	currency string
+	market   string
+}
+
+func keyOf(price models.ProductPrice) priceKey {
+	return priceKey{price.Currency, price.Market}
+}
+
+// recordPriceChanges writes the price history for a product whose prices
+// went from old to next: the open history row of each price that was
+// removed or changed is closed, and a row is opened for each price that
+// was added or changed. Unchanged prices keep their open row.
+func recordPriceChanges(tx *gorm.DB, tenantID string, productID uuid.UUID, old, next []models.ProductPrice, source priceSource) error {
+	now := time.Now()
+	previous := make(map[priceKey]int64, len(old))
+	for _, price := range old {
+		previous[keyOf(price)] = price.Amount
+	}
+
+	var opened []models.PriceHistory
+	for _, price := range next {
+		key := keyOf(price)
+		amount, existed := previous[key]
+		delete(previous, key)
+		if existed && amount == price.Amount {
+			continue
+		}
+		if existed {
+			if err := closePriceHistory(tx, productID, key, now); err != nil {
+				return err
+			}
+		}
+		opened = append(opened, models.PriceHistory{
+			TenantID:          tenantID,
+			ProductID:         productID,
+			Currency:          price.Currency,
+			Market:            price.Market,
+			Amount:            price.Amount,
+			EffectiveFrom:     now,
+			Actor:             source.actor,
+			ScheduledChangeID: source.scheduledChangeID,
+		})
+	}
+	for key := range previous {
+		if err := closePriceHistory(tx, productID, key, now); err != nil {
+			return err
+		}
+	}
+
+	if len(opened) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).Create(&opened).Error
+}
+
+func closePriceHistory(tx *gorm.DB, productID uuid.UUID, key priceKey, at time.Time) error {
+	return tx.Model(&models.PriceHistory{}).
+		Where("product_id = ? AND currency = ? AND market = ? AND effective_to IS NULL", productID, key.currency, key.market).
+		Update("effective_to", at).Error
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-product-api/internal/testutil"
+	"go-product-api/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestPriceRepositoryApplyDueLocksProductsBeforeReadingPrices(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	productID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM "scheduled_price_changes" .* FOR UPDATE SKIP LOCKED`).
+		WithArgs(models.ScheduledChangePending, sqlmock.AnyArg(), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "product_id", "prices", "effective_at", "status"}).
+			AddRow(uuid.New(), "tenant-a", productID, `[{"currency":"USD","amount":100}]`, time.Now(), models.ScheduledChangePending))
+	mock.ExpectQuery(`FROM "products" .* FOR UPDATE`).
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(productID))
+	mock.ExpectQuery(`FROM "product_prices"`).
+		WithArgs(productID).
+		WillReturnError(testutil.ErrStop)
+	mock.ExpectRollback()
+
+	if _, err := NewPriceRepository().ApplyDue(context.Background(), 10); !errors.Is(err, testutil.ErrStop) {
+		t.Fatalf("got error %v, want %v", err, testutil.ErrStop)
+	}
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-product-api/config"
+	"go-product-api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm/clause"
+)
+
+// ErrEventConflict is returned by Append when another event is already
+// stored at the position of the event, which happens when the product topic
+// was recreated without raising KAFKA_PRODUCT_TOPIC_EPOCH.
+var ErrEventConflict = errors.New("another event is stored at this position")
+
+// ProductEventRepository stores the product events consumed from the
+// product topic, so the state of a product can be replayed up to any point
+// in time.
+type ProductEventRepository struct{}
+
+func NewProductEventRepository() *ProductEventRepository {
+	return &ProductEventRepository{}
+}
+
+// Append stores an event. Storing an event twice, as happens when a message
+// is redelivered, keeps the first copy; a different event stored at the same
+// position fails with ErrEventConflict.
+func (r *ProductEventRepository) Append(ctx context.Context, event *models.StoredProductEvent) error {
+	ctx, span := startPostgresSpan(ctx, "stored_product_events", "Append", attribute.String("product.id", event.ProductID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db := config.DB.WithContext(ctx)
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return endSpan(span, result.Error)
+	}
+
+	var stored models.StoredProductEvent
+	err := db.Take(&stored, `topic = ? AND epoch = ? AND "partition" = ? AND "offset" = ?`,
+		event.Topic, event.Epoch, event.Partition, event.Offset).Error
+	if err != nil {
+		return endSpan(span, err)
+	}
+	// Postgres keeps microseconds, so compare the times at that precision.
+	if stored.ProductID != event.ProductID || stored.Type != event.Type ||
+		stored.OccurredAt.Sub(event.OccurredAt).Abs() >= time.Microsecond {
+		err = fmt.Errorf("%w: %s epoch %d partition %d offset %d holds %s of product %s",
+			ErrEventConflict, event.Topic, event.Epoch, event.Partition, event.Offset, stored.Type, stored.ProductID)
+	}
+	return endSpan(span, err)
+}
+
+// FindByProduct returns the events of the product that occurred no later
+// than until, oldest first.
+func (r *ProductEventRepository) FindByProduct(ctx context.Context, id uuid.UUID, until time.Time) ([]models.StoredProductEvent, error) {
+	ctx, span := startPostgresSpan(ctx, "stored_product_events", "FindByProduct", attribute.String("product.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	var events []models.StoredProductEvent
+	err = db.Where("product_id = ? AND occurred_at <= ?", id, until).
+		Order(`epoch, "partition", "offset"`).
+		Find(&events).Error
+	return events, endSpan(span, err)
+}
+
+// FindAfter returns up to limit events of every tenant stored from topic,
+// in the order they were consumed: by epoch, then partition and offset.
+// It starts after the position of after, or at the first event when after
+// is nil, so the store can be read in pages.
+func (r *ProductEventRepository) FindAfter(ctx context.Context, topic string, after *models.StoredProductEvent, limit int) ([]models.StoredProductEvent, error) {
+	ctx, span := startPostgresSpan(ctx, "stored_product_events", "FindAfter")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db := config.DB.WithContext(ctx).Where("topic = ?", topic)
+	if after != nil {
+		db = db.Where(`(epoch, "partition", "offset") > (?, ?, ?)`, after.Epoch, after.Partition, after.Offset)
+	}
+	var events []models.StoredProductEvent
+	err := db.Order(`epoch, "partition", "offset"`).Limit(limit).Find(&events).Error
+	return events, endSpan(span, err)
+}
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-product-api/internal/testutil"
+	"go-product-api/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestProductEventRepositoryAppendAtStoredPosition(t *testing.T) {
+	occurredAt := time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	event := models.StoredProductEvent{
+		Topic: "product_events", Epoch: 0, Partition: 1, Offset: 42,
+		TenantID: "tenant-a", ProductID: uuid.New(), Type: "product_updated", OccurredAt: occurredAt,
+	}
+	tests := []struct {
+		name      string
+		productID uuid.UUID
+		want      error
+	}{
+		{"redelivery", event.ProductID, nil},
+		{"another event", uuid.New(), ErrEventConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := testutil.MockDatabase(t)
+			mock.ExpectBegin()
+			mock.ExpectExec(`INSERT INTO "stored_product_events"`).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			mock.ExpectQuery(`FROM "stored_product_events"`).
+				WithArgs("product_events", 0, 1, 42, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"topic", "epoch", "partition", "offset", "product_id", "type", "occurred_at"}).
+					AddRow("product_events", 0, 1, 42, tt.productID, "product_updated", occurredAt.Truncate(time.Microsecond)))
+
+			appended := event
+			err := NewProductEventRepository().Append(context.Background(), &appended)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import "github.com/google/uuid"
+
+// ProductFilter narrows and orders product lists by price. Price bounds and
+// sorting apply to prices in Currency and are ignored without it.
+// CategoryPath keeps products filed under that category or any descendant
+// and InStock keeps products with or without available stock. Options keeps
+// products with a variant that has all of the given option values. Query
+// keeps products whose name, SKU, description or tags match the text. IDs,
+// when not empty, keeps only the products with those IDs.
+//
+// Limit and Offset page through the results, which are ordered by ID after
+// any price sort or relevance; a zero Limit returns all products from
+// Postgres and the first 100 from Elasticsearch.
+type ProductFilter struct {
+	Query        string
+	IDs          []uuid.UUID
+	Currency     string
+	MinPrice     *int64
+	MaxPrice     *int64
+	Sort         string
+	CategoryPath string
+	InStock      *bool
+	Options      map[string]string
+	Limit        int
+	Offset       int
+}
+
+const (
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+)
+
+const defaultSearchSize = 100
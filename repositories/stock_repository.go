@@ -0,0 +1,310 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-product-api/config"
+	"go-product-api/models"
+	"go-product-api/tenant"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInsufficientStock   = errors.New("insufficient stock")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationClosed   = errors.New("reservation is no longer pending")
+	ErrReservationExpired  = errors.New("reservation has expired")
+)
+
+// StockRepository keeps stock levels and reservations of the tenant on the
+// context. Every change locks the stock row it touches, so concurrent
+// reservations cannot take more than is available.
+type StockRepository struct{}
+
+func NewStockRepository() *StockRepository {
+	return &StockRepository{}
+}
+
+// FindByProduct returns the product's stock in every warehouse it has been
+// stocked in. It returns gorm.ErrRecordNotFound when the product does not
+// exist.
+func (r *StockRepository) FindByProduct(ctx context.Context, productID uuid.UUID) ([]models.StockLevel, error) {
+	ctx, span := startPostgresSpan(ctx, "stock_levels", "FindByProduct", attribute.String("product.id", productID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	db := config.DB.WithContext(ctx)
+	if err := productExists(db, tenantID, productID); err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	var levels []models.StockLevel
+	result := db.Where("tenant_id = ? AND product_id = ?", tenantID, productID).Order("warehouse").Find(&levels)
+	return levels, endSpan(span, result.Error)
+}
+
+// InStock reports whether any warehouse has stock of the product available
+// or any of its variants has stock on hand.
+func (r *StockRepository) InStock(ctx context.Context, productID uuid.UUID) (bool, error) {
+	ctx, span := startPostgresSpan(ctx, "stock_levels", "InStock", attribute.String("product.id", productID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	var inStock bool
+	result := config.DB.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM products WHERE id = ? AND ("+inStockCondition+"))", productID).
+		Scan(&inStock)
+	return inStock, endSpan(span, result.Error)
+}
+
+// Adjust changes the stock on hand by delta. Taking out units that are
+// reserved fails with ErrInsufficientStock.
+func (r *StockRepository) Adjust(ctx context.Context, productID uuid.UUID, warehouse string, delta int64) (models.StockLevel, error) {
+	ctx, span := startPostgresSpan(ctx, "stock_levels", "Adjust",
+		attribute.String("product.id", productID.String()),
+		attribute.String("stock.warehouse", warehouse),
+	)
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.StockLevel{}, endSpan(span, err)
+	}
+
+	var level models.StockLevel
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := productExists(tx, tenantID, productID); err != nil {
+			return err
+		}
+		if level, err = lockStock(tx, tenantID, productID, warehouse); err != nil {
+			return err
+		}
+		if level.OnHand+delta < level.Reserved {
+			return ErrInsufficientStock
+		}
+		level.OnHand += delta
+		return saveStock(tx, &level)
+	})
+	return level, endSpan(span, err)
+}
+
+// Reserve holds quantity units in the warehouse until expiresAt, failing
+// with ErrInsufficientStock when fewer are available.
+func (r *StockRepository) Reserve(ctx context.Context, productID uuid.UUID, warehouse string, quantity int64, expiresAt time.Time) (models.Reservation, models.StockLevel, error) {
+	ctx, span := startPostgresSpan(ctx, "reservations", "Reserve",
+		attribute.String("product.id", productID.String()),
+		attribute.String("stock.warehouse", warehouse),
+	)
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Reservation{}, models.StockLevel{}, endSpan(span, err)
+	}
+
+	reservation := models.Reservation{
+		TenantID:  tenantID,
+		ProductID: productID,
+		Warehouse: warehouse,
+		Quantity:  quantity,
+		Status:    models.ReservationPending,
+		ExpiresAt: expiresAt,
+	}
+	var level models.StockLevel
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := productExists(tx, tenantID, productID); err != nil {
+			return err
+		}
+		if level, err = lockStock(tx, tenantID, productID, warehouse); err != nil {
+			return err
+		}
+		if level.Available < quantity {
+			return ErrInsufficientStock
+		}
+		level.Reserved += quantity
+		if err := saveStock(tx, &level); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Create(&reservation).Error
+	})
+	return reservation, level, endSpan(span, err)
+}
+
+func (r *StockRepository) FindReservation(ctx context.Context, id uuid.UUID) (models.Reservation, error) {
+	ctx, span := startPostgresSpan(ctx, "reservations", "FindReservation", attribute.String("reservation.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return models.Reservation{}, endSpan(span, err)
+	}
+
+	var reservation models.Reservation
+	err = db.First(&reservation, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrReservationNotFound
+	}
+	return reservation, endSpan(span, err)
+}
+
+// Commit takes the reserved units out of stock. A pending reservation past
+// its expiry is expired instead and ErrReservationExpired is returned along
+// with it.
+func (r *StockRepository) Commit(ctx context.Context, id uuid.UUID) (models.Reservation, models.StockLevel, error) {
+	return r.close(ctx, "Commit", id, models.ReservationCommitted)
+}
+
+// Release returns the reserved units to the available stock.
+func (r *StockRepository) Release(ctx context.Context, id uuid.UUID) (models.Reservation, models.StockLevel, error) {
+	return r.close(ctx, "Release", id, models.ReservationReleased)
+}
+
+func (r *StockRepository) close(ctx context.Context, operation string, id uuid.UUID, status string) (models.Reservation, models.StockLevel, error) {
+	ctx, span := startPostgresSpan(ctx, "reservations", operation, attribute.String("reservation.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Reservation{}, models.StockLevel{}, endSpan(span, err)
+	}
+
+	var reservation models.Reservation
+	var level models.StockLevel
+	expired := false
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&reservation, "id = ? AND tenant_id = ?", id, tenantID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if reservation.Status != models.ReservationPending {
+			return ErrReservationClosed
+		}
+
+		if status == models.ReservationCommitted && !reservation.ExpiresAt.After(time.Now()) {
+			status, expired = models.ReservationExpired, true
+		}
+		level, err = closeReservation(tx, &reservation, status)
+		return err
+	})
+	if err == nil && expired {
+		err = ErrReservationExpired
+	}
+	return reservation, level, endSpan(span, err)
+}
+
+// ExpiredReservation is a reservation released by ExpireReservations
+// together with the stock level it left behind.
+type ExpiredReservation struct {
+	Reservation models.Reservation
+	Level       models.StockLevel
+}
+
+// ExpireReservations releases up to limit pending reservations of any
+// tenant that are past their expiry. Like FindTenantIDs it is unscoped and
+// meant for the background sweeper only. Reservations locked by a
+// concurrent commit or release are skipped.
+func (r *StockRepository) ExpireReservations(ctx context.Context, limit int) ([]ExpiredReservation, error) {
+	ctx, span := startPostgresSpan(ctx, "reservations", "ExpireReservations")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	var expired []ExpiredReservation
+	err := config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reservations []models.Reservation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expires_at <= ?", models.ReservationPending, time.Now()).
+			Order("expires_at").
+			Limit(limit).
+			Find(&reservations).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range reservations {
+			level, err := closeReservation(tx, &reservations[i], models.ReservationExpired)
+			if err != nil {
+				return err
+			}
+			expired = append(expired, ExpiredReservation{Reservation: reservations[i], Level: level})
+		}
+		return nil
+	})
+	return expired, endSpan(span, err)
+}
+
+// closeReservation moves a locked pending reservation to status and gives
+// back or takes out its units.
+func closeReservation(tx *gorm.DB, reservation *models.Reservation, status string) (models.StockLevel, error) {
+	level, err := lockStock(tx, reservation.TenantID, reservation.ProductID, reservation.Warehouse)
+	if err != nil {
+		return models.StockLevel{}, err
+	}
+	level.Reserved -= reservation.Quantity
+	if status == models.ReservationCommitted {
+		level.OnHand -= reservation.Quantity
+	}
+	if err := saveStock(tx, &level); err != nil {
+		return models.StockLevel{}, err
+	}
+
+	reservation.Status = status
+	err = tx.Model(reservation).Omit(clause.Associations).Select("status", "updated_at").Updates(reservation).Error
+	return level, err
+}
+
+// lockStock returns the stock row of the product in the warehouse locked
+// for update, creating an empty one first if there is none.
+func lockStock(tx *gorm.DB, tenantID string, productID uuid.UUID, warehouse string) (models.StockLevel, error) {
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations).Create(&models.StockLevel{
+		ProductID: productID,
+		Warehouse: warehouse,
+		TenantID:  tenantID,
+	}).Error
+	if err != nil {
+		return models.StockLevel{}, err
+	}
+
+	var level models.StockLevel
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&level, "product_id = ? AND warehouse = ?", productID, warehouse).Error
+	return level, err
+}
+
+func saveStock(tx *gorm.DB, level *models.StockLevel) error {
+	level.Available = level.OnHand - level.Reserved
+	return tx.Model(level).Omit(clause.Associations).Select("on_hand", "reserved", "updated_at").Updates(level).Error
+}
+
+func productExists(db *gorm.DB, tenantID string, productID uuid.UUID) error {
+	var count int64
+	err := db.Model(&models.Product{}).Where("id = ? AND tenant_id = ?", productID, tenantID).Count(&count).Error
+	if err == nil && count == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-product-api/internal/testutil"
+	"go-product-api/models"
+	"go-product-api/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var stockColumns = []string{"product_id", "warehouse", "tenant_id", "on_hand", "reserved"}
+
+// expectLockedStock expects the stock row of the product to be created if
+// missing and locked, and returns it with the given quantities.
+func expectLockedStock(mock sqlmock.Sqlmock, productID uuid.UUID, onHand, reserved int64) {
+	mock.ExpectExec(`INSERT INTO "stock_levels" .* ON CONFLICT DO NOTHING`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM "stock_levels" .* FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows(stockColumns).AddRow(productID, "main", "tenant-a", onHand, reserved))
+}
+
+func TestStockRepositoryReserveBeyondAvailableStock(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	productID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "products"`).
+		WithArgs(productID, "tenant-a").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	// Five on hand with three held leaves two to reserve; nothing is written.
+	expectLockedStock(mock, productID, 5, 3)
+	mock.ExpectRollback()
+
+	ctx := tenant.WithTenant(context.Background(), "tenant-a")
+	_, _, err := NewStockRepository().Reserve(ctx, productID, "main", 3, time.Now().Add(time.Minute))
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("got error %v, want %v", err, ErrInsufficientStock)
+	}
+}
+
+func TestStockRepositoryCommitPastExpiryReleasesTheUnits(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	productID, id := uuid.New(), uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM "reservations" .* FOR UPDATE`).
+		WithArgs(id, "tenant-a", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "product_id", "warehouse", "quantity", "status", "expires_at"}).
+			AddRow(id, "tenant-a", productID, "main", 2, models.ReservationPending, time.Now().Add(-time.Second)))
+	expectLockedStock(mock, productID, 5, 2)
+	// The units go back to the available stock instead of out of it.
+	mock.ExpectExec(`UPDATE "stock_levels"`).
+		WithArgs(5, 0, sqlmock.AnyArg(), productID, "main").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "reservations"`).
+		WithArgs(models.ReservationExpired, sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ctx := tenant.WithTenant(context.Background(), "tenant-a")
+	reservation, level, err := NewStockRepository().Commit(ctx, id)
+	if !errors.Is(err, ErrReservationExpired) {
+		t.Fatalf("got error %v, want %v", err, ErrReservationExpired)
+	}
+	if reservation.Status != models.ReservationExpired || level.OnHand != 5 || level.Available != 5 {
+		t.Fatalf("got reservation %s with %d on hand, %d available; want expired with all 5 available",
+			reservation.Status, level.OnHand, level.Available)
+	}
+}
@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-product-api/config"
+	"go-product-api/models"
+	"go-product-api/tenant"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrVariantNotFound = errors.New("variant not found")
+
+// VariantRepository manages the variants of products of the tenant on the
+// context. Lookups of a product that does not exist return
+// gorm.ErrRecordNotFound and of a variant ErrVariantNotFound.
+type VariantRepository struct{}
+
+func NewVariantRepository() *VariantRepository {
+	return &VariantRepository{}
+}
+
+func (r *VariantRepository) FindByProduct(ctx context.Context, productID uuid.UUID) ([]models.ProductVariant, error) {
+	ctx, span := startPostgresSpan(ctx, "product_variants", "FindByProduct", attribute.String("product.id", productID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	db := config.DB.WithContext(ctx)
+	if err := productExists(db, tenantID, productID); err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	variants := []models.ProductVariant{}
+	result := db.Preload("Prices").Where("product_id = ? AND tenant_id = ?", productID, tenantID).Order("created_at").Find(&variants)
+	return variants, endSpan(span, result.Error)
+}
+
+func (r *VariantRepository) FindByID(ctx context.Context, productID, id uuid.UUID) (models.ProductVariant, error) {
+	ctx, span := startPostgresSpan(ctx, "product_variants", "FindByID", attribute.String("variant.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return models.ProductVariant{}, endSpan(span, err)
+	}
+
+	var variant models.ProductVariant
+	err = db.Preload("Prices").First(&variant, "id = ? AND product_id = ?", id, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrVariantNotFound
+	}
+	return variant, endSpan(span, err)
+}
+
+func (r *VariantRepository) Create(ctx context.Context, variant *models.ProductVariant) error {
+	ctx, span := startPostgresSpan(ctx, "product_variants", "Create", attribute.String("product.id", variant.ProductID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+	variant.TenantID = tenantID
+
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := productExists(tx, tenantID, variant.ProductID); err != nil {
+			return err
+		}
+		return tx.Create(variant).Error
+	})
+	return endSpan(span, err)
+}
+
+// Update saves the SKU, options, stock and prices of the variant.
+func (r *VariantRepository) Update(ctx context.Context, variant *models.ProductVariant) error {
+	ctx, span := startPostgresSpan(ctx, "product_variants", "Update", attribute.String("variant.id", variant.ID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+	variant.TenantID = tenantID
+	variant.OptionsKey = variant.Options.Key()
+
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(variant).
+			Where("product_id = ? AND tenant_id = ?", variant.ProductID, tenantID).
+			Select("sku", "options", "options_key", "stock", "updated_at").
+			Omit(clause.Associations).
+			Updates(variant)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVariantNotFound
+		}
+
+		if err := tx.Where("variant_id = ?", variant.ID).Delete(&models.VariantPrice{}).Error; err != nil {
+			return err
+		}
+		if len(variant.Prices) == 0 {
+			return nil
+		}
+		for i := range variant.Prices {
+			variant.Prices[i].ID = uuid.Nil
+			variant.Prices[i].VariantID = variant.ID
+		}
+		return tx.Create(&variant.Prices).Error
+	})
+	return endSpan(span, err)
+}
+
+func (r *VariantRepository) Delete(ctx context.Context, productID, id uuid.UUID) error {
+	ctx, span := startPostgresSpan(ctx, "product_variants", "Delete", attribute.String("variant.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+
+	result := db.Delete(&models.ProductVariant{}, "id = ? AND product_id = ?", id, productID)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVariantNotFound
+	}
+	return endSpan(span, result.Error)
+}
@@ -0,0 +1,307 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-product-api/config"
+	"go-product-api/models"
+	"go-product-api/tenant"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+// WebhookRepository manages the webhook subscriptions of the tenant on the
+// context and their deliveries. Lookups of a subscription that does not
+// exist return gorm.ErrRecordNotFound and of a delivery
+// ErrWebhookDeliveryNotFound.
+type WebhookRepository struct{}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{}
+}
+
+func (r *WebhookRepository) FindAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	ctx, span := startPostgresSpan(ctx, "webhook_subscriptions", "FindAll")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	subscriptions := []models.WebhookSubscription{}
+	err = db.Order("created_at").Find(&subscriptions).Error
+	return subscriptions, endSpan(span, err)
+}
+
+func (r *WebhookRepository) FindByID(ctx context.Context, id uuid.UUID) (models.WebhookSubscription, error) {
+	ctx, span := startPostgresSpan(ctx, "webhook_subscriptions", "FindByID", attribute.String("webhook.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return models.WebhookSubscription{}, endSpan(span, err)
+	}
+	var subscription models.WebhookSubscription
+	err = db.First(&subscription, "id = ?", id).Error
+	return subscription, endSpan(span, err)
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, subscription *models.WebhookSubscription) error {
+	ctx, span := startPostgresSpan(ctx, "webhook_subscriptions", "Create")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+	subscription.TenantID = tenantID
+	return endSpan(span, config.DB.WithContext(ctx).Create(subscription).Error)
+}
+
+// Update saves the settings of a subscription: URL, event types, secret and
+// whether it is active, together with its failure count and disabled time.
+func (r *WebhookRepository) Update(ctx context.Context, subscription *models.WebhookSubscription) error {
+	ctx, span := startPostgresSpan(ctx, "webhook_subscriptions", "Update", attribute.String("webhook.id", subscription.ID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+	result := db.Model(subscription).
+		Select("url", "event_types", "secret", "active", "consecutive_failures", "disabled_at", "updated_at").
+		Updates(subscription)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return endSpan(span, gorm.ErrRecordNotFound)
+	}
+	return endSpan(span, result.Error)
+}
+
+// Delete deletes a subscription together with its delivery log.
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startPostgresSpan(ctx, "webhook_subscriptions", "Delete", attribute.String("webhook.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return endSpan(span, err)
+	}
+	result := db.Delete(&models.WebhookSubscription{}, "id = ?", id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return endSpan(span, gorm.ErrRecordNotFound)
+	}
+	return endSpan(span, result.Error)
+}
+
+// FindDeliveries returns up to limit of the latest deliveries of a
+// subscription, newest first.
+func (r *WebhookRepository) FindDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	ctx, span := startPostgresSpan(ctx, "webhook_deliveries", "FindDeliveries", attribute.String("webhook.id", subscriptionID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBQueryTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	if err := db.First(&models.WebhookSubscription{}, "id = ?", subscriptionID).Error; err != nil {
+		return nil, endSpan(span, err)
+	}
+	deliveries := []models.WebhookDelivery{}
+	err = db.Where("subscription_id = ?", subscriptionID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, endSpan(span, err)
+}
+
+// Redeliver queues a delivery again as a new delivery of the same event,
+// leaving the original in the log.
+func (r *WebhookRepository) Redeliver(ctx context.Context, subscriptionID, id uuid.UUID) (models.WebhookDelivery, error) {
+	ctx, span := startPostgresSpan(ctx, "webhook_deliveries", "Redeliver", attribute.String("webhook.delivery.id", id.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return models.WebhookDelivery{}, endSpan(span, err)
+	}
+	var original models.WebhookDelivery
+	err = db.First(&original, "id = ? AND subscription_id = ?", id, subscriptionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, endSpan(span, err)
+	}
+
+	delivery := models.WebhookDelivery{
+		TenantID:       original.TenantID,
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		RedeliveryOf:   &original.ID,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now(),
+	}
+	err = db.Omit(clause.Associations).Create(&delivery).Error
+	return delivery, endSpan(span, err)
+}
+
+// Enqueue queues an event for every active subscription of the tenant that
+// wants its type and returns how many deliveries were queued. Enqueueing an
+// event again, as happens when a message is redelivered, queues nothing.
+func (r *WebhookRepository) Enqueue(ctx context.Context, eventID, eventType string, payload json.RawMessage) (int, error) {
+	ctx, span := startPostgresSpan(ctx, "webhook_deliveries", "Enqueue", attribute.String("event.type", eventType))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	db, err := scoped(ctx)
+	if err != nil {
+		return 0, endSpan(span, err)
+	}
+	wanted, err := json.Marshal([]string{eventType})
+	if err != nil {
+		return 0, endSpan(span, err)
+	}
+	var subscriptions []models.WebhookSubscription
+	err = db.Where("active AND (event_types = '[]'::jsonb OR event_types @> ?::jsonb)", string(wanted)).
+		Find(&subscriptions).Error
+	if err != nil || len(subscriptions) == 0 {
+		return 0, endSpan(span, err)
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, len(subscriptions))
+	for i, subscription := range subscriptions {
+		deliveries[i] = models.WebhookDelivery{
+			TenantID:       subscription.TenantID,
+			SubscriptionID: subscription.ID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  now,
+		}
+	}
+	result := db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "redelivery_of IS NULL"}}},
+		DoNothing:   true,
+	}).Omit(clause.Associations).Create(&deliveries)
+	return int(result.RowsAffected), endSpan(span, result.Error)
+}
+
+// ClaimDue claims up to limit pending deliveries of any tenant that are due
+// and belong to active subscriptions, with their subscriptions. A claimed
+// delivery is not due again for lease, so a dispatcher that dies mid-send
+// only delays it. Like ExpireReservations it is unscoped and meant for the
+// background dispatcher only.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	ctx, span := startPostgresSpan(ctx, "webhook_deliveries", "ClaimDue")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	var deliveries []models.WebhookDelivery
+	err := config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+			Where("subscription_id IN (SELECT id FROM webhook_subscriptions WHERE active)").
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		err = tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(lease)).Error
+		if err != nil {
+			return err
+		}
+		return tx.Preload("Subscription").Where("id IN ?", ids).Order("next_attempt_at").Find(&deliveries).Error
+	})
+	return deliveries, endSpan(span, err)
+}
+
+// RecordAttempt stores the outcome of sending a claimed delivery. A failed
+// delivery is retried after retryAfter unless it has used up its attempts,
+// and counts against its subscription, which is disabled after
+// config.WebhookDisableAfter failures in a row. It reports whether the
+// subscription was disabled.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, statusCode int, sendErr error, retryAfter time.Duration) (bool, error) {
+	ctx, span := startPostgresSpan(ctx, "webhook_deliveries", "RecordAttempt", attribute.String("webhook.delivery.id", delivery.ID.String()))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, config.DBWriteTimeout)
+	defer cancel()
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= config.WebhookMaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.LastError = sendErr.Error()
+		delivery.NextAttemptAt = now.Add(retryAfter)
+	}
+
+	var disabled bool
+	err := config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(delivery).Omit(clause.Associations).
+			Select("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at", "updated_at").
+			Updates(delivery).Error
+		if err != nil {
+			return err
+		}
+
+		if sendErr == nil {
+			return tx.Model(&models.WebhookSubscription{}).Where("id = ?", delivery.SubscriptionID).
+				Update("consecutive_failures", 0).Error
+		}
+		var subscription models.WebhookSubscription
+		err = tx.Model(&subscription).Clauses(clause.Returning{}).
+			Where("id = ?", delivery.SubscriptionID).
+			UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+		if err != nil || subscription.ConsecutiveFailures < config.WebhookDisableAfter {
+			return err
+		}
+		result := tx.Model(&models.WebhookSubscription{}).
+			Where("id = ? AND active", delivery.SubscriptionID).
+			Updates(map[string]any{"active": false, "disabled_at": now})
+		disabled = result.RowsAffected > 0
+		return result.Error
+	})
+	return disabled, endSpan(span, err)
+}
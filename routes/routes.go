@@ -1,18 +1,111 @@
 package routes
 
 import (
+	"expvar"
+	"fmt"
+	"net/http"
+
+	"go-product-api/auth"
+	"go-product-api/config"
 	"go-product-api/controllers"
+	"go-product-api/middleware"
+	"go-product-api/problem"
+	"go-product-api/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
+// NewEngine returns the engine the API is served on. It believes forwarding
+// headers only from config.TrustedProxies, so callers cannot pick the client
+// IP that per-IP rate limits key on.
+func NewEngine() (*gin.Engine, error) {
+	router := gin.New()
+	if err := router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	return router, nil
+}
+
 func SetupRoutes(router *gin.Engine) {
-	productRoutes := router.Group("/products")
+	router.NoRoute(func(c *gin.Context) {
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeNotFound, "Route not found"))
+	})
+
+	ip := ratelimit.PerIP()
+	read := ratelimit.Middleware(ratelimit.ClassRead)
+	search := ratelimit.Middleware(ratelimit.ClassSearch)
+	write := ratelimit.Middleware(ratelimit.ClassWrite)
+
+	productRoutes := router.Group("/products", ip, auth.Authenticate(), middleware.Tenant())
+	{
+		productRoutes.GET("/", search, auth.RequireRole(auth.RoleReader), controllers.GetProducts)
+		productRoutes.GET("/stream", read, auth.RequireRole(auth.RoleReader), controllers.StreamProducts)
+		productRoutes.GET("/stream/ws", read, auth.RequireRole(auth.RoleReader), controllers.StreamProductsWebSocket)
+		productRoutes.GET("/sku/:sku", read, auth.RequireRole(auth.RoleReader), controllers.GetProductBySKU)
+		productRoutes.GET("/:id", read, auth.RequireRole(auth.RoleReader), controllers.GetProduct)
+		productRoutes.POST("/", write, auth.RequireRole(auth.RoleEditor), controllers.CreateProduct)
+		productRoutes.PUT("/:id", write, auth.RequireRole(auth.RoleEditor), controllers.UpdateProduct)
+		productRoutes.DELETE("/:id", write, auth.RequireRole(auth.RoleEditor), controllers.DeleteProduct)
+		productRoutes.GET("/:id/variants", read, auth.RequireRole(auth.RoleReader), controllers.GetProductVariants)
+		productRoutes.GET("/:id/variants/:variant_id", read, auth.RequireRole(auth.RoleReader), controllers.GetProductVariant)
+		productRoutes.POST("/:id/variants", write, auth.RequireRole(auth.RoleEditor), controllers.CreateProductVariant)
+		productRoutes.PUT("/:id/variants/:variant_id", write, auth.RequireRole(auth.RoleEditor), controllers.UpdateProductVariant)
+		productRoutes.DELETE("/:id/variants/:variant_id", write, auth.RequireRole(auth.RoleEditor), controllers.DeleteProductVariant)
+		productRoutes.GET("/:id/history", read, auth.RequireRole(auth.RoleReader), controllers.GetProductHistory)
+		productRoutes.GET("/:id/prices", read, auth.RequireRole(auth.RoleReader), controllers.GetProductPrices)
+		productRoutes.POST("/:id/prices/schedule", write, auth.RequireRole(auth.RoleEditor), controllers.ScheduleProductPriceChange)
+		productRoutes.DELETE("/:id/prices/schedule/:schedule_id", write, auth.RequireRole(auth.RoleEditor), controllers.CancelProductPriceChange)
+		productRoutes.GET("/:id/stock", read, auth.RequireRole(auth.RoleReader), controllers.GetProductStock)
+		productRoutes.POST("/:id/stock/adjustments", write, auth.RequireRole(auth.RoleEditor), controllers.AdjustProductStock)
+		productRoutes.POST("/:id/reservations", write, auth.RequireRole(auth.RoleEditor), controllers.ReserveProductStock)
+	}
+
+	reservationRoutes := router.Group("/reservations", ip, auth.Authenticate(), middleware.Tenant())
+	{
+		reservationRoutes.GET("/:id", read, auth.RequireRole(auth.RoleReader), controllers.GetReservation)
+		reservationRoutes.POST("/:id/commit", write, auth.RequireRole(auth.RoleEditor), controllers.CommitReservation)
+		reservationRoutes.POST("/:id/release", write, auth.RequireRole(auth.RoleEditor), controllers.ReleaseReservation)
+	}
+
+	categoryRoutes := router.Group("/categories", ip, auth.Authenticate(), middleware.Tenant())
 	{
-		productRoutes.GET("/", controllers.GetProducts)
-		productRoutes.GET("/:id", controllers.GetProduct)
-		productRoutes.POST("/", controllers.CreateProduct)
-		productRoutes.PUT("/:id", controllers.UpdateProduct)
-		productRoutes.DELETE("/:id", controllers.DeleteProduct)
+		categoryRoutes.GET("/", read, auth.RequireRole(auth.RoleReader), controllers.GetCategories)
+		categoryRoutes.GET("/:id", read, auth.RequireRole(auth.RoleReader), controllers.GetCategory)
+		categoryRoutes.GET("/:id/products", search, auth.RequireRole(auth.RoleReader), controllers.GetCategoryProducts)
+		categoryRoutes.POST("/", write, auth.RequireRole(auth.RoleEditor), controllers.CreateCategory)
+		categoryRoutes.PUT("/:id", write, auth.RequireRole(auth.RoleEditor), controllers.UpdateCategory)
+		categoryRoutes.POST("/:id/move", write, auth.RequireRole(auth.RoleEditor), controllers.MoveCategory)
+		categoryRoutes.DELETE("/:id", write, auth.RequireRole(auth.RoleEditor), controllers.DeleteCategory)
 	}
-}
\ No newline at end of file
+
+	exchangeRateRoutes := router.Group("/exchange-rates", ip, auth.Authenticate())
+	{
+		exchangeRateRoutes.GET("/", read, auth.RequireRole(auth.RoleReader), controllers.GetExchangeRates)
+		exchangeRateRoutes.PUT("/:base/:quote", write, auth.RequireRole(auth.RoleAdmin), controllers.PutExchangeRate)
+		exchangeRateRoutes.DELETE("/:base/:quote", write, auth.RequireRole(auth.RoleAdmin), controllers.DeleteExchangeRate)
+	}
+
+	webhookRoutes := router.Group("/webhooks", ip, auth.Authenticate(), middleware.Tenant(), auth.RequireRole(auth.RoleAdmin))
+	{
+		webhookRoutes.GET("/", read, controllers.GetWebhooks)
+		webhookRoutes.GET("/:id", read, controllers.GetWebhook)
+		webhookRoutes.POST("/", write, controllers.CreateWebhook)
+		webhookRoutes.PUT("/:id", write, controllers.UpdateWebhook)
+		webhookRoutes.DELETE("/:id", write, controllers.DeleteWebhook)
+		webhookRoutes.GET("/:id/deliveries", read, controllers.GetWebhookDeliveries)
+		webhookRoutes.POST("/:id/deliveries/:delivery_id/redeliver", write, controllers.RedeliverWebhook)
+	}
+
+	router.POST("/graphql", ip, auth.Authenticate(), middleware.Tenant(), search, auth.RequireRole(auth.RoleReader), controllers.GraphQL)
+
+	apiKeyRoutes := router.Group("/api-keys", ip, auth.Authenticate(), auth.RequireRole(auth.RoleAdmin))
+	{
+		apiKeyRoutes.GET("/", read, controllers.GetAPIKeys)
+		apiKeyRoutes.POST("/", write, controllers.CreateAPIKey)
+		apiKeyRoutes.DELETE("/:id", write, controllers.RevokeAPIKey)
+	}
+
+	// Process metrics include the command line and memory statistics as
+	// well as the cache counters, so they are for operators only.
+	router.GET("/debug/vars", ip, auth.Authenticate(), auth.RequireRole(auth.RoleAdmin), read, gin.WrapH(expvar.Handler()))
+}
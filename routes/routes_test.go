@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"go-product-api/config"
+	"go-product-api/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPerIPLimitIgnoresForgedForwardingHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previousRPS, previousBurst := config.RateLimitIPRPS, config.RateLimitIPBurst
+	config.RateLimitIPRPS, config.RateLimitIPBurst = 0.001, 2
+	t.Cleanup(func() { config.RateLimitIPRPS, config.RateLimitIPBurst = previousRPS, previousBurst })
+	ratelimit.Init()
+
+	router, err := NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.GET("/", ratelimit.PerIP(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	want := []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests}
+	for i, status := range want {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		// A fresh address on every request would get a fresh bucket if
+		// the headers were believed.
+		req.Header.Set("X-Forwarded-For", "198.51.100."+strconv.Itoa(i))
+		req.Header.Set("X-Real-IP", "198.51.100."+strconv.Itoa(i))
+		router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Fatalf("request %d: got status %d, want %d", i+1, w.Code, status)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidEventID = errors.New("invalid event ID")
+
+// Cursor is a position in the product topic: the offset of the last message
+// seen in each partition. Its string form, e.g. "0:125,1:88", is the ID of
+// every streamed event, so a client resuming with Last-Event-ID picks up
+// exactly where it left off.
+type Cursor map[int32]int64
+
+// ParseCursor parses the string form of a cursor.
+func ParseCursor(s string) (Cursor, error) {
+	cursor := Cursor{}
+	for _, part := range strings.Split(s, ",") {
+		p, o, found := strings.Cut(part, ":")
+		if !found {
+			return nil, ErrInvalidEventID
+		}
+		partition, err := strconv.ParseInt(p, 10, 32)
+		if err != nil || partition < 0 {
+			return nil, ErrInvalidEventID
+		}
+		offset, err := strconv.ParseInt(o, 10, 64)
+		if err != nil || offset < -1 {
+			return nil, ErrInvalidEventID
+		}
+		cursor[int32(partition)] = offset
+	}
+	return cursor, nil
+}
+
+func (c Cursor) String() string {
+	partitions := make([]int32, 0, len(c))
+	for partition := range c {
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	parts := make([]string, len(partitions))
+	for i, partition := range partitions {
+		parts[i] = fmt.Sprintf("%d:%d", partition, c[partition])
+	}
+	return strings.Join(parts, ",")
+}
+
+// offset returns the last offset seen in the partition, -1 for none.
+func (c Cursor) offset(partition int32) int64 {
+	if offset, ok := c[partition]; ok {
+		return offset
+	}
+	return -1
+}
+
+func (c Cursor) clone() Cursor {
+	clone := make(Cursor, len(c))
+	for partition, offset := range c {
+		clone[partition] = offset
+	}
+	return clone
+}
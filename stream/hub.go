@@ -0,0 +1,159 @@
+package stream
+
+import (
+	"errors"
+	"go-product-api/config"
+	"go-product-api/events"
+	"go-product-api/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrLagged ends the stream of a client that did not keep up.
+	ErrLagged = errors.New("client fell behind the stream")
+	// ErrShutdown ends every stream when the server shuts down.
+	ErrShutdown = errors.New("server is shutting down")
+	// ErrReplayBusy turns away a resume while config.StreamMaxReplays
+	// others are replaying.
+	ErrReplayBusy = errors.New("too many streams are resuming")
+)
+
+// Event is a product event read from the product topic.
+type Event struct {
+	Partition  int32
+	Offset     int64
+	Type       events.EventType
+	TenantID   string
+	Product    models.Product
+	OccurredAt time.Time
+}
+
+type subscriber struct {
+	tenantID string
+	events   chan Event
+	// dropped is closed, with reason set, when the hub stops sending.
+	dropped chan struct{}
+	reason  error
+}
+
+// hub fans the events read by this instance out to the subscribers of
+// their tenant. It never blocks on a subscriber: one whose buffer is full
+// is dropped with ErrLagged.
+type hub struct {
+	mu          sync.Mutex
+	position    Cursor
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+var defaultHub = &hub{position: Cursor{}, subscribers: map[*subscriber]struct{}{}}
+
+// subscribe registers a subscriber for the tenant's events and returns the
+// position of the last event published before it, which is the first one
+// it does not receive.
+func (h *hub) subscribe(tenantID string) (*subscriber, Cursor, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil, ErrShutdown
+	}
+	s := &subscriber{
+		tenantID: tenantID,
+		events:   make(chan Event, config.StreamBufferSize),
+		dropped:  make(chan struct{}),
+	}
+	h.subscribers[s] = struct{}{}
+	return s, h.position.clone(), nil
+}
+
+func (h *hub) unsubscribe(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, s)
+}
+
+// start sets the position the reader starts from.
+func (h *hub) start(position Cursor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.position = position
+}
+
+func (h *hub) publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.position[e.Partition] = e.Offset
+	for s := range h.subscribers {
+		if s.tenantID != e.TenantID {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			h.drop(s, ErrLagged)
+		}
+	}
+}
+
+// shutdown drops every subscriber and refuses new ones.
+func (h *hub) shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subscribers {
+		h.drop(s, ErrShutdown)
+	}
+}
+
+func (h *hub) drop(s *subscriber, reason error) {
+	delete(h.subscribers, s)
+	s.reason = reason
+	close(s.dropped)
+}
+
+// Shutdown ends every open stream, so the HTTP server can drain. It is
+// meant to be registered with http.Server.RegisterOnShutdown.
+func Shutdown() {
+	defaultHub.shutdown()
+}
+
+// Query filters a product change stream. LastEventID resumes after the
+// event with that ID.
+type Query struct {
+	ProductIDs  []string `form:"product_id" json:"product_id" binding:"max=100,dive,uuid"`
+	Types       []string `form:"type" json:"type" binding:"max=3,dive,oneof=product_created product_updated product_deleted"`
+	LastEventID string   `form:"last_event_id" json:"last_event_id" binding:"max=1024"`
+}
+
+// Filter returns the filter for the products and event types of a query
+// that passed its binding tags.
+func (q Query) Filter() Filter {
+	filter := Filter{ProductIDs: map[uuid.UUID]bool{}, Types: map[events.EventType]bool{}}
+	for _, id := range q.ProductIDs {
+		filter.ProductIDs[uuid.MustParse(id)] = true
+	}
+	for _, eventType := range q.Types {
+		filter.Types[events.EventType(eventType)] = true
+	}
+	return filter
+}
+
+// Filter narrows a stream down to some products or event types; an empty
+// set matches everything.
+type Filter struct {
+	ProductIDs map[uuid.UUID]bool
+	Types      map[events.EventType]bool
+}
+
+func (f Filter) match(e Event) bool {
+	if len(f.ProductIDs) > 0 && !f.ProductIDs[e.Product.ID] {
+		return false
+	}
+	if len(f.Types) > 0 && !f.Types[e.Type] {
+		return false
+	}
+	return true
+}
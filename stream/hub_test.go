@@ -0,0 +1,77 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-product-api/events"
+	"go-product-api/models"
+
+	"github.com/google/uuid"
+)
+
+func TestSessionOnlyReceivesItsTenantsEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sessionA, err := Open(ctx, "tenant-a", "", Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionB, err := Open(ctx, "tenant-b", "", Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	productA, productB := uuid.New(), uuid.New()
+	published := []Event{
+		{Partition: 0, Offset: 1, Type: events.ProductCreated, TenantID: "tenant-a", Product: models.Product{ID: productA, TenantID: "tenant-a"}},
+		{Partition: 0, Offset: 2, Type: events.ProductCreated, TenantID: "tenant-b", Product: models.Product{ID: productB, TenantID: "tenant-b"}},
+		{Partition: 0, Offset: 3, Type: events.ProductUpdated, TenantID: "tenant-a", Product: models.Product{ID: productA, TenantID: "tenant-a"}},
+	}
+	for _, e := range published {
+		defaultHub.publish(e)
+	}
+
+	expectMessages(t, "tenant-a", sessionA, productA, productA)
+	expectMessages(t, "tenant-b", sessionB, productB)
+}
+
+// expectMessages reads a message per product ID from the session and fails
+// if any is of another product, or if a further message arrives.
+func expectMessages(t *testing.T, tenantID string, s *Session, productIDs ...uuid.UUID) {
+	t.Helper()
+	for _, want := range productIDs {
+		select {
+		case msg := <-s.Messages():
+			if msg.ProductID != want || msg.Product.TenantID != tenantID {
+				t.Fatalf("%s: got product %s of %q, want %s", tenantID, msg.ProductID, msg.Product.TenantID, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: timed out waiting for product %s", tenantID, want)
+		}
+	}
+	select {
+	case msg := <-s.Messages():
+		t.Fatalf("%s: got unexpected product %s of %q", tenantID, msg.ProductID, msg.Product.TenantID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReplayIsTurnedAwayWhenAllSlotsAreTaken(t *testing.T) {
+	for range cap(replaySlots) {
+		replaySlots <- struct{}{}
+	}
+	t.Cleanup(func() {
+		for range cap(replaySlots) {
+			<-replaySlots
+		}
+	})
+
+	_, err := replay(context.Background(), "tenant-a", Cursor{0: 1}, Cursor{0: 5})
+	if !errors.Is(err, ErrReplayBusy) {
+		t.Fatalf("got error %v, want %v", err, ErrReplayBusy)
+	}
+}
@@ -0,0 +1,208 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-product-api/config"
+	"go-product-api/events"
+	"log/slog"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// metadataTimeout bounds the broker metadata and watermark queries, in
+// milliseconds.
+const metadataTimeout = 10000
+
+var errReplayTooFar = errors.New("resume position is too far behind")
+
+// replaySlots bounds how many replays, and so replay consumers, run at once.
+var replaySlots = make(chan struct{}, max(config.StreamMaxReplays, 1))
+
+// Start reads the product topic from its current end and fans the events
+// out to the open streams until ctx is cancelled, then ends them. Every
+// instance reads all partitions itself, outside the consumer group, so its
+// clients see every change whichever instance the consumer group assigned
+// the partitions to. The returned channel is closed once the reader has
+// stopped. If Kafka cannot be read, streams stay open but receive no
+// events.
+func Start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	consumer, err := openLive()
+	if err != nil {
+		slog.Error("Failed to start product stream reader", "error.message", err)
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		defer consumer.Close()
+		defer defaultHub.shutdown()
+		for ctx.Err() == nil {
+			msg, err := consumer.ReadMessage(100 * time.Millisecond)
+			if err != nil {
+				if kerr, ok := err.(kafka.Error); ok && kerr.Code() == kafka.ErrTimedOut {
+					continue
+				}
+				slog.Error("Product stream reader error", "error.message", err)
+				continue
+			}
+			event, err := decode(msg)
+			if err != nil {
+				slog.Warn("Skipping undecodable event", "kafka.partition", msg.TopicPartition.Partition, "kafka.offset", int64(msg.TopicPartition.Offset), "error.message", err)
+				continue
+			}
+			defaultHub.publish(event)
+		}
+		slog.Info("product stream reader stopped")
+	}()
+	slog.Info("product stream reader started", "kafka.topic", config.ProductTopic)
+	return done
+}
+
+// openLive assigns every partition of the product topic at its end and
+// records that as the position of the hub.
+func openLive() (*kafka.Consumer, error) {
+	consumer, err := config.NewReplayConsumer()
+	if err != nil {
+		return nil, fmt.Errorf("error creating consumer: %w", err)
+	}
+	low, high, err := watermarks(consumer)
+	if err != nil {
+		consumer.Close()
+		return nil, err
+	}
+
+	position := Cursor{}
+	var assignment []kafka.TopicPartition
+	for partition := range low {
+		position[partition] = high[partition] - 1
+		assignment = append(assignment, topicPartition(partition, high[partition]))
+	}
+	if err := consumer.Assign(assignment); err != nil {
+		consumer.Close()
+		return nil, fmt.Errorf("error assigning partitions: %w", err)
+	}
+	defaultHub.start(position)
+	return consumer, nil
+}
+
+// replay reads the tenant's events after from up to and including to from
+// the product topic. It fails with errReplayTooFar when that is more than
+// config.StreamReplayLimit messages or reaches back past what the topic
+// still retains, and with ErrReplayBusy when too many replays are running.
+func replay(ctx context.Context, tenantID string, from, to Cursor) ([]Event, error) {
+	select {
+	case replaySlots <- struct{}{}:
+		defer func() { <-replaySlots }()
+	default:
+		return nil, ErrReplayBusy
+	}
+
+	consumer, err := config.NewReplayConsumer()
+	if err != nil {
+		return nil, fmt.Errorf("error creating consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	low, _, err := watermarks(consumer)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	remaining := map[int32]int64{}
+	var assignment []kafka.TopicPartition
+	for partition, last := range to {
+		start := from.offset(partition) + 1
+		if start > last {
+			continue
+		}
+		if start < low[partition] {
+			return nil, errReplayTooFar
+		}
+		total += last - start + 1
+		remaining[partition] = last
+		assignment = append(assignment, topicPartition(partition, start))
+	}
+	if total > int64(config.StreamReplayLimit) {
+		return nil, errReplayTooFar
+	}
+	if len(assignment) == 0 {
+		return nil, nil
+	}
+	if err := consumer.Assign(assignment); err != nil {
+		return nil, fmt.Errorf("error assigning partitions: %w", err)
+	}
+
+	var replayed []Event
+	for len(remaining) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, err := consumer.ReadMessage(100 * time.Millisecond)
+		if err != nil {
+			if kerr, ok := err.(kafka.Error); ok && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			return nil, fmt.Errorf("error reading product topic: %w", err)
+		}
+		partition, offset := msg.TopicPartition.Partition, int64(msg.TopicPartition.Offset)
+		last, ok := remaining[partition]
+		if !ok || offset > last {
+			continue
+		}
+		if offset == last {
+			delete(remaining, partition)
+		}
+
+		event, err := decode(msg)
+		if err != nil || event.TenantID != tenantID {
+			continue
+		}
+		replayed = append(replayed, event)
+	}
+	return replayed, nil
+}
+
+// watermarks returns the first and one past the last offset of every
+// partition of the product topic.
+func watermarks(consumer *kafka.Consumer) (low, high map[int32]int64, err error) {
+	topic := config.ProductTopic
+	metadata, err := consumer.GetMetadata(&topic, false, metadataTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching topic metadata: %w", err)
+	}
+
+	low, high = map[int32]int64{}, map[int32]int64{}
+	for _, partition := range metadata.Topics[topic].Partitions {
+		l, h, err := consumer.QueryWatermarkOffsets(topic, partition.ID, metadataTimeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error fetching offsets of partition %d: %w", partition.ID, err)
+		}
+		low[partition.ID], high[partition.ID] = l, h
+	}
+	return low, high, nil
+}
+
+func topicPartition(partition int32, offset int64) kafka.TopicPartition {
+	return kafka.TopicPartition{Topic: &config.ProductTopic, Partition: partition, Offset: kafka.Offset(offset)}
+}
+
+func decode(msg *kafka.Message) (Event, error) {
+	event, err := events.DecodeProductEvent(msg)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		Partition:  msg.TopicPartition.Partition,
+		Offset:     int64(msg.TopicPartition.Offset),
+		Type:       event.Type,
+		TenantID:   event.TenantID,
+		Product:    event.Product,
+		OccurredAt: event.OccurredAt,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"go-product-api/events"
+	"go-product-api/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResetEvent tells a resuming client that the events it missed cannot be
+// replayed and it should reload the products instead.
+const ResetEvent events.EventType = "reset"
+
+// Message is what a stream sends for an event. ID is the position to
+// resume after it with Last-Event-ID.
+type Message struct {
+	ID         string           `json:"id"`
+	Type       events.EventType `json:"type"`
+	ProductID  uuid.UUID        `json:"product_id,omitzero"`
+	OccurredAt time.Time        `json:"occurred_at,omitzero"`
+	Product    *models.Product  `json:"product,omitempty"`
+}
+
+// Session is one client's stream of product events.
+type Session struct {
+	sub      *subscriber
+	filter   Filter
+	position Cursor
+	backlog  []Event
+	reset    bool
+	messages chan Message
+	err      error
+}
+
+// Open starts a stream of the tenant's product events matching filter. With
+// a lastEventID it first replays the events the client missed, or starts
+// with a ResetEvent when they cannot be replayed. It fails with
+// ErrInvalidEventID for a malformed lastEventID.
+func Open(ctx context.Context, tenantID, lastEventID string, filter Filter) (*Session, error) {
+	var from Cursor
+	if lastEventID != "" {
+		var err error
+		if from, err = ParseCursor(lastEventID); err != nil {
+			return nil, err
+		}
+	}
+
+	sub, position, err := defaultHub.subscribe(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{sub: sub, filter: filter, position: position, messages: make(chan Message)}
+
+	if from != nil {
+		s.backlog, err = replay(ctx, tenantID, from, position)
+		if errors.Is(err, errReplayTooFar) {
+			s.reset, err = true, nil
+		}
+		if err != nil {
+			defaultHub.unsubscribe(sub)
+			return nil, err
+		}
+		// A client that resumes from another instance may be ahead of this
+		// one; events it has already seen are skipped.
+		for partition, offset := range from {
+			if offset > s.position.offset(partition) {
+				s.position[partition] = offset
+			}
+		}
+	}
+
+	go s.run(ctx)
+	return s, nil
+}
+
+// Messages delivers the stream. It is closed when ctx is done or the stream
+// ends, after which Err says why.
+func (s *Session) Messages() <-chan Message {
+	return s.messages
+}
+
+// Err returns ErrLagged or ErrShutdown when the stream was ended by the
+// server, and nil otherwise. It may only be called once Messages is closed.
+func (s *Session) Err() error {
+	return s.err
+}
+
+func (s *Session) run(ctx context.Context) {
+	defer close(s.messages)
+	defer defaultHub.unsubscribe(s.sub)
+
+	if s.reset && !s.send(ctx, Message{ID: s.position.String(), Type: ResetEvent}) {
+		return
+	}
+	for _, event := range s.backlog {
+		if !s.emit(ctx, event) {
+			return
+		}
+	}
+	s.backlog = nil
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.sub.dropped:
+			s.err = s.sub.reason
+			return
+		case event := <-s.sub.events:
+			if !s.emit(ctx, event) {
+				return
+			}
+		}
+	}
+}
+
+// emit advances the position past the event and sends it if it matches the
+// filter. Events at or before the position were already seen.
+func (s *Session) emit(ctx context.Context, event Event) bool {
+	if event.Offset <= s.position.offset(event.Partition) {
+		return true
+	}
+	s.position[event.Partition] = event.Offset
+	if !s.filter.match(event) {
+		return true
+	}
+
+	product := event.Product
+	return s.send(ctx, Message{
+		ID:         s.position.String(),
+		Type:       event.Type,
+		ProductID:  product.ID,
+		OccurredAt: event.OccurredAt,
+		Product:    &product,
+	})
+}
+
+func (s *Session) send(ctx context.Context, msg Message) bool {
+	select {
+	case s.messages <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-s.sub.dropped:
+		s.err = s.sub.reason
+		return false
+	}
+}
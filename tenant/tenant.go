@@ -0,0 +1,38 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrMissingTenant is returned by tenant-scoped repositories when the context
+// carries no tenant, so a missing scope fails closed instead of leaking data.
+var ErrMissingTenant = errors.New("no tenant in context")
+
+// Tenant IDs end up in Elasticsearch index and alias names, so they follow the
+// same rules: lowercase, no spaces, short.
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("invalid tenant ID: %q", id)
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant the request is scoped to.
+func FromContext(ctx context.Context) (string, error) {
+	id, _ := ctx.Value(contextKey{}).(string)
+	if id == "" {
+		return "", ErrMissingTenant
+	}
+	return id, nil
+}
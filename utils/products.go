@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-product-api/catalog"
+	"go-product-api/models"
+	"go-product-api/problem"
+	"go-product-api/repositories"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// maxImportLine bounds one product of an import, which is far more than the
+// binding limits of a product allow.
+const maxImportLine = 4 << 20
+
+// ExportProducts writes every product of the tenant on ctx to w as JSON
+// lines, in ID order, and returns how many it wrote. Progress, when set, is
+// called after every batch with the running total.
+func ExportProducts(ctx context.Context, w io.Writer, batchSize int, progress func(exported int)) (int, error) {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	pgRepo := repositories.NewPostgresRepository()
+	enc := json.NewEncoder(w)
+
+	exported := 0
+	for {
+		products, err := pgRepo.FindAll(ctx, repositories.ProductFilter{Limit: batchSize, Offset: exported})
+		if err != nil {
+			return exported, fmt.Errorf("failed to fetch products from PostgreSQL: %w", err)
+		}
+		for _, product := range products {
+			if err := enc.Encode(product); err != nil {
+				return exported, fmt.Errorf("failed to write product %s: %w", product.SKU, err)
+			}
+			exported++
+		}
+		if progress != nil {
+			progress(exported)
+		}
+		if len(products) < batchSize {
+			return exported, nil
+		}
+	}
+}
+
+// ImportOptions control an import. A dry run validates every line and
+// reports whether it would create or update a product without writing
+// anything. Progress, when set, is called after every line.
+type ImportOptions struct {
+	DryRun   bool
+	Progress func(result ImportResult)
+}
+
+// ImportResult counts the lines of an import by outcome. Errors holds one
+// entry per failed line.
+type ImportResult struct {
+	Created int
+	Updated int
+	Failed  int
+	Errors  []LineError
+}
+
+// LineError is why one line of an import was not imported.
+type LineError struct {
+	Line int
+	SKU  string
+	Err  error
+}
+
+func (e LineError) Error() string {
+	if e.SKU == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d (sku %s): %v", e.Line, e.SKU, e.Err)
+}
+
+// ImportProducts reads products as JSON lines, as written by ExportProducts,
+// and upserts them by SKU into the tenant on ctx through the catalog, so
+// they are validated, audited and published like products written over the
+// API. Server-managed fields such as IDs, variants and stock are ignored.
+// Lines that fail are recorded in the result and skipped; the error is only
+// set when r cannot be read.
+func ImportProducts(ctx context.Context, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	pgRepo := repositories.NewPostgresRepository()
+
+	var result ImportResult
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var input models.Product
+		created, err := importProduct(ctx, pgRepo, scanner.Bytes(), &input, opts.DryRun)
+		switch {
+		case err != nil:
+			result.Failed++
+			result.Errors = append(result.Errors, LineError{Line: line, SKU: input.SKU, Err: err})
+		case created:
+			result.Created++
+		default:
+			result.Updated++
+		}
+		if opts.Progress != nil {
+			opts.Progress(result)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read products: %w", err)
+	}
+	return result, nil
+}
+
+// importProduct decodes one line into input and creates or updates the
+// product with its SKU. It reports whether the product is new.
+func importProduct(ctx context.Context, pgRepo *repositories.PostgresRepository, data []byte, input *models.Product, dryRun bool) (bool, error) {
+	if err := json.Unmarshal(data, input); err != nil {
+		return false, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return false, fieldsError(problem.FromBindError(err).Errors)
+	}
+	existing, err := pgRepo.FindBySKU(ctx, input.SKU)
+	created := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !created {
+		return false, fmt.Errorf("failed to look up SKU: %w", err)
+	}
+	if dryRun {
+		errs := append(catalog.DuplicatePrices(input.Prices), catalog.DuplicateOptions(input.Options)...)
+		if len(errs) > 0 {
+			return false, fieldsError(errs)
+		}
+		return created, nil
+	}
+
+	if created {
+		_, err = catalog.Create(ctx, *input)
+	} else {
+		_, err = catalog.Update(ctx, existing.ID, *input)
+	}
+	var validationErr *catalog.ValidationError
+	if errors.As(err, &validationErr) {
+		return false, fieldsError(validationErr.Errors)
+	}
+	return created, err
+}
+
+// fieldsError lists field errors as one line, e.g. "name: is required".
+func fieldsError(errs []problem.FieldError) error {
+	messages := make([]string, len(errs))
+	for i, fe := range errs {
+		messages[i] = fe.Field + ": " + fe.Message
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-product-api/internal/testutil"
+	"go-product-api/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestImportProductsDryRunClassifiesEveryLine(t *testing.T) {
+	mock := testutil.MockDatabase(t)
+	findBySKU := func(sku string) *sqlmock.ExpectedQuery {
+		return mock.ExpectQuery(`FROM "products" WHERE tenant_id = \$1 AND sku = \$2`).WithArgs("tenant-a", sku, 1)
+	}
+	findBySKU("SKU-NEW").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	id := uuid.New()
+	findBySKU("SKU-OLD").WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "sku"}).AddRow(id, "tenant-a", "SKU-OLD"))
+	mock.ExpectQuery(`FROM "product_prices"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`FROM "product_variants"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`FROM product_categories`).WillReturnRows(sqlmock.NewRows([]string{"product_id"}))
+	mock.ExpectQuery(`FROM "products" WHERE products.id IN`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	findBySKU("SKU-DUP").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	input := strings.Join([]string{
+		`{"sku":`,
+		`{"sku":"SKU-NONAME","prices":[{"currency":"USD","amount":1}]}`,
+		`{"sku":"SKU-NEW","name":"New","prices":[{"currency":"USD","amount":1}]}`,
+		``,
+		`{"sku":"SKU-OLD","name":"Old","prices":[{"currency":"USD","amount":1}]}`,
+		`{"sku":"SKU-DUP","name":"Dup","prices":[{"currency":"USD","amount":1},{"currency":"USD","amount":2}]}`,
+	}, "\n")
+	ctx := tenant.WithTenant(context.Background(), "tenant-a")
+	result, err := ImportProducts(ctx, strings.NewReader(input), ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Created != 1 || result.Updated != 1 || result.Failed != 3 {
+		t.Fatalf("got %d created, %d updated, %d failed; want 1, 1, 3", result.Created, result.Updated, result.Failed)
+	}
+	want := []string{
+		"line 1: invalid JSON",
+		"line 2 (sku SKU-NONAME): name: is required",
+		"line 6 (sku SKU-DUP): prices[1]: duplicates",
+	}
+	for i, lineErr := range result.Errors {
+		if !strings.HasPrefix(lineErr.Error(), want[i]) {
+			t.Errorf("error %d: got %q, want it to start with %q", i, lineErr, want[i])
+		}
+	}
+}
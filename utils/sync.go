@@ -1,49 +1,102 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"go-product-api/config"
-	"go-product-api/models"
 	"go-product-api/repositories"
-	"log"
+	"go-product-api/tenant"
+	"log/slog"
 )
 
-func SyncPostgresToElasticsearch() error {
-	log.Println("Starting data synchronization from PostgreSQL to Elasticsearch...")
+const defaultBatchSize = 500
 
-	pgRepo := repositories.NewPostgresRepository()
-	esRepo := repositories.NewElasticsearchRepository()
+// SyncOptions narrow a sync down to one tenant and set how many products
+// are read from PostgreSQL at a time. Progress, when set, is called after
+// every batch with the running totals of the tenant.
+type SyncOptions struct {
+	TenantID  string
+	BatchSize int
+	Progress  func(tenantID string, indexed, failed int)
+}
 
-	products, err := pgRepo.FindAll()
-	if err != nil {
-		return fmt.Errorf("failed to fetch products from PostgreSQL: %w", err)
+// SyncResult counts the products a sync indexed and those it failed to.
+type SyncResult struct {
+	Tenants int
+	Indexed int
+	Failed  int
+}
+
+// SyncPostgresToElasticsearch indexes every product of every tenant, or of
+// opts.TenantID, from PostgreSQL into Elasticsearch. Products that fail to
+// index are logged, counted and skipped; only failures to read from
+// PostgreSQL stop the sync.
+func SyncPostgresToElasticsearch(ctx context.Context, opts SyncOptions) (SyncResult, error) {
+	slog.Info("Starting data synchronization from PostgreSQL to Elasticsearch")
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
 	}
 
-	log.Printf("Found %d products in PostgreSQL", len(products))
+	pgRepo := repositories.NewPostgresRepository()
+	esRepo := repositories.NewElasticsearchRepository()
 
-	for _, product := range products {
-		if err := esRepo.Index(product); err != nil {
-			log.Printf("Error indexing product %s: %v", product.ID, err)
-			continue
+	tenantIDs := []string{opts.TenantID}
+	if opts.TenantID == "" {
+		var err error
+		if tenantIDs, err = pgRepo.FindTenantIDs(ctx); err != nil {
+			return SyncResult{}, fmt.Errorf("failed to fetch tenants from PostgreSQL: %w", err)
 		}
 	}
 
-	log.Println("Synchronization completed")
-	return nil
-}
+	var result SyncResult
+	for _, tenantID := range tenantIDs {
+		ctx := tenant.WithTenant(ctx, tenantID)
+		result.Tenants++
 
-func InitializeIndices() error {
+		var indexed, failed int
+		for offset := 0; ; offset += opts.BatchSize {
+			products, err := pgRepo.FindAll(ctx, repositories.ProductFilter{Limit: opts.BatchSize, Offset: offset})
+			if err != nil {
+				return result, fmt.Errorf("failed to fetch products from PostgreSQL: %w", err)
+			}
 
-	log.Println("Elasticsearch indices initialized")
-	return nil
+			for _, product := range products {
+				if err := esRepo.Index(ctx, product); err != nil {
+					slog.Error("Error indexing product", "product.id", product.ID, "error.message", err)
+					failed++
+					continue
+				}
+				indexed++
+			}
+			if opts.Progress != nil {
+				opts.Progress(tenantID, indexed, failed)
+			}
+			if len(products) < opts.BatchSize {
+				break
+			}
+		}
+
+		slog.Info("Synchronized tenant", "organization.id", tenantID, "count", indexed, "failed", failed)
+		result.Indexed += indexed
+		result.Failed += failed
+	}
+
+	slog.Info("Synchronization completed", "count", result.Indexed, "failed", result.Failed)
+	return result, nil
 }
 
-func MigrateDatabase() error {
-	log.Println("Migrating PostgreSQL database...")
-	err := config.DB.AutoMigrate(&models.Product{})
+// InitializeIndices deletes and recreates the product indices with the
+// current mapping for every tenant with products, leaving them empty for a
+// sync to fill.
+func InitializeIndices(ctx context.Context) error {
+	tenantIDs, err := repositories.NewPostgresRepository().FindTenantIDs(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+		return fmt.Errorf("failed to fetch tenants from PostgreSQL: %w", err)
 	}
-	log.Println("Database migration completed")
+	if err := config.RecreateProductIndices(ctx, tenantIDs); err != nil {
+		return err
+	}
+
+	slog.Info("Elasticsearch indices initialized", "tenants", len(tenantIDs))
 	return nil
 }
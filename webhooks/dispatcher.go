@@ -0,0 +1,156 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go-product-api/config"
+	"go-product-api/logging"
+	"go-product-api/models"
+	"go-product-api/repositories"
+	"go-product-api/tenant"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// claimBatch bounds how many deliveries one round claims.
+const claimBatch = 100
+
+// StartDispatcher sends due webhook deliveries every WebhookPollInterval
+// until ctx is cancelled. Deliveries already being sent are finished before
+// the loop exits; the returned channel is closed once it has. Running it in
+// several instances is safe: each delivery is claimed by exactly one.
+func StartDispatcher(ctx context.Context) <-chan struct{} {
+	d := &dispatcher{
+		repo:   repositories.NewWebhookRepository(),
+		client: newClient(),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(config.WebhookPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("webhook dispatcher stopped")
+				return
+			case <-ticker.C:
+				d.dispatch(ctx)
+			}
+		}
+	}()
+	slog.Info("webhook dispatcher started", "interval", config.WebhookPollInterval.String())
+	return done
+}
+
+type dispatcher struct {
+	repo   *repositories.WebhookRepository
+	client *http.Client
+}
+
+func (d *dispatcher) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		// The lease outlasts a full batch sent at the configured concurrency.
+		lease := config.WebhookTimeout * time.Duration(claimBatch/max(config.WebhookConcurrency, 1)+2)
+		deliveries, err := d.repo.ClaimDue(ctx, claimBatch, lease)
+		if err != nil {
+			slog.Error("Failed to claim webhook deliveries", "error.message", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, max(config.WebhookConcurrency, 1))
+		for i := range deliveries {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				d.deliver(ctx, delivery)
+			}(&deliveries[i])
+		}
+		wg.Wait()
+
+		if len(deliveries) < claimBatch {
+			return
+		}
+	}
+}
+
+// deliver sends one delivery and records the outcome. Sending is not tied
+// to ctx, so a delivery in flight at shutdown still gets its outcome
+// recorded.
+func (d *dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	ctx = tenant.WithTenant(context.WithoutCancel(ctx), delivery.TenantID)
+	logger := logging.FromContext(ctx).With(
+		"webhook.id", delivery.SubscriptionID,
+		"webhook.delivery.id", delivery.ID,
+		"event.id", delivery.EventID,
+	)
+
+	statusCode, err := d.send(ctx, delivery)
+	disabled, recordErr := d.repo.RecordAttempt(ctx, delivery, statusCode, err, backoff(delivery.Attempts+1))
+	if recordErr != nil {
+		logger.Error("Failed to record webhook delivery", "error.message", recordErr)
+		return
+	}
+
+	switch {
+	case err == nil:
+		logger.Info("Webhook delivered", "http.response.status_code", statusCode)
+	case delivery.Status == models.WebhookDeliveryFailed:
+		logger.Warn("Webhook delivery failed, giving up", "webhook.attempts", delivery.Attempts, "error.message", err)
+	default:
+		logger.Info("Webhook delivery failed, will retry", "webhook.attempts", delivery.Attempts, "webhook.next_attempt_at", delivery.NextAttemptAt, "error.message", err)
+	}
+	if disabled {
+		logger.Warn("Webhook subscription disabled after repeated failures")
+	}
+}
+
+// send POSTs the signed payload. Any response other than 2xx, including a
+// redirect, is a failure.
+func (d *dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	if req.URL.Scheme != "https" {
+		return 0, fmt.Errorf("%w: scheme must be https", ErrUnsafeTarget)
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", config.ServiceName+"-webhooks")
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Subscription.Secret, now, delivery.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint responded %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff returns how long to wait before the given attempt: the base
+// backoff doubled for every attempt after the second, capped at the maximum.
+func backoff(attempt int) time.Duration {
+	wait := config.WebhookBaseBackoff
+	for i := 2; i < attempt && wait < config.WebhookMaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, config.WebhookMaxBackoff)
+}
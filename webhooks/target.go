@@ -0,0 +1,83 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-product-api/config"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrUnsafeTarget is returned for webhook URLs deliveries are not sent to:
+// anything but https, and hosts that are or resolve to an address outside
+// the public internet, such as a private, loopback or link-local one.
+var ErrUnsafeTarget = errors.New("webhook target is not a public https URL")
+
+// sharedAddressSpace is carrier-grade NAT space, which is not reachable from
+// the internet but is not reported by netip.Addr.IsPrivate either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// CheckTarget returns an error wrapping ErrUnsafeTarget unless rawURL is an
+// https URL whose host resolves only to public addresses. The dialer checks
+// the address again at send time, since the name may since have been
+// pointed elsewhere.
+func CheckTarget(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsafeTarget, err)
+	}
+	if u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("%w: scheme must be https", ErrUnsafeTarget)
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsafeTarget, err)
+	}
+	for _, addr := range addrs {
+		if !public(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrUnsafeTarget, u.Hostname(), addr)
+		}
+	}
+	return nil
+}
+
+// public reports whether addr is a unicast address on the public internet.
+func public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// newClient returns the client deliveries are sent with. It only connects to
+// public addresses, checked on the resolved address right before dialing,
+// and does not follow redirects, which could lead anywhere.
+func newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: config.WebhookTimeout, Control: dialControl}
+	return &http.Client{
+		Timeout: config.WebhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: max(config.WebhookConcurrency, 1),
+			TLSHandshakeTimeout: config.WebhookTimeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func dialControl(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !public(addrPort.Addr()) {
+		return fmt.Errorf("%w: refusing to connect to %s", ErrUnsafeTarget, addrPort.Addr())
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		url  string
+		safe bool
+	}{
+		{"https://93.184.215.14/hooks", true},
+		{"https://[2606:2800:21f:cb07:6820:80da:af6b:8b2c]/hooks", true},
+		{"http://93.184.215.14/hooks", false},
+		{"https://127.0.0.1/hooks", false},
+		{"https://10.1.2.3/hooks", false},
+		{"https://192.168.0.10/hooks", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://100.64.0.1/hooks", false},
+		{"https://[::1]/hooks", false},
+		{"https://[fe80::1]/hooks", false},
+		{"https://[::ffff:10.0.0.1]/hooks", false},
+		{"https://0.0.0.0/hooks", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := CheckTarget(context.Background(), tt.url)
+			if tt.safe && err != nil {
+				t.Fatalf("got error %v, want none", err)
+			}
+			if !tt.safe && !errors.Is(err, ErrUnsafeTarget) {
+				t.Fatalf("got error %v, want %v", err, ErrUnsafeTarget)
+			}
+		})
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, nil)
+	_, err := newClient().Do(req)
+	if !errors.Is(err, ErrUnsafeTarget) {
+		t.Fatalf("got error %v, want %v", err, ErrUnsafeTarget)
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	client := newClient()
+	if err := client.CheckRedirect(nil, nil); !errors.Is(err, http.ErrUseLastResponse) {
+		t.Fatalf("got %v, want %v", err, http.ErrUseLastResponse)
+	}
+}